@@ -1,6 +1,7 @@
 package bytocol
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -10,47 +11,129 @@ type blob interface {
 	string | []byte
 }
 
-// Blob to bytes is for encoding, it adds the length prefix.
-func blobToBytes[T blob](data T, lenBits byte) []byte {
-	// Ensure raw byte data
-	var bytData []byte
-	if asByts, ok := any(data).([]byte); ok {
-		bytData = asByts
-	} else if asStr, ok := any(data).(string); ok {
-		bytData = []byte(asStr)
+// maxBlobLength returns the largest content length that can be represented by
+// a length prefix of the given bit-size.
+func maxBlobLength(lenBits byte) uint64 {
+	if lenBits >= 64 {
+		return ^uint64(0)
 	}
+	return (uint64(1) << lenBits) - 1
+}
 
-	// Setup the buffer
+// Length to bytes encodes a length prefix of the given bit-size and byte order.
+// If the length does not fit within the prefix an [ErrLengthOverflow] error is
+// returned instead of truncating it.
+func lengthToBytes(length uint64, lenBits byte, order binary.ByteOrder) ([]byte, error) {
 	lenBytes := int(lenBits / 8)
-	output := make([]byte, lenBytes+len(bytData))
+	if lenBytes != 1 && lenBytes != 2 && lenBytes != 4 && lenBytes != 8 {
+		panic(fmt.Sprintf("unsupported length bits %d", lenBits))
+	}
 
+	if length > maxBlobLength(lenBits) {
+		return nil, fmt.Errorf("%w: length %d exceeds %d-bit length prefix", ErrLengthOverflow, length, lenBits)
+	}
+
+	output := make([]byte, lenBytes)
 	switch lenBytes {
 	case 1:
-		output[0] = byte(len(bytData))
+		output[0] = byte(length)
 	case 2:
-		binary.BigEndian.PutUint16(output[:2], uint16(len(bytData)))
+		order.PutUint16(output, uint16(length))
 	case 4:
-		binary.BigEndian.PutUint32(output[:4], uint32(len(bytData)))
+		order.PutUint32(output, uint32(length))
 	case 8:
-		binary.BigEndian.PutUint64(output[:8], uint64(len(bytData)))
-	default:
-		panic(fmt.Sprintf("unsupported length bits %d", lenBits))
+		order.PutUint64(output, length)
+	}
+
+	return output, nil
+}
+
+// Bytes to length decodes a length prefix in the given byte order, the bit-size
+// is deduced from the number of bytes provided.
+func bytesToLength(data []byte, order binary.ByteOrder) uint64 {
+	switch len(data) {
+	case 1:
+		return uint64(data[0])
+	case 2:
+		return uint64(order.Uint16(data))
+	case 4:
+		return uint64(order.Uint32(data))
+	case 8:
+		return order.Uint64(data)
 	}
+	return 0
+}
 
-	copy(output[lenBytes:], bytData)
+func writeLength(length uint64, lenBits byte, order binary.ByteOrder, w io.Writer) error {
+	byts, err := lengthToBytes(length, lenBits, order)
+	if err != nil {
+		return err
+	}
 
-	return output
+	_, err = w.Write(byts)
+	return err
 }
 
-func writeBlob[T blob](data T, lenBits byte, w io.Writer) (err error) {
+func readLength(r io.Reader, lenBits byte, order binary.ByteOrder) (uint64, error) {
+	buf := make([]byte, lenBits/8)
+	if err := readFull(r, buf); err != nil {
+		return 0, err
+	}
+	return bytesToLength(buf, order), nil
+}
+
+// Blob to bytes is for encoding, it adds the length prefix. If the content
+// length does not fit within the length prefix an [ErrLengthOverflow] error is
+// returned instead of truncating the prefix.
+func blobToBytes[T blob](data T, lenBits byte, order binary.ByteOrder) ([]byte, error) {
+	// Ensure raw byte data
+	bytData := []byte(data)
+
+	prefix, err := lengthToBytes(uint64(len(bytData)), lenBits, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(prefix, bytData...), nil
+}
+
+// Terminated blob to bytes is for encoding null-terminated blobs, it appends
+// the NUL terminator. Since the terminator marks the end of the content, the
+// content itself cannot contain a NUL byte, if it does an [ErrNullInContent]
+// error is returned.
+func terminatedBlobToBytes[T blob](data T) ([]byte, error) {
+	bytData := []byte(data)
+	if bytes.IndexByte(bytData, 0) != -1 {
+		return nil, ErrNullInContent
+	}
+
+	output := make([]byte, len(bytData)+1)
+	copy(output, bytData)
+	return output, nil
+}
+
+func writeBlob[T blob](data T, lenBits byte, order binary.ByteOrder, w io.Writer) (err error) {
 	defer func() {
 		recErr := recover()
 		if recErr != nil {
-			err = recErr.(error)
+			err = fmt.Errorf("%v", recErr)
 		}
 	}()
-	byts := blobToBytes(data, lenBits)
+	byts, err := blobToBytes(data, lenBits, order)
+	if err != nil {
+		return err
+	}
 
 	_, wErr := w.Write(byts)
 	return wErr
 }
+
+func writeTerminatedBlob[T blob](data T, w io.Writer) error {
+	byts, err := terminatedBlobToBytes(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(byts)
+	return err
+}
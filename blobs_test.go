@@ -1,42 +1,62 @@
 package bytocol
 
-import "testing"
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
 
 func TestBlobToBytes(t *testing.T) {
 	str := "Hello, World"
 
 	// 8-bit
-	data := blobToBytes(str, 8)
-	if len(data) != (len(str) + 1) {
+	data, err := blobToBytes(str, 8, binary.BigEndian)
+	if err != nil {
+		t.Error(err)
+	} else if len(data) != (len(str) + 1) {
 		t.Errorf("unexpected length %d", len(data))
 	} else if string(data[1:]) != str {
 		t.Errorf("unexpected body %s", data[1:])
 	}
 
 	// 16-bit
-	data = blobToBytes(str, 16)
-	if len(data) != (len(str) + 2) {
+	data, err = blobToBytes(str, 16, binary.BigEndian)
+	if err != nil {
+		t.Error(err)
+	} else if len(data) != (len(str) + 2) {
 		t.Errorf("unexpected length %d", len(data))
 	} else if string(data[2:]) != str {
 		t.Errorf("unexpected body %s", data[2:])
 	}
 
 	// 32-bit
-	data = blobToBytes(str, 32)
-	if len(data) != (len(str) + 4) {
+	data, err = blobToBytes(str, 32, binary.BigEndian)
+	if err != nil {
+		t.Error(err)
+	} else if len(data) != (len(str) + 4) {
 		t.Errorf("unexpected length %d", len(data))
 	} else if string(data[4:]) != str {
 		t.Errorf("unexpected body %s", data[4:])
 	}
 
 	// 64-bit
-	data = blobToBytes(str, 64)
-	if len(data) != (len(str) + 8) {
+	data, err = blobToBytes(str, 64, binary.BigEndian)
+	if err != nil {
+		t.Error(err)
+	} else if len(data) != (len(str) + 8) {
 		t.Errorf("unexpected length %d", len(data))
 	} else if string(data[8:]) != str {
 		t.Errorf("unexpected body %s", data[8:])
 	}
 
+	// Catch overflowing the length prefix
+	_, err = blobToBytes(make([]byte, 256), 8, binary.BigEndian)
+	if !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+
 	{
 		// Check panic recovery
 		defer func() {
@@ -46,6 +66,36 @@ func TestBlobToBytes(t *testing.T) {
 			}
 		}()
 
-		_ = blobToBytes([]byte{}, 4)
+		_, _ = blobToBytes([]byte{}, 4, binary.BigEndian)
+	}
+}
+
+func TestTerminatedBlobToBytes(t *testing.T) {
+	str := "Hello, World"
+
+	data, err := terminatedBlobToBytes(str)
+	if err != nil {
+		t.Error(err)
+	} else if len(data) != (len(str) + 1) {
+		t.Errorf("unexpected length %d", len(data))
+	} else if data[len(data)-1] != 0 {
+		t.Error("expected NUL terminator")
+	} else if string(data[:len(str)]) != str {
+		t.Errorf("unexpected body %s", data[:len(str)])
+	}
+
+	// Catch NUL bytes in the content
+	_, err = terminatedBlobToBytes([]byte{1, 0, 2})
+	if !errors.Is(err, ErrNullInContent) {
+		t.Errorf("expected NUL content error, got %v", err)
+	}
+}
+
+func TestWriteBlobRecover(t *testing.T) {
+	// Unsupported length bits panic with a string, which is returned as the
+	// error instead
+	err := writeBlob("abc", 4, binary.BigEndian, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "unsupported length bits 4") {
+		t.Errorf("expected unsupported length bits error, got %v", err)
 	}
 }
@@ -0,0 +1,92 @@
+package bytocol
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// planCache holds the [TypePlan] for every message type planned through the
+// cache, keyed by the struct [reflect.Type]. Pointer and value types of the same
+// struct share the same plan.
+var planCache sync.Map
+
+// cacheKey returns the key used in the plan cache for the type, indirecting
+// pointers to their struct type.
+func cacheKey(typeOf reflect.Type) reflect.Type {
+	if typeOf.Kind() == reflect.Pointer {
+		return typeOf.Elem()
+	}
+	return typeOf
+}
+
+// CachedPlan returns the cached [TypePlan] for the type of the object provided,
+// building and caching it first if this type has not been planned before. It is
+// safe for concurrent use and is used by [Write], [Marshal], and [Register].
+//
+// The plan is built once per type, so the [MessageInfo] returned by the message
+// must not change between values of the same type.
+func CachedPlan(obj Message) (*TypePlan, error) {
+	if obj == nil {
+		return nil, ErrNonMessageType
+	}
+
+	key := cacheKey(reflect.TypeOf(obj))
+	if cached, ok := planCache.Load(key); ok {
+		return cached.(*TypePlan), nil
+	}
+
+	plan, err := PlanObject(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another goroutine may have planned it at the same time, keep the first
+	cached, _ := planCache.LoadOrStore(key, plan)
+	return cached.(*TypePlan), nil
+}
+
+// CachePlan pre-warms the plan cache with the [Message] type provided as the
+// generic argument and returns its [TypePlan]. If the type is already cached the
+// existing plan is returned.
+func CachePlan[T Message]() (*TypePlan, error) {
+	key := cacheKey(reflect.TypeFor[T]())
+	if cached, ok := planCache.Load(key); ok {
+		return cached.(*TypePlan), nil
+	}
+
+	plan, err := PlanType[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	cached, _ := planCache.LoadOrStore(key, plan)
+	return cached.(*TypePlan), nil
+}
+
+// CachedPlans returns every plan currently in the plan cache, ordered by their
+// type indicator and then type name.
+func CachedPlans() []*TypePlan {
+	plans := make([]*TypePlan, 0)
+	planCache.Range(func(_, value any) bool {
+		plans = append(plans, value.(*TypePlan))
+		return true
+	})
+
+	sort.Slice(plans, func(i, j int) bool {
+		if plans[i].typeIndicator != plans[j].typeIndicator {
+			return plans[i].typeIndicator < plans[j].typeIndicator
+		}
+		return plans[i].typeOf.String() < plans[j].typeOf.String()
+	})
+	return plans
+}
+
+// ClearPlanCache removes every plan from the plan cache. Plans already held by
+// a [Registry] are not affected.
+func ClearPlanCache() {
+	planCache.Range(func(key, _ any) bool {
+		planCache.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,72 @@
+package bytocol
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCachedPlan(t *testing.T) {
+	ClearPlanCache()
+
+	plan, err := CachedPlan(testMessageObj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Pointer and value types share the same plan
+	again, err := CachedPlan(&testMessageObj)
+	if err != nil {
+		t.Error(err)
+	} else if again != plan {
+		t.Error("expected the same cached plan")
+	}
+
+	warmed, err := CachePlan[testMessage]()
+	if err != nil {
+		t.Error(err)
+	} else if warmed != plan {
+		t.Error("expected pre-warming to return the cached plan")
+	}
+
+	if _, err = CachePlan[testBlobMessage](); err != nil {
+		t.Error(err)
+	}
+
+	plans := CachedPlans()
+	if len(plans) != 2 {
+		t.Errorf("expected 2 cached plans, got %d", len(plans))
+	} else if plans[0].TypeIndicator() != 1 || plans[1].TypeIndicator() != 3 {
+		t.Error("expected cached plans ordered by type indicator")
+	}
+
+	ClearPlanCache()
+	if len(CachedPlans()) != 0 {
+		t.Error("expected empty cache")
+	}
+}
+
+func TestCachedPlanConcurrent(t *testing.T) {
+	ClearPlanCache()
+
+	var wg sync.WaitGroup
+	plans := make([]*TypePlan, 16)
+	for i := range plans {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			plans[i], _ = CachedPlan(testMessageObj)
+			if _, err := Marshal(testMessageObj); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, plan := range plans {
+		if plan == nil || plan != plans[0] {
+			t.Error("expected every goroutine to share the same plan")
+			break
+		}
+	}
+}
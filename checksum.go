@@ -0,0 +1,274 @@
+package bytocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+)
+
+// Checksum is an integrity check appended to encoded messages as a trailer,
+// enabled with [MessageInfo.Checksum]. It is computed over every byte of the
+// message starting with the type indicator, and written as an unsigned integer
+// in the byte order of the message.
+type Checksum byte
+
+const (
+	// ChecksumNone appends no trailer.
+	ChecksumNone Checksum = iota
+
+	// ChecksumCRC16 appends the 2 byte CRC-16/CCITT-FALSE, with polynomial
+	// 0x1021 and initial value 0xFFFF.
+	ChecksumCRC16
+
+	// ChecksumCRC32 appends the 4 byte CRC-32 of the IEEE polynomial.
+	ChecksumCRC32
+
+	// ChecksumCRC32C appends the 4 byte CRC-32 of the Castagnoli polynomial.
+	ChecksumCRC32C
+
+	// ChecksumXXHash64 appends the 8 byte 64-bit xxHash with seed 0.
+	ChecksumXXHash64
+)
+
+var checksumNames = []string{"none", "crc16", "crc32", "crc32c", "xxhash64"}
+
+// String returns the name of the checksum as used by schemas.
+func (c Checksum) String() string {
+	if int(c) < len(checksumNames) {
+		return checksumNames[c]
+	}
+	return fmt.Sprintf("Checksum(%d)", c)
+}
+
+// Size returns the size of the trailer in bytes.
+func (c Checksum) Size() int {
+	switch c {
+	case ChecksumCRC16:
+		return 2
+	case ChecksumCRC32, ChecksumCRC32C:
+		return 4
+	case ChecksumXXHash64:
+		return 8
+	}
+	return 0
+}
+
+// parseChecksum returns the checksum with the name.
+func parseChecksum(name string) (Checksum, bool) {
+	for i, checksumName := range checksumNames {
+		if checksumName == name {
+			return Checksum(i), true
+		}
+	}
+	return ChecksumNone, false
+}
+
+// checksumState computes a checksum over the bytes written to it.
+type checksumState interface {
+	io.Writer
+	sum() uint64
+}
+
+// newState returns the state computing the checksum, or nil for
+// [ChecksumNone].
+func (c Checksum) newState() checksumState {
+	switch c {
+	case ChecksumCRC16:
+		return &crc16State{crc: 0xFFFF}
+	case ChecksumCRC32:
+		return &crc32State{table: crc32.IEEETable}
+	case ChecksumCRC32C:
+		return &crc32State{table: crc32cTable}
+	case ChecksumXXHash64:
+		return newXXHash64()
+	}
+	return nil
+}
+
+// compute returns the checksum of the data.
+func (c Checksum) compute(data []byte) uint64 {
+	state := c.newState()
+	state.Write(data)
+	return state.sum()
+}
+
+// writeChecksum writes the trailer of the state.
+func writeChecksum(c Checksum, state checksumState, order binary.ByteOrder, w io.Writer) error {
+	return writeLength(state.sum(), byte(c.Size()*8), order, w)
+}
+
+// readChecksum reads the trailer and compares it with the state. A different
+// checksum returns an [ErrChecksumMismatch] error.
+func readChecksum(c Checksum, state checksumState, order binary.ByteOrder, r io.Reader) error {
+	received, err := readLength(r, byte(c.Size()*8), order)
+	if err != nil {
+		return fmt.Errorf("bytocol: reading %s checksum: %w", c, err)
+	}
+
+	if computed := state.sum(); computed != received {
+		return fmt.Errorf("bytocol: %w, computed %s %#x but received %#x", ErrChecksumMismatch, c, computed, received)
+	}
+	return nil
+}
+
+// verifyChecksum compares the trailer at the end of the data with the checksum
+// of the bytes before it.
+func verifyChecksum(c Checksum, data []byte, order binary.ByteOrder) error {
+	end := len(data) - c.Size()
+	if end < 1 {
+		return fmt.Errorf("bytocol: %d bytes cannot hold a message with a %s checksum: %w", len(data), c, io.ErrUnexpectedEOF)
+	}
+
+	state := c.newState()
+	state.Write(data[:end])
+	return readChecksum(c, state, order, bytes.NewReader(data[end:]))
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+type crc32State struct {
+	table *crc32.Table
+	crc   uint32
+}
+
+func (s *crc32State) Write(p []byte) (int, error) {
+	s.crc = crc32.Update(s.crc, s.table, p)
+	return len(p), nil
+}
+
+func (s *crc32State) sum() uint64 {
+	return uint64(s.crc)
+}
+
+// crc16Table is the lookup table of the CCITT polynomial 0x1021.
+var crc16Table = func() (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+type crc16State struct {
+	crc uint16
+}
+
+func (s *crc16State) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.crc = s.crc<<8 ^ crc16Table[byte(s.crc>>8)^b]
+	}
+	return len(p), nil
+}
+
+func (s *crc16State) sum() uint64 {
+	return uint64(s.crc)
+}
+
+// Primes of the 64-bit xxHash.
+const (
+	xxPrime1 uint64 = 0x9E3779B185EBCA87
+	xxPrime2 uint64 = 0xC2B2AE3D27D4EB4F
+	xxPrime3 uint64 = 0x165667B19E3779F9
+	xxPrime4 uint64 = 0x85EBCA77C2B2AE63
+	xxPrime5 uint64 = 0x27D4EB2F165667C5
+)
+
+// xxHash64State computes the 64-bit xxHash with seed 0, consuming the input in
+// stripes of 32 bytes into four accumulators.
+type xxHash64State struct {
+	acc   [4]uint64
+	buf   [32]byte
+	n     int
+	total uint64
+}
+
+func newXXHash64() *xxHash64State {
+	// The initial accumulators wrap around, which constants cannot
+	prime1 := xxPrime1
+	return &xxHash64State{acc: [4]uint64{prime1 + xxPrime2, xxPrime2, 0, -prime1}}
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	return bits.RotateLeft64(acc, 31) * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+func (s *xxHash64State) stripe(p []byte) {
+	for i := range s.acc {
+		s.acc[i] = xxRound(s.acc[i], binary.LittleEndian.Uint64(p[i*8:]))
+	}
+}
+
+func (s *xxHash64State) Write(p []byte) (int, error) {
+	written := len(p)
+	s.total += uint64(written)
+
+	// Complete the buffered stripe first
+	if s.n > 0 {
+		copied := copy(s.buf[s.n:], p)
+		s.n += copied
+		p = p[copied:]
+		if s.n < len(s.buf) {
+			return written, nil
+		}
+		s.stripe(s.buf[:])
+		s.n = 0
+	}
+
+	for ; len(p) >= 32; p = p[32:] {
+		s.stripe(p)
+	}
+	s.n = copy(s.buf[:], p)
+	return written, nil
+}
+
+func (s *xxHash64State) sum() uint64 {
+	var h uint64
+	if s.total >= 32 {
+		h = bits.RotateLeft64(s.acc[0], 1) + bits.RotateLeft64(s.acc[1], 7) +
+			bits.RotateLeft64(s.acc[2], 12) + bits.RotateLeft64(s.acc[3], 18)
+		for _, acc := range s.acc {
+			h = xxMergeRound(h, acc)
+		}
+	} else {
+		h = xxPrime5
+	}
+	h += s.total
+
+	p := s.buf[:s.n]
+	for ; len(p) >= 8; p = p[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(p))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(p) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(p)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		h ^= uint64(b) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
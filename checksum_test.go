@@ -0,0 +1,169 @@
+package bytocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+type testChecksumMessage struct {
+	ID   uint32 `bytocol:"0"`
+	Name string `bytocol:"1,length-prefix=8"`
+}
+
+func (m testChecksumMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 18, DebugName: "checksum", ByteOrder: binary.LittleEndian, Checksum: ChecksumCRC16}
+}
+
+type testUnknownChecksumMessage struct {
+	ID uint32 `bytocol:"0"`
+}
+
+func (m testUnknownChecksumMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 19, DebugName: "unknown-checksum", Checksum: 200}
+}
+
+func TestChecksumVectors(t *testing.T) {
+	for _, test := range []struct {
+		checksum Checksum
+		input    string
+		expected uint64
+	}{
+		{ChecksumCRC16, "123456789", 0x29B1},
+		{ChecksumCRC32, "123456789", 0xCBF43926},
+		{ChecksumCRC32C, "123456789", 0xE3069283},
+		{ChecksumXXHash64, "", 0xEF46DB3751D8E999},
+		{ChecksumXXHash64, "abc", 0x44BC2CF5AD770999},
+		{ChecksumXXHash64, "Nobody inspects the spammish repetition", 0xFBCEA83C8A378BF1},
+	} {
+		if sum := test.checksum.compute([]byte(test.input)); sum != test.expected {
+			t.Errorf("%s of %q: expected %#x, got %#x", test.checksum, test.input, test.expected, sum)
+		}
+	}
+
+	// Writing in chunks crossing the xxHash stripes gives the same result
+	data := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxyz"), 5)
+	for _, chunk := range []int{1, 3, 7, 32, 33} {
+		state := ChecksumXXHash64.newState()
+		for rest := data; len(rest) > 0; rest = rest[min(chunk, len(rest)):] {
+			state.Write(rest[:min(chunk, len(rest))])
+		}
+		if sum, expected := state.sum(), ChecksumXXHash64.compute(data); sum != expected {
+			t.Errorf("chunks of %d: expected %#x, got %#x", chunk, expected, sum)
+		}
+	}
+}
+
+func TestPlanChecksum(t *testing.T) {
+	plan, err := PlanType[testChecksumMessage]()
+	if err != nil {
+		t.Fatal(err)
+	} else if plan.Size() != 7 || plan.Checksum() != ChecksumCRC16 {
+		t.Errorf("expected minimum size 7 with crc16, got %d with %s", plan.Size(), plan.Checksum())
+	}
+
+	msg := testChecksumMessage{ID: 7, Name: "bytocol"}
+	data, err := plan.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The trailer follows the fields in the byte order of the message
+	trailer := ChecksumCRC16.compute(data[:len(data)-2])
+	if received := binary.LittleEndian.Uint16(data[len(data)-2:]); uint64(received) != trailer {
+		t.Errorf("expected trailer %#x, got %#x", trailer, received)
+	}
+
+	var decoded testChecksumMessage
+	if err = plan.Unmarshal(data[1:], &decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded != msg {
+		t.Errorf("expected %+v, got %+v", msg, decoded)
+	}
+	if explained := plan.Explain(data); !strings.Contains(explained, "Checksum crc16 = ") || !strings.Contains(explained, "(valid)") || !strings.Contains(explained, "All bytes accounted for") {
+		t.Errorf("expected valid checksum explained, got:\n%s", explained)
+	}
+
+	// Flip a bit of the name
+	corrupted := bytes.Clone(data)
+	corrupted[7] ^= 1
+	if err = plan.Unmarshal(corrupted[1:], &decoded); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	if explained := plan.Explain(corrupted); !strings.Contains(explained, "(MISMATCH, computed") {
+		t.Errorf("expected mismatch explained, got:\n%s", explained)
+	}
+
+	// Missing trailer
+	if err = plan.Unmarshal(data[1:len(data)-1], &decoded); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+
+	if _, err = PlanType[testUnknownChecksumMessage](); err == nil || !strings.Contains(err.Error(), "unknown checksum") {
+		t.Errorf("expected unknown checksum error, got %v", err)
+	}
+}
+
+func TestConnChecksum(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testChecksumMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Marshal(testChecksumMessage{ID: 7, Name: "bytocol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the length prefix, which would otherwise fail to decode
+	data[5] = 200
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	frame = append(append(frame, 0), data...)
+
+	a, b := net.Pipe()
+	conn := NewConn(a, WithRegistry(reg))
+	defer conn.Close()
+	go func() {
+		b.Write(frame)
+		b.Close()
+	}()
+
+	if _, err = conn.Receive(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestChecksumSchema(t *testing.T) {
+	plan, err := PlanType[testChecksumMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := plan.Schema()
+	if schema.Messages[0].Checksum != "crc16" || schema.Messages[0].Size != 7 {
+		t.Errorf("expected crc16 message of 7 bytes, got %+v", schema.Messages[0])
+	}
+
+	idl := schema.IDL()
+	if !strings.Contains(idl, "endian=little checksum=crc16 {") {
+		t.Errorf("expected checksum in IDL, got:\n%s", idl)
+	}
+	parsed, err := ParseIDL(idl)
+	if err != nil {
+		t.Fatal(err)
+	} else if parsed.Messages[0].Size != 7 || parsed.Fingerprint() != schema.Fingerprint() {
+		t.Errorf("expected IDL round trip to match, got %+v", parsed.Messages[0])
+	}
+
+	plain := *schema
+	plain.Messages = []MessageSchema{schema.Messages[0]}
+	plain.Messages[0].Checksum = ""
+	found := CheckCompat(schema, &plain)
+	if len(found) != 1 || found[0].Reason != "checksum changed from crc16 to none" {
+		t.Errorf("expected checksum change, got %v", found)
+	}
+}
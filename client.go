@@ -0,0 +1,183 @@
+package bytocol
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// correlationKey is the context key holding the correlation ID of the request
+// being handled by a [Server].
+type correlationKey struct{}
+
+// callResult is the outcome of a single [Client.Call].
+type callResult struct {
+	msg Message
+	err error
+}
+
+// Client makes request/response calls over a [Conn]. Each request is sent with
+// a correlation ID that the peer returns with its reply, so many calls can be in
+// flight on the same connection at once. It is safe for concurrent use.
+//
+// The client reads every frame of the connection itself, replies without a
+// correlation ID or for calls that are no longer waiting are discarded.
+type Client struct {
+	conn *Conn
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan callResult
+	err     error
+	done    chan struct{}
+}
+
+// NewClient creates a new [Client] making calls over the connection. It starts
+// a goroutine reading the replies until the client is closed or the connection
+// fails.
+func NewClient(conn *Conn) *Client {
+	c := &Client{
+		conn:    conn,
+		pending: make(map[uint32]chan callResult),
+		done:    make(chan struct{}),
+	}
+
+	go c.readLoop()
+	return c
+}
+
+// Dial connects to the address on the named network and returns a [Client] for
+// it, with the connection configured by the options provided. If a handshake is
+// enabled with [WithHandshake] it is exchanged before returning.
+func Dial(network, address string, opts ...ConnOption) (*Client, error) {
+	netConn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := NewConn(netConn, opts...)
+	if err = conn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// Call sends the request and waits for its reply. If the peer replies with an
+// [ErrorMessage] it is returned as the error. If the context ends before the
+// reply is received its error is returned and the reply is discarded when it
+// arrives.
+func (c *Client) Call(ctx context.Context, req Message) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	id, result, err := c.register()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.conn.sendFrame(frameHeader{flags: frameFlagCorrelated, id: id}, req); err != nil {
+		c.unregister(id)
+		return nil, err
+	}
+
+	select {
+	case res := <-result:
+		return res.msg, res.err
+	case <-ctx.Done():
+		c.unregister(id)
+		return nil, ctx.Err()
+	case <-c.done:
+		// The reply may have been delivered right before the failure
+		select {
+		case res := <-result:
+			return res.msg, res.err
+		default:
+			return nil, c.failure()
+		}
+	}
+}
+
+// Close closes the connection, failing every call still waiting with
+// [ErrClientClosed].
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClientClosed
+	}
+	c.mu.Unlock()
+
+	return c.conn.Close()
+}
+
+// register reserves a new correlation ID for a call.
+func (c *Client) register() (uint32, chan callResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, nil, c.err
+	}
+
+	// Skip IDs still in use after wrapping around
+	id := c.nextID
+	for {
+		if _, ok := c.pending[id]; !ok {
+			break
+		}
+		id++
+	}
+	c.nextID = id + 1
+
+	result := make(chan callResult, 1)
+	c.pending[id] = result
+	return id, result, nil
+}
+
+// failure returns the error that stopped the client.
+func (c *Client) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) unregister(id uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+}
+
+// readLoop delivers replies to the waiting calls until the connection fails.
+func (c *Client) readLoop() {
+	for {
+		hdr, body, err := c.conn.readFrame()
+		if err != nil {
+			c.fail(err)
+			return
+		} else if !hdr.correlated() {
+			continue
+		}
+
+		c.mu.Lock()
+		result, ok := c.pending[hdr.id]
+		delete(c.pending, hdr.id)
+		c.mu.Unlock()
+
+		if ok {
+			msg, err := c.conn.decodeFrame(body)
+			result <- callResult{msg, err}
+		}
+	}
+}
+
+// fail stops the client with the error, unless it was closed already.
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+	close(c.done)
+}
@@ -0,0 +1,123 @@
+package bytocol
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientCall(t *testing.T) {
+	srv := NewServer(nil)
+	defer srv.Close()
+
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		switch msg.String {
+		case "fail":
+			return errors.New("handler failed")
+		case "ignore":
+			return nil
+		}
+
+		// Reply out of order to exercise the correlation
+		go func() {
+			time.Sleep(time.Duration(msg.Uint%5) * time.Millisecond)
+			msg.Int = int(msg.Uint) * 2
+			s.Reply(ctx, msg)
+		}()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := Dial("tcp", startTestServer(t, srv), WithRegistry(srv.Registry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			req := testMessageObj
+			req.Uint = uint16(i)
+			reply, err := client.Call(context.Background(), req)
+			if err != nil {
+				t.Error(err)
+			} else if reply.(testMessage).Int != i*2 {
+				t.Errorf("call %d got reply %+v", i, reply)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	failing := testMessageObj
+	failing.String = "fail"
+	var remote ErrorMessage
+	if _, err = client.Call(context.Background(), failing); !errors.As(err, &remote) || remote.Message != "handler failed" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	ignored := testMessageObj
+	ignored.String = "ignore"
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = client.Call(ctx, ignored); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	client.Close()
+	if _, err = client.Call(context.Background(), testMessageObj); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("expected client closed, got %v", err)
+	}
+}
+
+func TestClientCallConcurrent(t *testing.T) {
+	srv := NewServer(nil)
+	defer srv.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		if msg.String == "slow" {
+			close(started)
+			<-release
+		}
+		return s.Reply(ctx, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := Dial("tcp", startTestServer(t, srv), WithRegistry(srv.Registry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	slow := testMessageObj
+	slow.String = "slow"
+	slowDone := make(chan error)
+	go func() {
+		_, err := client.Call(context.Background(), slow)
+		slowDone <- err
+	}()
+	<-started
+
+	// The fast call overtakes the slow one still being handled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err = client.Call(ctx, testMessageObj); err != nil {
+		t.Errorf("expected fast call to complete, got %v", err)
+	}
+
+	close(release)
+	if err = <-slowDone; err != nil {
+		t.Error(err)
+	}
+}
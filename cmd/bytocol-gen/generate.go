@@ -0,0 +1,734 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bytocolPath is the import path of the runtime package.
+const bytocolPath = "github.com/maple-tech/bytocol"
+
+// generator builds the source of the generated file for a single package.
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string
+
+	// usesTime is set once the time helpers are needed.
+	usesTime bool
+	// usesNaNKeys is set once the helpers for maps with float keys are needed.
+	usesNaNKeys bool
+}
+
+// message is a message type found in the package along with its planned fields.
+type message struct {
+	name   string
+	fields []*entry
+}
+
+func newGenerator(pkg *types.Package) *generator {
+	return &generator{
+		pkg: pkg,
+		imports: map[string]string{
+			"encoding/binary": "binary",
+			"errors":          "errors",
+			"fmt":             "fmt",
+			"io":              "io",
+			"math":            "math",
+			"slices":          "slices",
+			bytocolPath:       "bytocol",
+		},
+	}
+}
+
+// qualifier names types of other packages by their package name, recording the
+// import they need.
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	g.imports[pkg.Path()] = pkg.Name()
+	return pkg.Name()
+}
+
+func (g *generator) typeString(typ types.Type) string {
+	return types.TypeString(typ, g.qualifier)
+}
+
+// useTime records the time helpers and their imports are needed.
+func (g *generator) useTime() {
+	g.usesTime = true
+	g.use("math")
+	g.use("time")
+}
+
+// useNaNKeys records the helpers ordering the pairs of NaN map keys and their
+// imports are needed.
+func (g *generator) useNaNKeys() {
+	g.usesNaNKeys = true
+	g.use("bytes")
+	g.use("slices")
+}
+
+// use records an import needed by the generated code.
+func (g *generator) use(path string) {
+	g.imports[path] = path[strings.LastIndexByte(path, '/')+1:]
+}
+
+// planMessage plans the fields of the named message type.
+func (g *generator) planMessage(named *types.Named) (message, error) {
+	msg := message{name: named.Obj().Name()}
+
+	st := named.Underlying().(*types.Struct)
+	fields, err := g.planFields(st, "order", []types.Type{named})
+	if err != nil {
+		return msg, fmt.Errorf("%s: %w", msg.name, err)
+	} else if len(fields) == 0 {
+		return msg, fmt.Errorf("%s: no exported fields", msg.name)
+	}
+
+	msg.fields = fields
+	return msg, nil
+}
+
+// generate returns the formatted source of the generated file holding the
+// methods of every message.
+func (g *generator) generate(messages []message) ([]byte, error) {
+	var body bytes.Buffer
+	for _, msg := range messages {
+		g.writeMarshal(&body, msg)
+		g.writeSize(&body, msg)
+		g.writeUnmarshal(&body, msg)
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by bytocol-gen. DO NOT EDIT.\n\n")
+	src.WriteString("package " + g.pkg.Name() + "\n\n")
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if isStdImport(paths[i]) != isStdImport(paths[j]) {
+			return isStdImport(paths[i])
+		}
+		return paths[i] < paths[j]
+	})
+
+	// Standard library imports first, then the others
+	src.WriteString("import (\n")
+	for i, path := range paths {
+		if i > 0 && isStdImport(paths[i-1]) && !isStdImport(path) {
+			src.WriteByte('\n')
+		}
+		src.WriteString(strconv.Quote(path) + "\n")
+	}
+	src.WriteString(")\n\n")
+	src.Write(body.Bytes())
+	src.WriteString(helpers)
+	if g.usesTime {
+		src.WriteString(timeHelpers)
+	}
+	if g.usesNaNKeys {
+		src.WriteString(nanKeyHelpers)
+	}
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return src.Bytes(), fmt.Errorf("formatting generated source: %w", err)
+	}
+	return formatted, nil
+}
+
+// isStdImport returns true for the import paths of the standard library, which
+// have no dot in their first element.
+func isStdImport(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func (g *generator) writeMarshal(out *bytes.Buffer, msg message) {
+	fn := g.newFunc()
+	for _, field := range msg.fields {
+		fn.printf("\n// %s\n", field.name)
+		fn.encode(field, "m."+field.name, field.name)
+	}
+
+	fmt.Fprintf(out, "// MarshalBytocol appends the encoded %s to the buffer, see [bytocol.Marshaler].\n", msg.name)
+	fmt.Fprintf(out, "func (m %s) MarshalBytocol(b []byte) ([]byte, error) {\n", msg.name)
+	out.WriteString("info := m.BytocolMessage()\n")
+	if fn.usesOrder {
+		out.WriteString("order := bytocolByteOrder(info.ByteOrder)\n")
+	}
+	if fn.usesErr {
+		out.WriteString("var err error\n")
+	}
+	out.WriteString("b = append(b, info.TypeIndicator)\n")
+	out.Write(fn.body.Bytes())
+	out.WriteString("return b, nil\n}\n\n")
+}
+
+func (g *generator) writeSize(out *bytes.Buffer, msg message) {
+	fn := g.newFunc()
+	for _, field := range msg.fields {
+		fn.size(field, "m."+field.name)
+	}
+
+	fmt.Fprintf(out, "// SizeBytocol returns the encoded size of %s, see [bytocol.Marshaler].\n", msg.name)
+	fmt.Fprintf(out, "func (m %s) SizeBytocol() int {\n", msg.name)
+	out.WriteString("n := 1\n")
+	out.Write(fn.body.Bytes())
+	out.WriteString("return n\n}\n\n")
+}
+
+func (g *generator) writeUnmarshal(out *bytes.Buffer, msg message) {
+	fn := g.newFunc()
+	for _, field := range msg.fields {
+		fn.printf("\n// %s\n", field.name)
+		fn.printf("start = d.n\n")
+		fn.decode(field, "m."+field.name)
+		fn.printf("if d.err != nil {\nreturn d.error(%q, %d, start)\n}\n", field.name, field.order)
+	}
+
+	fmt.Fprintf(out, "// UnmarshalBytocol decodes %s without its type indicator, see [bytocol.Unmarshaler].\n", msg.name)
+	fmt.Fprintf(out, "func (m *%s) UnmarshalBytocol(r io.Reader) error {\n", msg.name)
+	out.WriteString("info := m.BytocolMessage()\n")
+	if fn.usesOrder {
+		out.WriteString("order := bytocolByteOrder(info.ByteOrder)\n")
+	}
+	out.WriteString("d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}\n")
+	out.WriteString("var start int64\n")
+	out.Write(fn.body.Bytes())
+	out.WriteString("return nil\n}\n\n")
+}
+
+// funcWriter writes the body of a single generated function.
+type funcWriter struct {
+	g         *generator
+	body      bytes.Buffer
+	temps     int
+	usesOrder bool
+	usesErr   bool
+}
+
+func (g *generator) newFunc() *funcWriter {
+	return &funcWriter{g: g}
+}
+
+func (fn *funcWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&fn.body, format, args...)
+}
+
+// temp returns a new unique variable name.
+func (fn *funcWriter) temp(prefix string) string {
+	fn.temps++
+	return prefix + strconv.Itoa(fn.temps)
+}
+
+// order returns the byte order expression of the entry.
+func (fn *funcWriter) order(e *entry) string {
+	if e.byteOrder == "order" {
+		fn.usesOrder = true
+	}
+	return e.byteOrder
+}
+
+func (fn *funcWriter) typeString(typ types.Type) string {
+	return fn.g.typeString(typ)
+}
+
+// encode writes the statements appending the value to b. The field is the name
+// of the top-level field used in errors.
+func (fn *funcWriter) encode(e *entry, v string, field string) {
+	switch e.kind {
+	case kindBool:
+		fn.printf("if %s {\nb = append(b, 1)\n} else {\nb = append(b, 0)\n}\n", v)
+	case kindUint, kindInt:
+		if e.varint {
+			if e.kind == kindInt {
+				fn.printf("b = binary.AppendVarint(b, int64(%s))\n", v)
+			} else {
+				fn.printf("b = binary.AppendUvarint(b, uint64(%s))\n", v)
+			}
+		} else if e.bits == 8 {
+			fn.printf("b = append(b, byte(%s))\n", v)
+		} else {
+			fn.printf("b = bytocolAppend%d(b, %s, uint%d(%s))\n", e.bits, fn.order(e), e.bits, v)
+		}
+	case kindFloat:
+		fn.g.use("math")
+		fn.printf("b = bytocolAppend%d(b, %s, math.Float%dbits(float%d(%s)))\n", e.bits, fn.order(e), e.bits, e.bits, v)
+	case kindString:
+		if !types.Identical(e.typ, types.Typ[types.String]) {
+			v = "string(" + v + ")"
+		}
+		fn.encodeBlob(e, v, true, field)
+	case kindBytes:
+		fn.encodeBlob(e, fn.byteSlice(e, v), false, field)
+	case kindSlice:
+		if e.elem.zeroSize() {
+			// Their count could not be told apart from a malformed one
+			msg := fmt.Sprintf("bytocol: slice of zero size elements %s must be empty", fn.typeString(e.typ))
+			fn.printf("if len(%s) > 0 {\nreturn b, bytocolFieldError(%q, errors.New(%q))\n}\n", v, field, msg)
+		}
+		fn.encodePrefix(e, "len("+v+")", field)
+		i := fn.temp("i")
+		fn.printf("for %s := range %s {\n", i, v)
+		fn.encode(e.elem, v+"["+i+"]", field)
+		fn.printf("}\n")
+	case kindArray:
+		i := fn.temp("i")
+		fn.printf("for %s := range %s {\n", i, v)
+		fn.encode(e.elem, v+"["+i+"]", field)
+		fn.printf("}\n")
+	case kindMap:
+		fn.encodePrefix(e, "len("+v+")", field)
+		pairType, pairs, k, x := fn.temp("pair"), fn.temp("pairs"), fn.temp("k"), fn.temp("x")
+		fn.printf("type %s struct {\nk %s\nv %s\n}\n", pairType, fn.typeString(e.key.typ), fn.typeString(e.elem.typ))
+		fn.printf("%s := make([]%s, 0, len(%s))\n", pairs, pairType, v)
+		fn.printf("for %s, %s := range %s {\n%s = append(%s, %s{%s, %s})\n}\n", k, x, v, pairs, pairs, pairType, k, x)
+
+		// Pairs are sorted by key so that the output is deterministic. They are
+		// collected while ranging, as NaN keys cannot be looked up again.
+		fn.g.use("cmp")
+		fn.g.use("slices")
+		if e.key.kind == kindBool {
+			fn.printf("slices.SortFunc(%s, func(x, y %s) int {\nreturn cmp.Compare(bytocolBool(bool(x.k)), bytocolBool(bool(y.k)))\n})\n", pairs, pairType)
+		} else {
+			fn.printf("slices.SortFunc(%s, func(x, y %s) int {\nreturn cmp.Compare(x.k, y.k)\n})\n", pairs, pairType)
+		}
+
+		// NaN keys are all equal to each other and sorted first, so the end of
+		// each of their pairs is recorded to order them by their encoding.
+		p, start, ends := fn.temp("p"), "", ""
+		if e.key.kind == kindFloat {
+			fn.g.useNaNKeys()
+			start, ends = fn.temp("start"), fn.temp("ends")
+			fn.printf("%s := len(b)\nvar %s []int\n", start, ends)
+		}
+		fn.printf("for _, %s := range %s {\n", p, pairs)
+		fn.encode(e.key, p+".k", field)
+		fn.encode(e.elem, p+".v", field)
+		if e.key.kind == kindFloat {
+			fn.printf("if %s.k != %s.k {\n%s = append(%s, len(b))\n}\n", p, p, ends, ends)
+		}
+		fn.printf("}\n")
+		if e.key.kind == kindFloat {
+			fn.printf("bytocolSortPairs(b, %s, %s)\n", start, ends)
+		}
+	case kindPointer:
+		fn.printf("if %s == nil {\nb = append(b, 0)\n} else {\nb = append(b, 1)\n", v)
+		fn.encode(e.elem, "(*"+v+")", field)
+		fn.printf("}\n")
+	case kindStruct:
+		for _, nested := range e.fields {
+			fn.encode(nested, v+"."+nested.name, field)
+		}
+	case kindCodec:
+		fn.usesErr = true
+		data := fn.temp("data")
+		fn.printf("var %s []byte\n", data)
+		fn.printf("if %s, err = %s.Marshal%s(); err != nil {\nreturn b, bytocolFieldError(%q, err)\n}\n", data, v, e.codec, field)
+		if e.sized {
+			typ := fn.typeString(e.typ)
+			fn.printf("if size := new(%s).BytocolFieldSize(); len(%s) != size {\n", typ, data)
+			fn.printf("return b, bytocolFieldError(%q, fmt.Errorf(\"bytocol: codec for %s encoded %%d bytes, expected %%d\", len(%s), size))\n}\n", field, typ, data)
+		} else {
+			fn.encodePrefix(e, "len("+data+")", field)
+		}
+		fn.printf("b = append(b, %s...)\n", data)
+	case kindDuration:
+		fn.g.useTime()
+		fn.encodeTimeInt(e, durationUnits(e, v))
+	case kindTime:
+		fn.g.useTime()
+		fn.usesErr = true
+		units := fn.temp("units")
+		fn.printf("var %s int64\n", units)
+		fn.printf("if %s, err = bytocolUnixTime(%s, %s); err != nil {\nreturn b, bytocolFieldError(%q, err)\n}\n", units, v, unitExpr(e.unit), field)
+		fn.encodeTimeInt(e, units)
+		if e.timeZone {
+			offset := fn.temp("offset")
+			fn.printf("_, %s := %s.Zone()\n", offset, v)
+			if e.varint {
+				fn.printf("b = binary.AppendVarint(b, int64(%s))\n", offset)
+			} else {
+				fn.printf("b = bytocolAppend32(b, %s, uint32(int32(%s)))\n", fn.order(e), offset)
+			}
+		}
+	}
+}
+
+// encodeTimeInt writes the signed integer of a time or duration.
+func (fn *funcWriter) encodeTimeInt(e *entry, units string) {
+	if e.varint {
+		fn.printf("b = binary.AppendVarint(b, %s)\n", units)
+	} else {
+		fn.printf("b = bytocolAppend64(b, %s, uint64(%s))\n", fn.order(e), units)
+	}
+}
+
+// durationUnits returns the expression of the duration in its unit.
+func durationUnits(e *entry, v string) string {
+	if e.unit == time.Nanosecond {
+		return "int64(" + v + ")"
+	}
+	return "int64(" + v + " / " + unitExpr(e.unit) + ")"
+}
+
+// unitExpr returns the expression of the time unit.
+func unitExpr(unit time.Duration) string {
+	switch unit {
+	case time.Second:
+		return "time.Second"
+	case time.Millisecond:
+		return "time.Millisecond"
+	case time.Microsecond:
+		return "time.Microsecond"
+	}
+	return "time.Nanosecond"
+}
+
+// byteSlice returns an expression of the byte slice value as a []byte. Slices of
+// named byte types are copied into a temporary []byte first.
+func (fn *funcWriter) byteSlice(e *entry, v string) string {
+	elem := e.typ.Underlying().(*types.Slice).Elem()
+	if types.Identical(elem, types.Typ[types.Uint8]) {
+		return v
+	}
+
+	data, i, c := fn.temp("data"), fn.temp("i"), fn.temp("c")
+	fn.printf("%s := make([]byte, len(%s))\n", data, v)
+	fn.printf("for %s, %s := range %s {\n%s[%s] = byte(%s)\n}\n", i, c, v, data, i, c)
+	return data
+}
+
+// encodeBlob writes the string or byte data with either a NUL terminator or a
+// length prefix.
+func (fn *funcWriter) encodeBlob(e *entry, data string, isString bool, field string) {
+	if e.nullTerminated {
+		if isString {
+			fn.g.use("strings")
+			fn.printf("if strings.IndexByte(%s, 0) != -1 {\n", data)
+		} else {
+			fn.g.use("bytes")
+			fn.printf("if bytes.IndexByte(%s, 0) != -1 {\n", data)
+		}
+		fn.printf("return b, bytocolFieldError(%q, bytocol.ErrNullInContent)\n}\n", field)
+		fn.printf("b = append(b, %s...)\nb = append(b, 0)\n", data)
+		return
+	}
+
+	fn.encodePrefix(e, "len("+data+")", field)
+	fn.printf("b = append(b, %s...)\n", data)
+}
+
+// encodePrefix writes the length or count prefix of the entry.
+func (fn *funcWriter) encodePrefix(e *entry, length string, field string) {
+	if e.varint {
+		fn.printf("b = binary.AppendUvarint(b, uint64(%s))\n", length)
+		return
+	}
+
+	fn.usesErr = true
+	fn.printf("if b, err = bytocolAppendLength(b, %s, %d, uint64(%s)); err != nil {\n", fn.order(e), e.lengthBits, length)
+	fn.printf("return b, bytocolFieldError(%q, err)\n}\n", field)
+}
+
+// prefixSize returns the expression of the encoded size of a length prefix.
+func (fn *funcWriter) prefixSize(e *entry, length string) string {
+	if e.varint {
+		return "bytocolUvarintSize(uint64(" + length + "))"
+	}
+	return strconv.Itoa(int(e.lengthBits / 8))
+}
+
+// fixedSize returns the expression of the encoded size of the entry and true
+// if it does not depend on the value.
+func (fn *funcWriter) fixedSize(e *entry) (string, bool) {
+	if size, ok := e.fixedSize(); ok {
+		return strconv.Itoa(size), true
+	} else if e.kind == kindCodec && e.sized {
+		return "new(" + fn.typeString(e.typ) + ").BytocolFieldSize()", true
+	}
+	return "", false
+}
+
+// size writes the statements adding the encoded size of the value to n.
+func (fn *funcWriter) size(e *entry, v string) {
+	if size, ok := fn.fixedSize(e); ok {
+		fn.printf("n += %s\n", size)
+		return
+	}
+
+	switch e.kind {
+	case kindUint:
+		fn.printf("n += bytocolUvarintSize(uint64(%s))\n", v)
+	case kindInt:
+		fn.printf("n += bytocolVarintSize(int64(%s))\n", v)
+	case kindString, kindBytes:
+		if e.nullTerminated {
+			fn.printf("n += len(%s) + 1\n", v)
+		} else {
+			fn.printf("n += %s + len(%s)\n", fn.prefixSize(e, "len("+v+")"), v)
+		}
+	case kindSlice, kindArray:
+		if e.kind == kindSlice {
+			fn.printf("n += %s\n", fn.prefixSize(e, "len("+v+")"))
+		}
+		if size, ok := fn.fixedSize(e.elem); ok {
+			fn.printf("n += len(%s) * %s\n", v, size)
+			break
+		}
+
+		i := fn.temp("i")
+		fn.printf("for %s := range %s {\n", i, v)
+		fn.size(e.elem, v+"["+i+"]")
+		fn.printf("}\n")
+	case kindMap:
+		fn.printf("n += %s\n", fn.prefixSize(e, "len("+v+")"))
+		keySize, keyFixed := fn.fixedSize(e.key)
+		elemSize, elemFixed := fn.fixedSize(e.elem)
+		if keyFixed && elemFixed {
+			fn.printf("n += len(%s) * (%s + %s)\n", v, keySize, elemSize)
+			break
+		}
+
+		k, x := "_", "_"
+		if !keyFixed {
+			k = fn.temp("k")
+		}
+		if !elemFixed {
+			x = fn.temp("x")
+		}
+		if x == "_" {
+			fn.printf("for %s := range %s {\n", k, v)
+		} else {
+			fn.printf("for %s, %s := range %s {\n", k, x, v)
+		}
+		fn.size(e.key, k)
+		fn.size(e.elem, x)
+		fn.printf("}\n")
+	case kindPointer:
+		fn.printf("n++\nif %s != nil {\n", v)
+		fn.size(e.elem, "(*"+v+")")
+		fn.printf("}\n")
+	case kindStruct:
+		for _, nested := range e.fields {
+			fn.size(nested, v+"."+nested.name)
+		}
+	case kindCodec:
+		// The size is only known once encoded, errors are left to the encoder
+		data := fn.temp("data")
+		fn.printf("if %s, err := %s.Marshal%s(); err == nil {\n", data, v, e.codec)
+		fn.printf("n += %s + len(%s)\n}\n", fn.prefixSize(e, "len("+data+")"), data)
+	case kindDuration:
+		// Only varints vary in size
+		fn.printf("n += bytocolVarintSize(%s)\n", durationUnits(e, v))
+	case kindTime:
+		units := fn.temp("units")
+		fn.printf("if %s, err := bytocolUnixTime(%s, %s); err == nil {\nn += bytocolVarintSize(%s)\n}\n", units, v, unitExpr(e.unit), units)
+		if e.timeZone {
+			offset := fn.temp("offset")
+			fn.printf("_, %s := %s.Zone()\nn += bytocolVarintSize(int64(%s))\n", offset, v, offset)
+		}
+	}
+}
+
+// decode writes the statements reading the value from the decoder into the
+// addressable expression. Decoding errors are kept on the decoder.
+func (fn *funcWriter) decode(e *entry, v string) {
+	typ := fn.typeString(e.typ)
+
+	// Structs, and values containing elements, are nested one level deeper
+	nested := e.elem != nil || e.kind == kindStruct
+	if nested {
+		fn.printf("d.enter()\n")
+	}
+
+	switch e.kind {
+	case kindBool:
+		fn.printf("%s = d.read(1)[0] == 1\n", v)
+	case kindUint, kindInt:
+		if e.varint {
+			fn.decodeVarint(e, v, typ)
+			break
+		}
+
+		raw, rawType := "d.read(1)[0]", "uint8"
+		if e.bits > 8 {
+			raw = fmt.Sprintf("%s.Uint%d(d.read(%d))", fn.order(e), e.bits, e.bits/8)
+			rawType = fmt.Sprintf("uint%d", e.bits)
+		}
+		if e.kind == kindInt {
+			rawType = fmt.Sprintf("int%d", e.bits)
+			raw = rawType + "(" + raw + ")"
+		}
+		fn.printf("%s = %s\n", v, convert(typ, rawType, raw))
+	case kindFloat:
+		fn.g.use("math")
+		raw := fmt.Sprintf("math.Float%dfrombits(%s.Uint%d(d.read(%d)))", e.bits, fn.order(e), e.bits, e.bits/8)
+		fn.printf("%s = %s\n", v, convert(typ, fmt.Sprintf("float%d", e.bits), raw))
+	case kindString, kindBytes:
+		data := fn.temp("data")
+		if e.nullTerminated {
+			fn.printf("if %s := d.terminated(%d); d.err == nil {\n", data, e.max)
+		} else {
+			fn.printf("if %s := d.bytes(%s, %d); d.err == nil {\n", data, fn.decodePrefix(e), e.max)
+		}
+
+		if e.kind == kindString {
+			fn.printf("%s = %s(%s)\n", v, typ, data)
+		} else if elem := e.typ.Underlying().(*types.Slice).Elem(); types.Identical(elem, types.Typ[types.Uint8]) {
+			fn.printf("%s = %s(%s)\n", v, typ, data)
+		} else {
+			// Slices of named byte types are converted one by one
+			s, i, c := fn.temp("s"), fn.temp("i"), fn.temp("c")
+			fn.printf("%s := make(%s, len(%s))\n", s, typ, data)
+			fn.printf("for %s, %s := range %s {\n%s[%s] = %s(%s)\n}\n", i, c, data, s, i, fn.typeString(elem), c)
+			fn.printf("%s = %s\n", v, s)
+		}
+		fn.printf("}\n")
+	case kindSlice:
+		if e.elem.zeroSize() {
+			// Elements encoded as nothing would be read forever
+			count := fn.temp("count")
+			fn.printf("if %s := d.count(%s, %d); %s > 0 {\n", count, fn.decodePrefix(e), e.max, count)
+			fn.printf("d.fail(fmt.Errorf(\"%%w: count %%d of zero size elements exceeds 0\", bytocol.ErrLimitExceeded, %s))\n", count)
+			fn.printf("} else if d.err == nil {\n%s = make(%s, 0)\n}\n", v, typ)
+			break
+		}
+
+		// The slice grows as the elements are read rather than being
+		// allocated for the count upfront
+		count, s, i := fn.temp("count"), fn.temp("s"), fn.temp("i")
+		fn.printf("if %s := d.count(%s, %d); d.err == nil {\n", count, fn.decodePrefix(e), e.max)
+		fn.printf("%s := make(%s, 0, min(%s, %d))\n", s, typ, count, preallocCount(e.elem.typ))
+		fn.printf("for %s := 0; %s < %s && d.err == nil; %s++ {\n", i, i, count, i)
+		fn.printf("%s = append(%s, *new(%s))\n", s, s, fn.typeString(e.elem.typ))
+		fn.decode(e.elem, s+"["+i+"]")
+		fn.printf("}\nif d.err == nil {\n%s = %s\n}\n}\n", v, s)
+	case kindArray:
+		i := fn.temp("i")
+		fn.printf("for %s := 0; %s < len(%s) && d.err == nil; %s++ {\n", i, i, v, i)
+		fn.decode(e.elem, v+"["+i+"]")
+		fn.printf("}\n")
+	case kindMap:
+		count, mp, i, k, x := fn.temp("count"), fn.temp("m"), fn.temp("i"), fn.temp("k"), fn.temp("x")
+		fn.printf("if %s := d.count(%s, %d); d.err == nil {\n", count, fn.decodePrefix(e), e.max)
+		fn.printf("%s := make(%s, min(%s, %d))\n", mp, typ, count, preallocCount(e.elem.typ))
+		fn.printf("for %s := 0; %s < %s && d.err == nil; %s++ {\n", i, i, count, i)
+		fn.printf("var %s %s\n", k, fn.typeString(e.key.typ))
+		fn.decode(e.key, k)
+		fn.printf("var %s %s\n", x, fn.typeString(e.elem.typ))
+		fn.decode(e.elem, x)
+		fn.printf("%s[%s] = %s\n}\nif d.err == nil {\n%s = %s\n}\n}\n", mp, k, x, v, mp)
+	case kindPointer:
+		p, x := fn.temp("present"), fn.temp("x")
+		fn.printf("switch %s := d.read(1)[0]; {\ncase d.err != nil:\n", p)
+		fn.printf("case %s == 0:\n%s = nil\n", p, v)
+		fn.printf("case %s == 1:\n%s := new(%s)\n", p, x, fn.typeString(e.elem.typ))
+		fn.decode(e.elem, "(*"+x+")")
+		fn.printf("if d.err == nil {\n%s = %s\n}\n", v, x)
+		fn.printf("default:\nd.fail(fmt.Errorf(\"%%w: %%d\", bytocol.ErrInvalidPresence, %s))\n}\n", p)
+	case kindStruct:
+		for _, nested := range e.fields {
+			fn.decode(nested, v+"."+nested.name)
+		}
+	case kindCodec:
+		data, x := fn.temp("data"), fn.temp("x")
+		if e.sized {
+			fn.printf("if %s := d.fixed(new(%s).BytocolFieldSize()); d.err == nil {\n", data, typ)
+		} else {
+			fn.printf("if %s := d.bytes(%s, %d); d.err == nil {\n", data, fn.decodePrefix(e), e.max)
+		}
+		fn.printf("var %s %s\nif err := %s.Unmarshal%s(%s); err != nil {\nd.fail(err)\n} else {\n%s = %s\n}\n}\n", x, typ, x, e.codec, data, v, x)
+	case kindDuration:
+		x := fn.temp("x")
+		fn.printf("if %s := bytocolDuration(d, %s, %s); d.err == nil {\n%s = %s\n}\n", x, fn.decodeTimeInt(e, 8), unitExpr(e.unit), v, x)
+	case kindTime:
+		units, offset, x := fn.temp("units"), "0", fn.temp("x")
+		fn.printf("%s := %s\n", units, fn.decodeTimeInt(e, 8))
+		if e.timeZone {
+			offset = fn.decodeTimeInt(e, 4)
+		}
+		fn.printf("if %s := bytocolTime(d, %s, %s, %s); d.err == nil {\n%s = %s\n}\n", x, units, unitExpr(e.unit), offset, v, x)
+	}
+
+	if nested {
+		fn.printf("d.leave()\n")
+	}
+}
+
+// preallocSize is the largest number of bytes of elements allocated before they
+// are read, matching the runtime.
+const preallocSize = 1 << 16
+
+// preallocSizes computes the element sizes like the gc compiler does on 64-bit
+// platforms, so the generated code does not depend on the platform.
+var preallocSizes = types.SizesFor("gc", "amd64")
+
+// preallocCount returns the number of elements of the type allocated before
+// they are read.
+func preallocCount(typ types.Type) int64 {
+	return preallocSize / max(preallocSizes.Sizeof(typ), 1)
+}
+
+// decodeTimeInt returns the expression reading the signed integer of a time or
+// duration, either variable-length or of the fixed size in bytes.
+func (fn *funcWriter) decodeTimeInt(e *entry, size int) string {
+	if e.varint {
+		return "d.varint()"
+	} else if size == 4 {
+		return fmt.Sprintf("int64(int32(%s.Uint32(d.read(4))))", fn.order(e))
+	}
+	return fmt.Sprintf("int64(%s.Uint64(d.read(8)))", fn.order(e))
+}
+
+// decodeVarint writes the statements reading a variable-length integer,
+// failing if the value does not fit in the type.
+func (fn *funcWriter) decodeVarint(e *entry, v string, typ string) {
+	read, check := "d.uvarint()", ""
+	if e.kind == kindInt {
+		read = "d.varint()"
+		if e.bits < 64 {
+			check = fmt.Sprintf("%%s < -1<<%d || %%s > 1<<%d-1", e.bits-1, e.bits-1)
+		}
+	} else if e.bits < 64 {
+		check = fmt.Sprintf("%%s > 1<<%d-1", e.bits)
+	}
+
+	if check == "" {
+		fn.printf("%s = %s(%s)\n", v, typ, read)
+		return
+	}
+
+	x := fn.temp("x")
+	cond := strings.ReplaceAll(check, "%s", x)
+	fn.printf("if %s := %s; %s {\n", x, read, cond)
+	fn.printf("d.fail(fmt.Errorf(\"%%w: %%d does not fit in %s\", bytocol.ErrVarintOverflow, %s))\n", typ, x)
+	fn.printf("} else {\n%s = %s(%s)\n}\n", v, typ, x)
+}
+
+// convert returns the expression converted to the type, unless it already has
+// that type.
+func convert(typ string, exprType string, expr string) string {
+	if typ == exprType || (typ == "byte" && exprType == "uint8") {
+		return expr
+	}
+	return typ + "(" + expr + ")"
+}
+
+// decodePrefix returns the expression reading the length or count prefix.
+func (fn *funcWriter) decodePrefix(e *entry) string {
+	if e.varint {
+		return "d.uvarint()"
+	}
+	return fmt.Sprintf("d.length(%s, %d)", fn.order(e), e.lengthBits)
+}
@@ -0,0 +1,363 @@
+package main
+
+// helpers is the source of the unexported functions used by the generated
+// methods. It is written once at the end of every generated file.
+const helpers = `
+// bytocolByteOrder returns the byte order of the message, big-endian unless
+// declared otherwise.
+func bytocolByteOrder(order binary.ByteOrder) binary.ByteOrder {
+	if order == nil {
+		return binary.BigEndian
+	}
+	return order
+}
+
+func bytocolBool(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func bytocolAppend16(b []byte, order binary.ByteOrder, v uint16) []byte {
+	var buf [2]byte
+	order.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func bytocolAppend32(b []byte, order binary.ByteOrder, v uint32) []byte {
+	var buf [4]byte
+	order.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func bytocolAppend64(b []byte, order binary.ByteOrder, v uint64) []byte {
+	var buf [8]byte
+	order.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// bytocolAppendLength appends a length prefix of the given bit-size, failing if
+// the length does not fit.
+func bytocolAppendLength(b []byte, order binary.ByteOrder, bits byte, length uint64) ([]byte, error) {
+	if bits < 64 && length > (uint64(1)<<bits)-1 {
+		return b, fmt.Errorf("%w: length %d exceeds %d-bit length prefix", bytocol.ErrLengthOverflow, length, bits)
+	}
+
+	switch bits {
+	case 8:
+		return append(b, byte(length)), nil
+	case 16:
+		return bytocolAppend16(b, order, uint16(length)), nil
+	case 32:
+		return bytocolAppend32(b, order, uint32(length)), nil
+	}
+	return bytocolAppend64(b, order, length), nil
+}
+
+func bytocolUvarintSize(v uint64) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+	return size
+}
+
+func bytocolVarintSize(v int64) int {
+	return bytocolUvarintSize(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func bytocolFieldError(field string, err error) error {
+	return fmt.Errorf("bytocol: error writing field %s: %w", field, err)
+}
+
+// bytocolDecoder reads the fields of a message, keeping the first error and
+// the number of bytes read. It enforces the same limits as the runtime.
+type bytocolDecoder struct {
+	r       io.Reader
+	message string
+	n       int64
+	buf     [8]byte
+	err     error
+	limits  bytocol.DecodeLimits
+	depth   int
+}
+
+func (d *bytocolDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *bytocolDecoder) error(field string, order uint, start int64) error {
+	return &bytocol.DecodeError{Message: d.message, Field: field, Order: order, Offset: 1 + start, Err: d.err}
+}
+
+// readFull fills the buffer, reporting short reads like the runtime does.
+func (d *bytocolDecoder) readFull(buf []byte) {
+	n, err := io.ReadFull(d.r, buf)
+	d.n += int64(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if n > 0 {
+			err = fmt.Errorf("%w, read %d of %d bytes: %w", bytocol.ErrReadInvariance, n, len(buf), io.ErrUnexpectedEOF)
+		} else {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		d.fail(err)
+		clear(buf)
+	}
+}
+
+// read returns the next size bytes, or zeroes once failed.
+func (d *bytocolDecoder) read(size int) []byte {
+	buf := d.buf[:size]
+	if d.err != nil {
+		clear(buf)
+		return buf
+	}
+	d.readFull(buf)
+	return buf
+}
+
+// limit fails if the value is larger than the limit, zero not being limited.
+func (d *bytocolDecoder) limit(what string, value uint64, limit uint64) bool {
+	if limit != 0 && value > limit {
+		d.fail(fmt.Errorf("%w: %s %d exceeds %d", bytocol.ErrLimitExceeded, what, value, limit))
+	}
+	return d.err == nil
+}
+
+// fixed reads the size bytes of a fixed size codec, which the blob limits do
+// not apply to.
+func (d *bytocolDecoder) fixed(size int) []byte {
+	buf := make([]byte, size)
+	if d.err == nil {
+		d.readFull(buf)
+	}
+	return buf
+}
+
+// bytes reads the length bytes once checked against the limits, growing the
+// buffer as the data is received rather than allocating the length upfront.
+func (d *bytocolDecoder) bytes(length uint64, max uint64) []byte {
+	if d.err != nil || !d.limit("length", length, max) || !d.limit("blob size", length, d.limits.MaxBlobSize) {
+		return nil
+	} else if maxSize := d.limits.MaxMessageSize; maxSize != 0 {
+		remaining := maxSize - min(uint64(1+d.n), maxSize)
+		if length > remaining {
+			d.fail(fmt.Errorf("%w: length %d exceeds the %d bytes left of the message", bytocol.ErrLimitExceeded, length, remaining))
+			return nil
+		}
+	}
+	if length > math.MaxInt {
+		d.fail(fmt.Errorf("%w: length %d does not fit in an int", bytocol.ErrLengthOverflow, length))
+		return nil
+	}
+
+	size := int(length)
+	buf := make([]byte, 0, min(size, 1<<16))
+	for len(buf) < size && d.err == nil {
+		if len(buf) == cap(buf) {
+			buf = slices.Grow(buf, min(size-len(buf), len(buf)))
+		}
+
+		n, err := io.ReadFull(d.r, buf[len(buf):min(cap(buf), size)])
+		buf = buf[:len(buf)+n]
+		d.n += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if len(buf) > 0 {
+				err = fmt.Errorf("%w, read %d of %d bytes: %w", bytocol.ErrReadInvariance, len(buf), size, io.ErrUnexpectedEOF)
+			} else {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		if err != nil {
+			d.fail(err)
+		}
+	}
+	return buf
+}
+
+// terminated reads until the NUL terminator, failing once the content exceeds
+// the limits.
+func (d *bytocolDecoder) terminated(max uint64) []byte {
+	limit := d.limits.MaxBlobSize
+	if max != 0 && (limit == 0 || max < limit) {
+		limit = max
+	}
+
+	content := make([]byte, 0)
+	for {
+		c := d.read(1)[0]
+		if d.err != nil || c == 0 || !d.limit("length", uint64(len(content)+1), limit) {
+			return content
+		}
+		content = append(content, c)
+	}
+}
+
+// count checks the count of slice or map elements against the limits, which
+// must fit in an int for the elements to be allocated.
+func (d *bytocolDecoder) count(count uint64, max uint64) int {
+	if d.err != nil || !d.limit("count", count, max) || !d.limit("count", count, d.limits.MaxSliceCount) {
+		return 0
+	} else if count > math.MaxInt {
+		d.fail(fmt.Errorf("%w: count %d does not fit in an int", bytocol.ErrLengthOverflow, count))
+		return 0
+	}
+	return int(count)
+}
+
+// enter nests one level deeper, failing past the maximum depth. Each call is
+// followed by leave.
+func (d *bytocolDecoder) enter() {
+	if maxDepth := d.limits.MaxDepth; maxDepth != 0 && d.depth >= maxDepth {
+		d.fail(fmt.Errorf("%w: nesting depth exceeds %d", bytocol.ErrLimitExceeded, maxDepth))
+	}
+	d.depth++
+}
+
+func (d *bytocolDecoder) leave() {
+	d.depth--
+}
+
+func (d *bytocolDecoder) length(order binary.ByteOrder, bits byte) uint64 {
+	switch bits {
+	case 8:
+		return uint64(d.read(1)[0])
+	case 16:
+		return uint64(order.Uint16(d.read(2)))
+	case 32:
+		return uint64(order.Uint32(d.read(4)))
+	}
+	return order.Uint64(d.read(8))
+}
+
+func (d *bytocolDecoder) uvarint() uint64 {
+	var value uint64
+	var shift uint
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		c := d.read(1)[0]
+		if d.err != nil {
+			return 0
+		}
+
+		if c < 0x80 {
+			if i == binary.MaxVarintLen64-1 && c > 1 {
+				break
+			}
+			return value | uint64(c)<<shift
+		}
+		value |= uint64(c&0x7f) << shift
+		shift += 7
+	}
+
+	d.fail(bytocol.ErrVarintOverflow)
+	return 0
+}
+
+func (d *bytocolDecoder) varint() int64 {
+	unsigned := d.uvarint()
+	value := int64(unsigned >> 1)
+	if unsigned&1 != 0 {
+		value = ^value
+	}
+	return value
+}
+`
+
+// timeHelpers is the source of the functions used by generated methods of
+// messages with times or durations. It is only written when they are used.
+const timeHelpers = `
+// bytocolUnixTime returns the number of units since the Unix epoch, or
+// math.MinInt64 for the zero time which no other time may be encoded as.
+func bytocolUnixTime(t time.Time, unit time.Duration) (int64, error) {
+	if t.IsZero() {
+		return math.MinInt64, nil
+	}
+
+	var units int64
+	switch unit {
+	case time.Second:
+		units = t.Unix()
+	case time.Millisecond:
+		units = t.UnixMilli()
+	case time.Microsecond:
+		units = t.UnixMicro()
+	default:
+		if t.Before(time.Unix(0, math.MinInt64+1)) || t.After(time.Unix(0, math.MaxInt64)) {
+			return 0, fmt.Errorf("%w: %s in nanoseconds", bytocol.ErrTimeOverflow, t)
+		}
+		units = t.UnixNano()
+	}
+
+	if units == math.MinInt64 {
+		return 0, fmt.Errorf("%w: %s in %s", bytocol.ErrTimeOverflow, t, unit)
+	}
+	return units, nil
+}
+
+// bytocolTime returns the time of the number of units since the Unix epoch in
+// the zone of the offset, or UTC when the offset is 0. math.MinInt64 units
+// decode as the zero time.
+func bytocolTime(d *bytocolDecoder, units int64, unit time.Duration, offset int64) time.Time {
+	if offset < math.MinInt32 || offset > math.MaxInt32 {
+		d.fail(fmt.Errorf("%w: zone offset %d does not fit in int32", bytocol.ErrVarintOverflow, offset))
+	}
+
+	var t time.Time
+	switch {
+	case d.err != nil, units == math.MinInt64:
+		return t
+	case unit == time.Second:
+		t = time.Unix(units, 0)
+	case unit == time.Millisecond:
+		t = time.UnixMilli(units)
+	case unit == time.Microsecond:
+		t = time.UnixMicro(units)
+	default:
+		t = time.Unix(0, units)
+	}
+
+	if offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", int(offset)))
+}
+
+// bytocolDuration returns the duration of the number of units, failing if it
+// does not fit.
+func bytocolDuration(d *bytocolDecoder, units int64, unit time.Duration) time.Duration {
+	if d.err == nil && (units > math.MaxInt64/int64(unit) || units < math.MinInt64/int64(unit)) {
+		d.fail(fmt.Errorf("%w: %d units of %s as a duration", bytocol.ErrTimeOverflow, units, unit))
+	}
+	return time.Duration(units) * unit
+}
+`
+
+// nanKeyHelpers is the source of the functions used by generated methods of
+// messages with float map keys. It is only written when they are used.
+const nanKeyHelpers = `
+// bytocolSortPairs orders the encoded pairs of b that start at start and end
+// at each of the ends by their bytes. NaN map keys are all equal to each other,
+// so their pairs are ordered by their encoding instead.
+func bytocolSortPairs(b []byte, start int, ends []int) {
+	if len(ends) < 2 {
+		return
+	}
+
+	pairs := make([][]byte, len(ends))
+	from := start
+	for i, end := range ends {
+		pairs[i] = bytes.Clone(b[from:end])
+		from = end
+	}
+	slices.SortFunc(pairs, bytes.Compare)
+	for _, pair := range pairs {
+		start += copy(b[start:], pair)
+	}
+}
+`
@@ -0,0 +1,156 @@
+// Command bytocol-gen generates reflection-free encoders and decoders for the
+// bytocol message types of a Go package.
+//
+// For every struct type implementing bytocol.Message it writes the
+// MarshalBytocol, SizeBytocol, and UnmarshalBytocol methods, which produce
+// the same bytes as bytocol.TypePlan.Write and are preferred by the runtime
+// when present. It is meant to be used with go generate:
+//
+//	//go:generate go run github.com/maple-tech/bytocol/cmd/bytocol-gen
+//
+// Usage:
+//
+//	bytocol-gen [-output file] [-type T1,T2] [dir]
+//
+// The package in the directory, by default the current one, is type-checked
+// from source. The output file, by default bytocol_gen.go, is ignored while
+// loading the package so that stale methods do not get in the way.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func main() {
+	output := flag.String("output", "bytocol_gen.go", "name of the generated file, within the package directory")
+	typeList := flag.String("type", "", "comma separated message types to generate, all of them when empty")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var typeNames []string
+	if *typeList != "" {
+		typeNames = strings.Split(*typeList, ",")
+	}
+
+	src, err := generate(dir, *output, typeNames)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bytocol-gen: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, *output), src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "bytocol-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate loads the package in the directory and returns the source of the
+// generated file for the message types named, or all of them.
+func generate(dir string, output string, typeNames []string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	} else if pkg.Path() == bytocolPath {
+		return nil, errors.New("cannot generate within the bytocol package itself")
+	}
+
+	g := newGenerator(pkg)
+	messages := make([]message, 0)
+	for _, name := range pkg.Scope().Names() {
+		named, ok := messageType(pkg, name)
+		if !ok || (len(typeNames) > 0 && !slices.Contains(typeNames, name)) {
+			continue
+		}
+
+		msg, err := g.planMessage(named)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no message types found in %s", dir)
+	}
+	return g.generate(messages)
+}
+
+// loadPackage parses and type-checks the package in the directory, skipping
+// the output file.
+func loadPackage(dir string, output string) (*types.Package, error) {
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(buildPkg.GoFiles))
+	for _, name := range buildPkg.GoFiles {
+		if name == output {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	path := buildPkg.ImportPath
+	if path == "." || path == "" {
+		path = buildPkg.Name
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(path, fset, files, nil)
+}
+
+// messageType returns the named struct type if it implements bytocol.Message,
+// either with a value or pointer receiver.
+func messageType(pkg *types.Package, name string) (*types.Named, bool) {
+	typeName, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok || typeName.IsAlias() {
+		return nil, false
+	}
+
+	named, ok := typeName.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return nil, false
+	} else if _, ok = named.Underlying().(*types.Struct); !ok {
+		return nil, false
+	}
+
+	method, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, pkg, "BytocolMessage")
+	fn, ok := method.(*types.Func)
+	if !ok {
+		return nil, false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 0 || sig.Results().Len() != 1 {
+		return nil, false
+	}
+
+	result, ok := sig.Results().At(0).Type().(*types.Named)
+	if !ok || result.Obj().Name() != "MessageInfo" || result.Obj().Pkg() == nil || result.Obj().Pkg().Path() != bytocolPath {
+		return nil, false
+	}
+	return named, true
+}
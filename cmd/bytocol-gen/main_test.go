@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+
+	src, err := generate(dir, "bytocol_gen.go", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	existing, err := os.ReadFile(filepath.Join(dir, "bytocol_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(src, existing) {
+		t.Error("internal/gentest/bytocol_gen.go is out of date, run go generate ./...")
+	}
+}
+
+func TestGenerateTypeFilter(t *testing.T) {
+	src, err := generate(filepath.Join("..", "..", "internal", "gentest"), "bytocol_gen.go", []string{"Numbers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(src, []byte("func (m Numbers) MarshalBytocol")) {
+		t.Error("expected Numbers to be generated")
+	} else if bytes.Contains(src, []byte("func (m Composite) MarshalBytocol")) {
+		t.Error("expected Composite to be filtered out")
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	_, err := generate(filepath.Join("testdata", "invalid"), "bytocol_gen.go", nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported encode type chan int") {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
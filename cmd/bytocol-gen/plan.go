@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go/types"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/maple-tech/bytocol/internal/tags"
+)
+
+// kind groups the Go types by how they are encoded.
+type kind int
+
+const (
+	kindBool kind = iota
+	kindUint
+	kindInt
+	kindFloat
+	kindString
+	kindBytes
+	kindSlice
+	kindArray
+	kindMap
+	kindPointer
+	kindStruct
+	kindCodec
+	kindTime
+	kindDuration
+)
+
+// entry mirrors the runtime plan entry for a single field or element, using
+// the go/types information instead of reflection.
+type entry struct {
+	// name is the Go field name, empty for elements.
+	name  string
+	order uint
+	typ   types.Type
+	kind  kind
+
+	// bits is the size of numbers in bits.
+	bits int
+
+	lengthBits     byte
+	nullTerminated bool
+	varint         bool
+
+	// max is the largest length or count accepted when decoding, 0 when not
+	// limited.
+	max uint64
+
+	// byteOrder is the expression of the byte order used by the entry, either
+	// the message default "order" or a binary package byte order.
+	byteOrder string
+
+	elem   *entry
+	key    *entry
+	fields []*entry
+	length int64
+
+	// codec is the method name suffix of types encoding themselves, either
+	// "BytocolField" or "Binary". Sized codecs declare their fixed size with
+	// BytocolFieldSize, which is only known at runtime.
+	codec string
+	sized bool
+
+	// unit is the precision of times and durations, and timeZone is set when
+	// times are followed by their zone offset.
+	unit     time.Duration
+	timeZone bool
+}
+
+// fixedSize returns the encoded size of the entry and true if it does not
+// depend on the value.
+func (e *entry) fixedSize() (int, bool) {
+	switch e.kind {
+	case kindBool:
+		return 1, true
+	case kindUint, kindInt, kindFloat:
+		if e.varint {
+			return 0, false
+		}
+		return e.bits / 8, true
+	case kindTime, kindDuration:
+		if e.varint {
+			return 0, false
+		} else if e.timeZone {
+			return 12, true
+		}
+		return 8, true
+	case kindArray:
+		size, ok := e.elem.fixedSize()
+		return size * int(e.length), ok
+	case kindStruct:
+		total := 0
+		for _, field := range e.fields {
+			size, ok := field.fixedSize()
+			if !ok {
+				return 0, false
+			}
+			total += size
+		}
+		return total, true
+	}
+	return 0, false
+}
+
+// zeroSize returns true if the entry is always encoded as nothing.
+func (e *entry) zeroSize() bool {
+	size, ok := e.fixedSize()
+	return ok && size == 0
+}
+
+// planFields plans every tagged field of the struct, sorted by their order. The
+// stack holds the struct types currently being planned to catch recursion.
+func (g *generator) planFields(st *types.Struct, byteOrder string, stack []types.Type) ([]*entry, error) {
+	fields := make([]*entry, 0)
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+
+		// Embedded structs of unexported types still promote their exported
+		// fields, so those are allowed
+		_, isStruct := field.Type().Underlying().(*types.Struct)
+		if !field.Exported() && !(field.Embedded() && isStruct) {
+			continue
+		}
+
+		raw, ok := reflect.StructTag(st.Tag(i)).Lookup("bytocol")
+		if !ok {
+			continue
+		}
+		tag, err := tags.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name(), err)
+		}
+
+		if !field.Exported() && field.Pkg() != g.pkg {
+			return nil, fmt.Errorf("field %s: cannot access unexported field of package %s", field.Name(), field.Pkg().Path())
+		}
+
+		e := &entry{
+			name:       field.Name(),
+			order:      tag.Order,
+			typ:        field.Type(),
+			lengthBits: 64,
+			byteOrder:  byteOrder,
+		}
+		if err = g.plan(e, tag, stack); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name(), err)
+		}
+		fields = append(fields, e)
+	}
+
+	slices.SortFunc(fields, func(a, b *entry) int {
+		return int(a.order) - int(b.order)
+	})
+	for i := 1; i < len(fields); i++ {
+		if fields[i].order == fields[i-1].order {
+			return nil, fmt.Errorf("duplicate field order %d on field %s", fields[i].order, fields[i].name)
+		}
+	}
+
+	return fields, nil
+}
+
+// plan sets the kind and encoding options of the entry from its type and the
+// parsed field tag, following the same rules as the runtime planner.
+func (g *generator) plan(e *entry, tag tags.Tag, stack []types.Type) error {
+	var err error
+
+	if tag.ByteOrder == binary.LittleEndian {
+		e.byteOrder = "binary.LittleEndian"
+	} else if tag.ByteOrder == binary.BigEndian {
+		e.byteOrder = "binary.BigEndian"
+	}
+
+	under := e.typ.Underlying()
+
+	// Types encoding themselves take precedence over the built-in encodings,
+	// codecs registered at runtime are not known here
+	switch under.(type) {
+	case *types.Pointer, *types.Interface:
+	default:
+		if hasMethod(e.typ, "MarshalBytocolField", marshalSignature) {
+			if !hasMethod(e.typ, "UnmarshalBytocolField", unmarshalSignature) {
+				return fmt.Errorf("type %s implements FieldMarshaler but not FieldUnmarshaler", g.typeString(e.typ))
+			}
+			return g.planCodec(e, tag, "BytocolField", hasMethod(e.typ, "BytocolFieldSize", sizeSignature))
+		}
+	}
+
+	// Times and durations take precedence over the binary marshaling of times
+	if isTimeType(e.typ, "Time") {
+		e.kind = kindTime
+		return g.planTime(e, tag)
+	} else if isTimeType(e.typ, "Duration") {
+		e.kind = kindDuration
+		return g.planTime(e, tag)
+	}
+
+	if basic, ok := under.(*types.Basic); ok && tag.Varint && basic.Info()&types.IsInteger != 0 {
+		if tag.Max != 0 {
+			return fmt.Errorf("max is not supported on type %s", g.typeString(e.typ))
+		}
+		e.varint = true
+		return g.planBasic(e, basic)
+	}
+
+	switch t := under.(type) {
+	case *types.Basic:
+		if t.Kind() == types.String {
+			e.kind = kindString
+			planBlob(e, tag)
+		} else if err = g.planBasic(e, t); err != nil && isBinaryMarshaler(e.typ) {
+			return g.planCodec(e, tag, "Binary", false)
+		}
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			e.kind = kindBytes
+			planBlob(e, tag)
+			break
+		}
+
+		if tag.NullTerminated {
+			return fmt.Errorf("null-terminated is not supported on slice type %s", g.typeString(e.typ))
+		}
+
+		e.kind = kindSlice
+		planPrefix(e, tag)
+		e.elem, err = g.newElem(t.Elem(), e.byteOrder, stack)
+	case *types.Map:
+		if !isMapKey(t.Key()) {
+			return fmt.Errorf("unsupported map key type %s", g.typeString(t.Key()))
+		} else if tag.NullTerminated {
+			return fmt.Errorf("null-terminated is not supported on map type %s", g.typeString(e.typ))
+		}
+
+		e.kind = kindMap
+		planPrefix(e, tag)
+		if e.key, err = g.newElem(t.Key(), e.byteOrder, stack); err == nil {
+			e.elem, err = g.newElem(t.Elem(), e.byteOrder, stack)
+		}
+	case *types.Array:
+		e.kind = kindArray
+		e.length = t.Len()
+		e.elem, err = g.newElem(t.Elem(), e.byteOrder, stack)
+	case *types.Pointer:
+		// The tag options apply to the value being pointed to
+		e.kind = kindPointer
+		e.elem = &entry{
+			typ:        t.Elem(),
+			lengthBits: 64,
+			byteOrder:  e.byteOrder,
+		}
+		err = g.plan(e.elem, tag, stack)
+	case *types.Struct:
+		// Structs without tags may still encode themselves
+		if !hasTaggedFields(t) && isBinaryMarshaler(e.typ) {
+			return g.planCodec(e, tag, "Binary", false)
+		}
+
+		for _, parent := range stack {
+			if types.Identical(parent, e.typ) {
+				return fmt.Errorf("recursive type %s is not supported", g.typeString(e.typ))
+			}
+		}
+
+		e.kind = kindStruct
+		e.fields, err = g.planFields(t, e.byteOrder, append(stack, e.typ))
+		if err == nil && len(e.fields) == 0 {
+			err = fmt.Errorf("nested struct %s has no exported fields", g.typeString(e.typ))
+		}
+	case *types.Interface:
+		err = fmt.Errorf("unsupported encode type %s", g.typeString(e.typ))
+	default:
+		if isBinaryMarshaler(e.typ) {
+			return g.planCodec(e, tag, "Binary", false)
+		}
+		err = fmt.Errorf("unsupported encode type %s", g.typeString(e.typ))
+	}
+
+	switch e.kind {
+	case kindString, kindBytes, kindSlice, kindMap, kindPointer:
+		// Length options were applied above
+	default:
+		if err == nil && !e.varint && (tag.StringLengthPrefix || tag.NullTerminated || tag.Varint || tag.Max != 0) {
+			err = fmt.Errorf("length options are not supported on type %s", g.typeString(e.typ))
+		}
+	}
+
+	// Pointers pass the time options on to the value being pointed to
+	if err == nil && e.kind != kindPointer && (tag.Unit != 0 || tag.TimeZone) {
+		err = fmt.Errorf("time options are not supported on type %s", g.typeString(e.typ))
+	}
+
+	return err
+}
+
+// planTime sets the options of times and durations, defaulting to nanoseconds.
+func (g *generator) planTime(e *entry, tag tags.Tag) error {
+	if tag.StringLengthPrefix || tag.NullTerminated || tag.Max != 0 {
+		return fmt.Errorf("length options are not supported on type %s", g.typeString(e.typ))
+	} else if tag.TimeZone && e.kind != kindTime {
+		return fmt.Errorf("tz is not supported on type %s", g.typeString(e.typ))
+	}
+
+	e.unit = time.Nanosecond
+	if tag.Unit != 0 {
+		e.unit = tag.Unit
+	}
+	e.timeZone = tag.TimeZone
+	e.varint = tag.Varint
+	return nil
+}
+
+// isTimeType returns true for the named type of the time package.
+func isTimeType(typ types.Type, name string) bool {
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Pkg().Path() == "time" && named.Obj().Name() == name
+}
+
+// planBasic sets the kind and size of numbers and booleans.
+func (g *generator) planBasic(e *entry, basic *types.Basic) error {
+	switch basic.Kind() {
+	case types.Bool:
+		e.kind, e.bits = kindBool, 8
+	case types.Uint8:
+		e.kind, e.bits = kindUint, 8
+	case types.Uint16:
+		e.kind, e.bits = kindUint, 16
+	case types.Uint32:
+		e.kind, e.bits = kindUint, 32
+	case types.Uint64, types.Uint:
+		e.kind, e.bits = kindUint, 64
+	case types.Int8:
+		e.kind, e.bits = kindInt, 8
+	case types.Int16:
+		e.kind, e.bits = kindInt, 16
+	case types.Int32:
+		e.kind, e.bits = kindInt, 32
+	case types.Int64, types.Int:
+		e.kind, e.bits = kindInt, 64
+	case types.Float32:
+		e.kind, e.bits = kindFloat, 32
+	case types.Float64:
+		e.kind, e.bits = kindFloat, 64
+	default:
+		return fmt.Errorf("unsupported encode type %s", g.typeString(e.typ))
+	}
+	return nil
+}
+
+// newElem plans the elements of a slice or array, or the keys and values of a
+// map, using the default encoding options for their type.
+func (g *generator) newElem(typ types.Type, byteOrder string, stack []types.Type) (*entry, error) {
+	elem := &entry{
+		typ:        typ,
+		lengthBits: 64,
+		byteOrder:  byteOrder,
+	}
+	if err := g.plan(elem, tags.Tag{}, stack); err != nil {
+		return nil, err
+	}
+	return elem, nil
+}
+
+// planCodec sets the options of types encoding themselves. Sized codecs do not
+// accept length options, while the others are prefixed like byte slices.
+func (g *generator) planCodec(e *entry, tag tags.Tag, codec string, sized bool) error {
+	e.kind, e.codec, e.sized = kindCodec, codec, sized
+	if sized {
+		if tag.StringLengthPrefix || tag.NullTerminated || tag.Varint || tag.Max != 0 {
+			return fmt.Errorf("length options are not supported on fixed size codec type %s", g.typeString(e.typ))
+		}
+		return nil
+	}
+
+	if tag.NullTerminated {
+		return fmt.Errorf("null-terminated is not supported on codec type %s", g.typeString(e.typ))
+	}
+	planPrefix(e, tag)
+	return nil
+}
+
+// The signatures of the methods used by types encoding themselves.
+var (
+	byteSliceType = types.NewSlice(types.Typ[types.Byte])
+	errorType     = types.Universe.Lookup("error").Type()
+
+	marshalSignature = types.NewSignatureType(nil, nil, nil, nil,
+		types.NewTuple(types.NewParam(0, nil, "", byteSliceType), types.NewParam(0, nil, "", errorType)), false)
+	unmarshalSignature = types.NewSignatureType(nil, nil, nil,
+		types.NewTuple(types.NewParam(0, nil, "", byteSliceType)), types.NewTuple(types.NewParam(0, nil, "", errorType)), false)
+	sizeSignature = types.NewSignatureType(nil, nil, nil, nil,
+		types.NewTuple(types.NewParam(0, nil, "", types.Typ[types.Int])), false)
+)
+
+// hasMethod returns true if the type, or a pointer to it, has the method with
+// the signature.
+func hasMethod(typ types.Type, name string, signature *types.Signature) bool {
+	sel := types.NewMethodSet(types.NewPointer(typ)).Lookup(nil, name)
+	return sel != nil && types.Identical(sel.Type(), signature)
+}
+
+// isBinaryMarshaler returns true if the type implements both
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler, matching the
+// runtime fallback.
+func isBinaryMarshaler(typ types.Type) bool {
+	return hasMethod(typ, "MarshalBinary", marshalSignature) && hasMethod(typ, "UnmarshalBinary", unmarshalSignature)
+}
+
+// hasTaggedFields returns true if the struct has any field with a bytocol tag.
+func hasTaggedFields(st *types.Struct) bool {
+	for i := 0; i < st.NumFields(); i++ {
+		if _, ok := reflect.StructTag(st.Tag(i)).Lookup("bytocol"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// planBlob sets the options of strings and byte slices, defaulting to a 64-bit
+// length prefix unless null-terminated.
+func planBlob(e *entry, tag tags.Tag) {
+	e.max = tag.Max
+	if tag.NullTerminated {
+		e.nullTerminated = true
+		e.lengthBits = 0
+		return
+	}
+	planPrefix(e, tag)
+}
+
+// planPrefix sets the length or count prefix options from the tag.
+func planPrefix(e *entry, tag tags.Tag) {
+	e.max = tag.Max
+	if tag.Varint {
+		e.varint = true
+		e.lengthBits = 0
+	} else if tag.StringLengthPrefix {
+		e.lengthBits = tag.StringLengthSize
+	}
+}
+
+func isByte(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.Uint8
+}
+
+// isMapKey returns true for the key types with a natural ordering, matching
+// the runtime.
+func isMapKey(typ types.Type) bool {
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	info := basic.Info()
+	return basic.Kind() != types.Uintptr && info&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0
+}
@@ -0,0 +1,11 @@
+package invalid
+
+import "github.com/maple-tech/bytocol"
+
+type Invalid struct {
+	Events chan int `bytocol:"0"`
+}
+
+func (m Invalid) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{TypeIndicator: 1, DebugName: "invalid"}
+}
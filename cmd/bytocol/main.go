@@ -0,0 +1,92 @@
+// Command bytocol provides tooling around exported bytocol schemas.
+//
+// Usage:
+//
+//	bytocol compat old.json new.json
+//
+// The compat command compares two versions of a schema, exported as JSON or as
+// IDL text when the file name ends in .idl, and lists the changes that break
+// the wire format. It exits with status 1 when there are any.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/maple-tech/bytocol"
+)
+
+const usage = `usage: bytocol compat old.json new.json
+
+Compares two versions of a schema, as JSON or .idl files, and lists the
+changes that break the wire format.
+`
+
+// errIncompatible is returned when the schemas are not compatible, after the
+// incompatibilities have been listed.
+var errIncompatible = errors.New("incompatible schemas")
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "compat" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := compat(os.Stdout, os.Args[2:]); errors.Is(err, errIncompatible) {
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "bytocol: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// compat compares the schemas in the files named by the arguments, writing the
+// incompatibilities found.
+func compat(w io.Writer, args []string) error {
+	if len(args) != 2 {
+		return errors.New("compat expects the old and new schema files")
+	}
+
+	previous, err := loadSchema(args[0])
+	if err != nil {
+		return err
+	}
+	current, err := loadSchema(args[1])
+	if err != nil {
+		return err
+	}
+
+	found := bytocol.CheckCompat(previous, current)
+	if len(found) == 0 {
+		fmt.Fprintln(w, "compatible")
+		return nil
+	}
+
+	for _, inc := range found {
+		fmt.Fprintln(w, inc)
+	}
+	return fmt.Errorf("%w, %d breaking changes", errIncompatible, len(found))
+}
+
+// loadSchema reads a schema file, as IDL text if it has the .idl extension and
+// as JSON otherwise.
+func loadSchema(name string) (*bytocol.Schema, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(name) == ".idl" {
+		return bytocol.ParseIDL(string(data))
+	}
+
+	var schema bytocol.Schema
+	if err = json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &schema, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSchema(t *testing.T, name, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompat(t *testing.T) {
+	previous := writeSchema(t, "old.json", `{"messages": [{
+		"name": "Ping", "size": 4, "typeIndicator": 1, "byteOrder": "big",
+		"fields": [{"name": "Seq", "order": 0, "type": {"kind": "uint32", "size": 4}}]
+	}]}`)
+	same := writeSchema(t, "same.idl", "message Ping 1 {\n\t0 Seq uint32\n}\n")
+	changed := writeSchema(t, "new.idl", "message Ping 1 {\n\t0 Seq uint16\n}\n")
+
+	var out strings.Builder
+	if err := compat(&out, []string{previous, same}); err != nil {
+		t.Fatal(err)
+	} else if out.String() != "compatible\n" {
+		t.Errorf("expected compatible, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := compat(&out, []string{previous, changed}); !errors.Is(err, errIncompatible) {
+		t.Errorf("expected incompatible error, got %v", err)
+	}
+	if expected := "Ping (1): field Seq: type changed from uint32 to uint16\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	if err := compat(&out, []string{previous}); err == nil {
+		t.Error("expected error for a missing schema")
+	}
+	if err := compat(&out, []string{previous, filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
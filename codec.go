@@ -0,0 +1,165 @@
+package bytocol
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec encodes and decodes values of a single type the planner does not know
+// about, such as types from other packages. Codecs are registered for their type
+// with [RegisterCodec].
+type Codec interface {
+	// Size returns the fixed encoded size in bytes of every value, or 0 if the
+	// size varies in which case values are length-prefixed on the wire.
+	Size() int
+
+	// Marshal encodes the value, which is of the registered type.
+	Marshal(value any) ([]byte, error)
+
+	// Unmarshal decodes the data into the target, which is a pointer to the
+	// registered type.
+	Unmarshal(data []byte, target any) error
+}
+
+// FieldMarshaler is implemented by field types that encode themselves. The type
+// must also implement [FieldUnmarshaler], and may implement [FieldSizer] to
+// declare a fixed size. Otherwise the encoded bytes are length-prefixed, using
+// the length options of the field tag.
+type FieldMarshaler interface {
+	MarshalBytocolField() ([]byte, error)
+}
+
+// FieldUnmarshaler is implemented by pointers to field types that decode
+// themselves, see [FieldMarshaler].
+type FieldUnmarshaler interface {
+	UnmarshalBytocolField(data []byte) error
+}
+
+// FieldSizer is implemented by [FieldMarshaler] types that always encode to the
+// same number of bytes. It is called on the zero value of the type.
+type FieldSizer interface {
+	BytocolFieldSize() int
+}
+
+// codecs holds the codecs registered with [RegisterCodec] by their type.
+var codecs sync.Map
+
+// RegisterCodec registers the codec used to encode and decode every field, or
+// element, of the type. It takes precedence over [FieldMarshaler] and the
+// built-in encodings. Codecs must be registered before any message using the
+// type is planned, and messages using them do not use encoders generated by
+// bytocol-gen.
+func RegisterCodec(typeOf reflect.Type, codec Codec) {
+	codecs.Store(typeOf, codec)
+}
+
+// registeredCodec returns the codec registered for the type, if any.
+func registeredCodec(typeOf reflect.Type) (Codec, bool) {
+	codec, ok := codecs.Load(typeOf)
+	if !ok {
+		return nil, false
+	}
+	return codec.(Codec), true
+}
+
+var (
+	fieldMarshalerType    = reflect.TypeFor[FieldMarshaler]()
+	fieldUnmarshalerType  = reflect.TypeFor[FieldUnmarshaler]()
+	fieldSizerType        = reflect.TypeFor[FieldSizer]()
+	binaryMarshalerType   = reflect.TypeFor[encoding.BinaryMarshaler]()
+	binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+)
+
+// implements returns true if the type, or a pointer to it, implements the
+// interface type.
+func implements(typeOf reflect.Type, iface reflect.Type) bool {
+	return typeOf.Implements(iface) || reflect.PointerTo(typeOf).Implements(iface)
+}
+
+// addressable returns a pointer to a copy of the value, so that methods with a
+// pointer receiver can be called on it.
+func addressable(value any) any {
+	valueOf := reflect.ValueOf(value)
+	ptr := reflect.New(valueOf.Type())
+	ptr.Elem().Set(valueOf)
+	return ptr.Interface()
+}
+
+// fieldMarshalerCodec adapts types implementing [FieldMarshaler] and
+// [FieldUnmarshaler] into a [Codec].
+type fieldMarshalerCodec struct {
+	size int
+}
+
+// newFieldMarshalerCodec returns the codec for the type if it implements
+// [FieldMarshaler], or an error if it does not also implement
+// [FieldUnmarshaler].
+func newFieldMarshalerCodec(typeOf reflect.Type) (Codec, bool, error) {
+	if !implements(typeOf, fieldMarshalerType) {
+		return nil, false, nil
+	} else if !reflect.PointerTo(typeOf).Implements(fieldUnmarshalerType) {
+		return nil, false, fmt.Errorf("bytocol: type %s implements FieldMarshaler but not FieldUnmarshaler", typeOf.String())
+	}
+
+	codec := fieldMarshalerCodec{}
+	if implements(typeOf, fieldSizerType) {
+		codec.size = reflect.New(typeOf).Interface().(FieldSizer).BytocolFieldSize()
+	}
+	return codec, true, nil
+}
+
+func (c fieldMarshalerCodec) Size() int {
+	return c.size
+}
+
+func (c fieldMarshalerCodec) Marshal(value any) ([]byte, error) {
+	if marshaler, ok := value.(FieldMarshaler); ok {
+		return marshaler.MarshalBytocolField()
+	}
+	return addressable(value).(FieldMarshaler).MarshalBytocolField()
+}
+
+func (c fieldMarshalerCodec) Unmarshal(data []byte, target any) error {
+	return target.(FieldUnmarshaler).UnmarshalBytocolField(data)
+}
+
+// binaryMarshalerCodec adapts types implementing [encoding.BinaryMarshaler] and
+// [encoding.BinaryUnmarshaler] into a variable size [Codec].
+type binaryMarshalerCodec struct{}
+
+// newBinaryMarshalerCodec returns the codec for the type if it implements both
+// [encoding.BinaryMarshaler] and [encoding.BinaryUnmarshaler].
+func newBinaryMarshalerCodec(typeOf reflect.Type) (Codec, bool) {
+	if !implements(typeOf, binaryMarshalerType) || !reflect.PointerTo(typeOf).Implements(binaryUnmarshalerType) {
+		return nil, false
+	}
+	return binaryMarshalerCodec{}, true
+}
+
+func (c binaryMarshalerCodec) Size() int {
+	return 0
+}
+
+func (c binaryMarshalerCodec) Marshal(value any) ([]byte, error) {
+	if marshaler, ok := value.(encoding.BinaryMarshaler); ok {
+		return marshaler.MarshalBinary()
+	}
+	return addressable(value).(encoding.BinaryMarshaler).MarshalBinary()
+}
+
+func (c binaryMarshalerCodec) Unmarshal(data []byte, target any) error {
+	return target.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+}
+
+// hasTaggedFields returns true if the struct type has any field with a bytocol
+// tag.
+func hasTaggedFields(typeOf reflect.Type) bool {
+	for i := 0; i < typeOf.NumField(); i++ {
+		if _, ok := typeOf.Field(i).Tag.Lookup("bytocol"); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package bytocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testColor is registered with a fixed size codec encoding only the RGB bytes.
+type testColor uint32
+
+type testColorCodec struct{}
+
+func (testColorCodec) Size() int {
+	return 3
+}
+
+func (testColorCodec) Marshal(value any) ([]byte, error) {
+	c := value.(testColor)
+	return []byte{byte(c >> 16), byte(c >> 8), byte(c)}, nil
+}
+
+func (testColorCodec) Unmarshal(data []byte, target any) error {
+	*target.(*testColor) = testColor(data[0])<<16 | testColor(data[1])<<8 | testColor(data[2])
+	return nil
+}
+
+// testAddrCodec encodes addresses as their raw bytes, rather than the binary
+// marshaling of [netip.Addr] which includes the zone.
+type testAddrCodec struct{}
+
+func (testAddrCodec) Size() int {
+	return 0
+}
+
+func (testAddrCodec) Marshal(value any) ([]byte, error) {
+	return value.(netip.Addr).AsSlice(), nil
+}
+
+func (testAddrCodec) Unmarshal(data []byte, target any) error {
+	addr, ok := netip.AddrFromSlice(data)
+	if !ok {
+		return fmt.Errorf("invalid address length %d", len(data))
+	}
+	*target.(*netip.Addr) = addr
+	return nil
+}
+
+func init() {
+	RegisterCodec(reflect.TypeFor[testColor](), testColorCodec{})
+	RegisterCodec(reflect.TypeFor[netip.Addr](), testAddrCodec{})
+}
+
+// testVersion encodes itself with a fixed size.
+type testVersion struct {
+	major, minor uint8
+}
+
+func (v testVersion) MarshalBytocolField() ([]byte, error) {
+	return []byte{v.major, v.minor}, nil
+}
+
+func (v *testVersion) UnmarshalBytocolField(data []byte) error {
+	v.major, v.minor = data[0], data[1]
+	return nil
+}
+
+func (v testVersion) BytocolFieldSize() int {
+	return 2
+}
+
+// testWords encodes itself as space separated text of variable size.
+type testWords []string
+
+func (w testWords) MarshalBytocolField() ([]byte, error) {
+	return []byte(strings.Join(w, " ")), nil
+}
+
+func (w *testWords) UnmarshalBytocolField(data []byte) error {
+	*w = strings.Fields(string(data))
+	return nil
+}
+
+// testPoint has no tags, and falls back to binary marshaling.
+type testPoint struct {
+	x, y int8
+}
+
+func (p testPoint) MarshalBinary() ([]byte, error) {
+	return []byte{byte(p.x), byte(p.y)}, nil
+}
+
+func (p *testPoint) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return errors.New("invalid point")
+	}
+	p.x, p.y = int8(data[0]), int8(data[1])
+	return nil
+}
+
+// testMarshalOnly is missing the [FieldUnmarshaler] half.
+type testMarshalOnly struct{}
+
+func (testMarshalOnly) MarshalBytocolField() ([]byte, error) {
+	return nil, nil
+}
+
+type testCodecMessage struct {
+	Color   testColor   `bytocol:"0"`
+	Addr    netip.Addr  `bytocol:"1,length-prefix=8"`
+	Version testVersion `bytocol:"2"`
+	Words   testWords   `bytocol:"3,varint"`
+	Points  []testPoint `bytocol:"4,length-prefix=8"`
+	Owner   *testColor  `bytocol:"5"`
+}
+
+func (m testCodecMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 11, DebugName: "codec"}
+}
+
+type testCodecInvalidMessage struct {
+	Value testMarshalOnly `bytocol:"0"`
+}
+
+func (m testCodecInvalidMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 12, DebugName: "codec-invalid"}
+}
+
+type testCodecOptionsMessage struct {
+	Version testVersion `bytocol:"0,length-prefix=8"`
+}
+
+func (m testCodecOptionsMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 13, DebugName: "codec-options"}
+}
+
+func TestPlanCodecs(t *testing.T) {
+	plan, err := PlanType[testCodecMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Color 3 + addr prefix 1 + version 2 + words varint 1 + points count 1 +
+	// owner presence 1
+	if size := plan.Size(); size != 9 {
+		t.Errorf("expected minimum size 9, got %d", size)
+	}
+
+	owner := testColor(0x0a0b0c)
+	msg := testCodecMessage{
+		Color:   0x112233,
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+		Version: testVersion{1, 2},
+		Words:   testWords{"hello", "world"},
+		Points:  []testPoint{{-1, 1}},
+		Owner:   &owner,
+	}
+
+	data, err := plan.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{
+		11,
+		0x11, 0x22, 0x33,
+		4, 10, 0, 0, 1,
+		1, 2,
+		11, 'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd',
+		1, 0, 0, 0, 0, 0, 0, 0, 2, 255, 1,
+		1, 0x0a, 0x0b, 0x0c,
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("expected %v, got %v", expected, data)
+	}
+
+	var decoded testCodecMessage
+	if err = plan.Unmarshal(data[1:], &decoded); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(decoded, msg) {
+		t.Errorf("expected %+v, got %+v", msg, decoded)
+	}
+
+	if explained := plan.Explain(data); !strings.Contains(explained, "All bytes accounted for") {
+		t.Errorf("expected every byte explained, got:\n%s", explained)
+	}
+}
+
+func TestPlanCodecErrors(t *testing.T) {
+	if _, err := PlanType[testCodecInvalidMessage](); err == nil || !strings.Contains(err.Error(), "FieldUnmarshaler") {
+		t.Errorf("expected missing FieldUnmarshaler error, got %v", err)
+	}
+
+	if _, err := PlanType[testCodecOptionsMessage](); err == nil || !strings.Contains(err.Error(), "length options") {
+		t.Errorf("expected length options error, got %v", err)
+	}
+
+	plan, err := PlanType[testCodecMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Points are 2 bytes each, so a single byte fails within the codec
+	data := []byte{0x11, 0x22, 0x33, 4, 10, 0, 0, 1, 1, 2, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 255, 0}
+	var decodeErr *DecodeError
+	if err = plan.Unmarshal(data, &testCodecMessage{}); !errors.As(err, &decodeErr) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	} else if decodeErr.Field != "Points[0]" {
+		t.Errorf("expected failing field Points[0], got %s", decodeErr.Field)
+	}
+}
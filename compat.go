@@ -0,0 +1,215 @@
+package bytocol
+
+import "fmt"
+
+// Incompatibility is a change between two versions of a [Schema] that breaks
+// the wire format, so that peers using either version cannot read the messages
+// of the other.
+type Incompatibility struct {
+	// Message is the name of the message and TypeIndicator its previous type
+	// indicator.
+	Message       string
+	TypeIndicator byte
+
+	// Field is the path of the field within the message, with nested struct
+	// fields separated by dots, [] for elements and [key] for map keys. It is
+	// empty for changes to the message itself.
+	Field string
+
+	Reason string
+}
+
+func (inc Incompatibility) String() string {
+	if inc.Field == "" {
+		return fmt.Sprintf("%s (%d): %s", inc.Message, inc.TypeIndicator, inc.Reason)
+	}
+	return fmt.Sprintf("%s (%d): field %s: %s", inc.Message, inc.TypeIndicator, inc.Field, inc.Reason)
+}
+
+// CheckCompat compares the previous version of a schema with the current one
+// and returns the breaking changes found, or none if current peers can still
+// talk to previous ones. Compare against the current Go types by passing the
+// schema of the [Registry] as the current version.
+//
+// Messages and fields are matched by name. Messages that are removed, change
+// type indicator, byte order, or checksum, or whose indicator is reused by
+// another message are breaking. As fields are encoded one after the other, any
+// field that is added, removed, or moved to another position is breaking, as is
+// any change to the encoding of the field at a position, which is compared like
+// [Schema.Fingerprint] does. A field whose position holds a new name is taken
+// as renamed, which is not breaking.
+func CheckCompat(previous, current *Schema) []Incompatibility {
+	c := compatChecker{previous: previous, current: current, found: make([]Incompatibility, 0)}
+
+	for _, prev := range previous.Messages {
+		c.message = prev.Name
+		c.typeIndicator = prev.TypeIndicator
+
+		var cur *MessageSchema
+		for i := range current.Messages {
+			if current.Messages[i].Name == prev.Name {
+				cur = &current.Messages[i]
+				break
+			}
+		}
+
+		if reused, ok := current.Message(prev.TypeIndicator); ok && reused.Name != prev.Name {
+			c.report("", "type indicator %d reused by %s", prev.TypeIndicator, reused.Name)
+		}
+
+		if cur == nil {
+			c.report("", "message removed")
+			continue
+		} else if cur.TypeIndicator != prev.TypeIndicator {
+			c.report("", "type indicator changed from %d to %d", prev.TypeIndicator, cur.TypeIndicator)
+		}
+		if cur.ByteOrder != prev.ByteOrder {
+			c.report("", "byte order changed from %s to %s", prev.ByteOrder, cur.ByteOrder)
+		}
+		if cur.Checksum != prev.Checksum {
+			c.report("", "checksum changed from %s to %s", checksumName(prev.Checksum), checksumName(cur.Checksum))
+		}
+
+		c.compareFields("", prev.Fields, cur.Fields, prev.ByteOrder, cur.ByteOrder)
+	}
+
+	return c.found
+}
+
+// checksumName returns the name of the checksum of a message schema.
+func checksumName(name string) string {
+	if name == "" {
+		return ChecksumNone.String()
+	}
+	return name
+}
+
+// compatChecker collects the incompatibilities of the message being compared.
+type compatChecker struct {
+	previous, current *Schema
+
+	message       string
+	typeIndicator byte
+	found         []Incompatibility
+}
+
+func (c *compatChecker) report(field string, format string, args ...any) {
+	c.found = append(c.found, Incompatibility{
+		Message:       c.message,
+		TypeIndicator: c.typeIndicator,
+		Field:         field,
+		Reason:        fmt.Sprintf(format, args...),
+	})
+}
+
+// compareFields compares the fields of a message or struct. Fields are matched
+// by name to find the ones added, removed, or moved, while their encodings are
+// compared by position since names are not encoded. The path prefixes the
+// previous names of the fields, and the byte orders are the ones inherited by
+// the fields.
+func (c *compatChecker) compareFields(path string, prev, cur []FieldSchema, prevOrder, curOrder string) {
+	prevPositions := fieldPositions(prev)
+	curPositions := fieldPositions(cur)
+
+	for i, field := range prev {
+		j, ok := curPositions[field.Name]
+		if ok && i != j {
+			c.report(path+field.Name, "moved from position %d to %d", i, j)
+		} else if !ok && (i >= len(cur) || hasField(prevPositions, cur[i].Name)) {
+			// Otherwise the field at the same position was renamed
+			c.report(path+field.Name, "field removed")
+		}
+
+		if i < len(cur) {
+			c.compareTypes(path+field.Name, field.Type, cur[i].Type, prevOrder, curOrder)
+		}
+	}
+
+	for j, field := range cur {
+		if !hasField(prevPositions, field.Name) && (j >= len(prev) || hasField(curPositions, prev[j].Name)) {
+			c.report(path+field.Name, "field added at position %d", j)
+		}
+	}
+}
+
+// fieldPositions returns the position of every field by name.
+func fieldPositions(fields []FieldSchema) map[string]int {
+	positions := make(map[string]int, len(fields))
+	for i, field := range fields {
+		positions[field.Name] = i
+	}
+	return positions
+}
+
+// hasField returns true if a field of the name is in the positions.
+func hasField(positions map[string]int, name string) bool {
+	_, ok := positions[name]
+	return ok
+}
+
+// compareTypes compares the encoding of a field, or of its elements.
+func (c *compatChecker) compareTypes(path string, prev, cur TypeSchema, prevOrder, curOrder string) {
+	if prev.Kind != cur.Kind {
+		c.report(path, "type changed from %s to %s", prev.idlType(), cur.idlType())
+		return
+	}
+
+	// Changes to the byte order inherited by both are reported where they
+	// happen
+	if prev.ByteOrder != "" {
+		prevOrder = prev.ByteOrder
+	}
+	if cur.ByteOrder != "" {
+		curOrder = cur.ByteOrder
+	}
+	if prevOrder != curOrder && (prev.ByteOrder != "" || cur.ByteOrder != "") {
+		c.report(path, "byte order changed from %s to %s", prevOrder, curOrder)
+	}
+
+	switch {
+	case prev.Varint != cur.Varint:
+		c.report(path, "varint changed from %t to %t", prev.Varint, cur.Varint)
+	case prev.NullTerminated != cur.NullTerminated:
+		c.report(path, "null-terminated changed from %t to %t", prev.NullTerminated, cur.NullTerminated)
+	case prev.Prefix != cur.Prefix:
+		c.report(path, "length prefix changed from %d to %d bits", prev.Prefix, cur.Prefix)
+	}
+
+	if prev.Kind == KindCodec && (prev.Variable != cur.Variable || prev.Size != cur.Size) {
+		c.report(path, "codec changed from %s to %s", prev.idlType(), cur.idlType())
+	}
+	if prev.Length != cur.Length {
+		c.report(path, "array length changed from %d to %d", prev.Length, cur.Length)
+	}
+	if prev.Unit != cur.Unit {
+		c.report(path, "unit changed from %s to %s", prev.Unit, cur.Unit)
+	}
+	if prev.TimeZone != cur.TimeZone {
+		c.report(path, "tz changed from %t to %t", prev.TimeZone, cur.TimeZone)
+	}
+
+	if prev.Key != nil && cur.Key != nil {
+		c.compareTypes(path+"[key]", *prev.Key, *cur.Key, prevOrder, curOrder)
+	}
+	if prev.Elem != nil && cur.Elem != nil {
+		elemPath := path + "[]"
+		if prev.Kind == KindOptional {
+			elemPath = path
+		}
+		c.compareTypes(elemPath, *prev.Elem, *cur.Elem, prevOrder, curOrder)
+	}
+
+	if prev.Kind == KindStruct {
+		prevStruct, ok := c.previous.Struct(prev.Struct)
+		if !ok {
+			c.report(path, "struct %s missing from the previous schema", prev.Struct)
+			return
+		}
+		curStruct, ok := c.current.Struct(cur.Struct)
+		if !ok {
+			c.report(path, "struct %s missing from the current schema", cur.Struct)
+			return
+		}
+		c.compareFields(path+".", prevStruct.Fields, curStruct.Fields, prevOrder, curOrder)
+	}
+}
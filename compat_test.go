@@ -0,0 +1,140 @@
+package bytocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckCompat(t *testing.T) {
+	plan, err := PlanType[testSchemaMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The Go types are compatible with their own exported IDL
+	parsed, err := ParseIDL(plan.Schema().IDL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := CheckCompat(parsed, plan.Schema()); len(found) != 0 {
+		t.Errorf("expected no incompatibilities, got %v", found)
+	}
+
+	previous, err := ParseIDL(`
+message Move 2 {
+	0 Entity uint32
+	1 Name string length-prefix=16
+	2 To Point
+	3 Tags map[string]uint16
+	4 At time unit=ms
+}
+
+message Chat 3 {
+	0 Text string
+}
+
+message Ping 4 {
+	0 Seq uint32
+}
+
+struct Point {
+	0 X int16
+	1 Y int16
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := ParseIDL(`
+message Move 5 {
+	0 Entity uint16
+	1 Name string length-prefix=8
+	2 To Point
+	3 Tags map[string]uint16 varint
+	4 At time unit=s
+}
+
+message Pong 3 {
+	0 Seq uint32
+}
+
+message Ping 4 {
+	0 Seq uint32 endian=little
+	1 Extra uint8
+}
+
+struct Point {
+	0 Y int16
+	1 X int16
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make([]string, 0)
+	for _, inc := range CheckCompat(previous, current) {
+		found = append(found, inc.String())
+	}
+
+	expected := []string{
+		"Move (2): type indicator changed from 2 to 5",
+		"Move (2): field Entity: type changed from uint32 to uint16",
+		"Move (2): field Name: length prefix changed from 16 to 8 bits",
+		"Move (2): field To.X: moved from position 0 to 1",
+		"Move (2): field To.Y: moved from position 1 to 0",
+		"Move (2): field Tags: varint changed from false to true",
+		"Move (2): field At: unit changed from ms to s",
+		"Chat (3): type indicator 3 reused by Pong",
+		"Chat (3): message removed",
+		"Ping (4): field Seq: byte order changed from big to little",
+		"Ping (4): field Extra: field added at position 1",
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected incompatibilities:\n%q\ngot:\n%q", expected, found)
+	}
+}
+
+func TestCheckCompatFields(t *testing.T) {
+	previous, err := ParseIDL(`
+message Pos 6 {
+	0 X int16
+	1 Y int16
+	2 Z int16
+	3 W int16
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// X is renamed, Z removed, W moved in its place, and V added at the end
+	current, err := ParseIDL(`
+message Pos 6 {
+	0 Left int32
+	1 Y int16
+	2 W int16
+	3 V uint8
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make([]string, 0)
+	for _, inc := range CheckCompat(previous, current) {
+		found = append(found, inc.String())
+	}
+
+	expected := []string{
+		"Pos (6): field X: type changed from int16 to int32",
+		"Pos (6): field Z: field removed",
+		"Pos (6): field W: moved from position 3 to 2",
+		"Pos (6): field W: type changed from int16 to uint8",
+		"Pos (6): field V: field added at position 3",
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected incompatibilities:\n%q\ngot:\n%q", expected, found)
+	}
+}
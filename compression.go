@@ -0,0 +1,116 @@
+package bytocol
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// Compression is the algorithm compressing the frames of a [Conn], enabled
+// with [WithCompression].
+type Compression byte
+
+const (
+	// CompressionNone sends every frame as is.
+	CompressionNone Compression = iota
+
+	// CompressionFlate compresses frames with raw DEFLATE, see
+	// [compress/flate].
+	CompressionFlate
+
+	// CompressionZlib compresses frames with the zlib format, see
+	// [compress/zlib].
+	CompressionZlib
+
+	// CompressionGzip compresses frames with the gzip format, see
+	// [compress/gzip].
+	CompressionGzip
+)
+
+var compressionNames = []string{"none", "flate", "zlib", "gzip"}
+
+func (c Compression) String() string {
+	if int(c) < len(compressionNames) {
+		return compressionNames[c]
+	}
+	return fmt.Sprintf("Compression(%d)", c)
+}
+
+// DefaultCompressionThreshold is a frame size, in bytes, under which
+// compression rarely saves enough to be worth it.
+const DefaultCompressionThreshold = 512
+
+// WithCompression compresses the messages of the frames sent of at least
+// threshold bytes with the algorithm. Smaller frames, and frames that do not
+// shrink, are sent raw. Compressed frames set the second lowest flag bit.
+//
+// When the connection exchanges a [Handshake], compression is only used if the
+// peer enables it as well, with the algorithm of lowest value if they differ.
+// Without a handshake both peers must enable the same algorithm.
+func WithCompression(alg Compression, threshold int) ConnOption {
+	return func(c *Conn) {
+		c.compression = alg
+		c.compressionThreshold = threshold
+	}
+}
+
+// frameCompressor is implemented by the writers of every algorithm.
+type frameCompressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// newCompressor returns the writer compressing with the algorithm onto w.
+func (c Compression) newCompressor(w io.Writer) (frameCompressor, error) {
+	switch c {
+	case CompressionFlate:
+		return flate.NewWriter(w, flate.DefaultCompression)
+	case CompressionZlib:
+		return zlib.NewWriter(w), nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	}
+	return nil, fmt.Errorf("bytocol: unknown compression %s", c)
+}
+
+// decompress returns the decompressed data, which fails with
+// [ErrFrameTooLarge] if it exceeds the limit.
+func (c Compression) decompress(data []byte, limit uint32) ([]byte, error) {
+	var r io.Reader
+	var err error
+	switch c {
+	case CompressionFlate:
+		r = flate.NewReader(bytes.NewReader(data))
+	case CompressionZlib:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case CompressionGzip:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	default:
+		err = fmt.Errorf("unknown compression %s", c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bytocol: %w, %s", ErrInvalidFrame, err)
+	}
+
+	// Read one more byte than allowed to detect frames above the limit
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("bytocol: %w, %s decompression: %s", ErrInvalidFrame, c, err)
+	} else if n > int64(limit) {
+		return nil, fmt.Errorf("bytocol: cannot decompress more than %d bytes, %w", limit, ErrFrameTooLarge)
+	}
+	return buf.Bytes(), nil
+}
+
+// negotiateCompression returns the compression used by both peers, none if
+// either does not enable it.
+func negotiateCompression(local, peer Compression) Compression {
+	if local == CompressionNone || peer == CompressionNone {
+		return CompressionNone
+	}
+	return min(local, peer)
+}
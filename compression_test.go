@@ -0,0 +1,127 @@
+package bytocol
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// testBufferConn is a stream writing to and reading from the same buffer.
+type testBufferConn struct {
+	bytes.Buffer
+}
+
+func (c *testBufferConn) Close() error {
+	return nil
+}
+
+func newTestCompressedConn(alg Compression, opts ...ConnOption) (*Conn, *testBufferConn) {
+	reg := NewRegistry()
+	if _, err := Register[testBlobMessage](reg); err != nil {
+		panic(err)
+	}
+
+	stream := new(testBufferConn)
+	opts = append([]ConnOption{WithRegistry(reg), WithCompression(alg, 64)}, opts...)
+	return NewConn(stream, opts...), stream
+}
+
+func TestConnCompression(t *testing.T) {
+	msg := testBlobMessage{
+		Short:  "short",
+		Medium: bytes.Repeat([]byte("log line\n"), 200),
+		Long:   strings.Repeat("image", 100),
+	}
+	raw, err := Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alg := range []Compression{CompressionFlate, CompressionZlib, CompressionGzip} {
+		conn, stream := newTestCompressedConn(alg)
+		if err = conn.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+
+		frame := stream.Bytes()
+		if frame[4] != frameFlagCompressed || len(frame) >= len(raw) {
+			t.Errorf("%s: expected compressed frame smaller than %d bytes, got %d bytes with flags %08b", alg, len(raw), len(frame), frame[4])
+		}
+
+		received, err := conn.Receive()
+		if err != nil {
+			t.Fatalf("%s: %s", alg, err)
+		} else if got := received.(testBlobMessage); !bytes.Equal(got.Medium, msg.Medium) || got.Long != msg.Long {
+			t.Errorf("%s: unexpected message %+v", alg, got)
+		}
+	}
+
+	// Small frames are sent raw
+	conn, stream := newTestCompressedConn(CompressionGzip)
+	if err = conn.Send(testBlobMessage{Short: "tiny"}); err != nil {
+		t.Fatal(err)
+	} else if flags := stream.Bytes()[4]; flags != 0 {
+		t.Errorf("expected raw frame, got flags %08b", flags)
+	}
+	if _, err = conn.Receive(); err != nil {
+		t.Error(err)
+	}
+
+	// Decompressing is limited to the maximum frame size
+	conn, stream = newTestCompressedConn(CompressionFlate)
+	if err = conn.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	limited := NewConn(stream, WithRegistry(conn.Registry()), WithCompression(CompressionFlate, 64), WithMaxFrameSize(512))
+	if _, err = limited.Receive(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("expected frame too large, got %v", err)
+	}
+
+	// Compressed frames are rejected without compression
+	conn, stream = newTestCompressedConn(CompressionFlate)
+	if err = conn.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	plain := NewConn(stream, WithRegistry(conn.Registry()))
+	if _, err = plain.Receive(); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("expected invalid frame, got %v", err)
+	}
+}
+
+func TestCompressionHandshake(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		a, b     Compression
+		expected Compression
+	}{
+		{CompressionGzip, CompressionGzip, CompressionGzip},
+		{CompressionGzip, CompressionFlate, CompressionFlate},
+		{CompressionZlib, CompressionNone, CompressionNone},
+	} {
+		pipeA, pipeB := net.Pipe()
+		hs := WithHandshake(Handshake{Version: 1})
+		a := NewConn(pipeA, hs, WithRegistry(reg), WithCompression(test.a, 0))
+		b := NewConn(pipeB, hs, WithRegistry(reg), WithCompression(test.b, 0))
+
+		if errA, errB := handshakePair(a, b); errA != nil || errB != nil {
+			t.Fatal(errA, errB)
+		}
+		if a.compression != test.expected || b.compression != test.expected {
+			t.Errorf("%s and %s: expected %s, got %s and %s", test.a, test.b, test.expected, a.compression, b.compression)
+		}
+
+		// Frames flow with the agreed compression
+		go a.Send(testMessageObj)
+		if _, err := b.Receive(); err != nil {
+			t.Errorf("%s and %s: %s", test.a, test.b, err)
+		}
+		a.Close()
+		b.Close()
+	}
+}
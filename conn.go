@@ -0,0 +1,344 @@
+package bytocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultMaxFrameSize is the largest frame, not counting its header, that a
+// [Conn] will send or receive unless configured otherwise.
+const DefaultMaxFrameSize = 16 << 20
+
+// frameHeaderSize is the size of the frame header preceding each message. It
+// holds the 32-bit big-endian length of the rest of the frame and a flags byte.
+const frameHeaderSize = 5
+
+const (
+	// frameFlagCorrelated marks frames carrying a 32-bit correlation ID between
+	// the header and the message.
+	frameFlagCorrelated byte = 1 << iota
+
+	// frameFlagCompressed marks frames whose message is compressed with the
+	// algorithm agreed on by the peers.
+	frameFlagCompressed
+
+	// frameFlagEncrypted marks frames sealed with the keys of the connection,
+	// whose correlation ID and message follow a sequence number.
+	frameFlagEncrypted
+
+	// frameFlagRotate marks encrypted frames announcing that the peer switched
+	// to another key, whose ID is the only byte sealed.
+	frameFlagRotate
+
+	// frameKnownFlags holds every flag understood by this version.
+	frameKnownFlags = frameFlagCorrelated | frameFlagCompressed | frameFlagEncrypted | frameFlagRotate
+)
+
+// frameHeader holds the options of a single frame.
+type frameHeader struct {
+	flags byte
+	id    uint32
+}
+
+func (hdr frameHeader) correlated() bool {
+	return hdr.flags&frameFlagCorrelated != 0
+}
+
+func (hdr frameHeader) compressed() bool {
+	return hdr.flags&frameFlagCompressed != 0
+}
+
+func (hdr frameHeader) encrypted() bool {
+	return hdr.flags&frameFlagEncrypted != 0
+}
+
+// size returns the number of bytes preceding the message in the frame.
+func (hdr frameHeader) size() int {
+	if hdr.correlated() {
+		return frameHeaderSize + 4
+	}
+	return frameHeaderSize
+}
+
+// Conn wraps a stream such as a [net.Conn] and exchanges length-framed messages
+// over it. Each frame is a header carrying the length of the frame and its
+// flags, optionally followed by a correlation ID, and then the message as
+// produced by [Write] starting with its type indicator. Because the whole frame
+// is read before decoding, a message that fails to decode does not leave the
+// stream out of sync. When enabled with [WithHandshake], the peers exchange a
+// [Handshake] before the first frame, messages are compressed when enabled with
+// [WithCompression], and frames are sealed when enabled with [WithEncryption].
+//
+// A Conn is safe for one goroutine calling [Conn.Send] concurrently with another
+// calling [Conn.Receive]. Concurrent sends, or concurrent receives, are
+// serialized.
+type Conn struct {
+	rwc          io.ReadWriteCloser
+	reg          *Registry
+	maxFrameSize uint32
+
+	sendMu  sync.Mutex
+	sendBuf bytes.Buffer
+
+	recvMu     sync.Mutex
+	recvHeader [frameHeaderSize]byte
+
+	handshake     *Handshake
+	handshakeOnce sync.Once
+	handshakeErr  error
+	version       uint16
+
+	// compression is replaced by the one agreed on during the handshake
+	compression          Compression
+	compressionThreshold int
+	compressor           frameCompressor
+	compressBuf          bytes.Buffer
+
+	encryption *frameCipher
+
+	decodeLimits DecodeLimits
+}
+
+// ConnOption configures a [Conn] when created with [NewConn].
+type ConnOption func(*Conn)
+
+// WithRegistry sets the [Registry] used to decode received messages. By default
+// the [DefaultRegistry] is used.
+func WithRegistry(reg *Registry) ConnOption {
+	return func(c *Conn) {
+		c.reg = reg
+	}
+}
+
+// WithMaxFrameSize sets the largest frame size, not counting its header, that
+// will be sent or received. Larger frames fail with
+// [ErrFrameTooLarge]. By default [DefaultMaxFrameSize] is used.
+func WithMaxFrameSize(size uint32) ConnOption {
+	return func(c *Conn) {
+		c.maxFrameSize = size
+	}
+}
+
+// NewConn wraps the stream, commonly a [net.Conn], into a new [Conn] configured
+// by the options provided.
+func NewConn(rwc io.ReadWriteCloser, opts ...ConnOption) *Conn {
+	c := &Conn{
+		rwc:          rwc,
+		reg:          DefaultRegistry,
+		maxFrameSize: DefaultMaxFrameSize,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Registry returns the [Registry] used to decode received messages.
+func (c *Conn) Registry() *Registry {
+	return c.reg
+}
+
+// Send encodes the message and writes it as a single frame. The message is
+// fully encoded before anything is written, so encoding errors never leave a
+// partial frame on the stream.
+func (c *Conn) Send(msg Message) error {
+	return c.sendFrame(frameHeader{}, msg)
+}
+
+// Receive reads the next frame and decodes the message within it using the
+// registry. It returns [io.EOF] if the stream ended cleanly between frames.
+// Correlation IDs used by [Client] are ignored.
+//
+// If the message received is an [ErrorMessage] it is returned as the error with
+// a nil message, as with [Registry.Read].
+func (c *Conn) Receive() (Message, error) {
+	_, body, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	return c.decodeFrame(body)
+}
+
+// sendFrame encodes the message and writes it as a single frame using the
+// header provided.
+func (c *Conn) sendFrame(hdr frameHeader, msg Message) error {
+	if err := c.Handshake(); err != nil {
+		return err
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	// Reserve the header, then encode the message directly after it
+	prefixSize := hdr.size()
+	c.sendBuf.Reset()
+	c.sendBuf.Write(make([]byte, prefixSize))
+	if err := Write(msg, &c.sendBuf); err != nil {
+		return err
+	}
+
+	// The peer limits decompressed frames to the maximum size as well, and
+	// compressed frames never grow
+	if length := c.sendBuf.Len() - frameHeaderSize + c.encryption.overhead(); uint64(length) > uint64(c.maxFrameSize) {
+		return fmt.Errorf("bytocol: cannot send %d bytes, %w of %d bytes", length, ErrFrameTooLarge, c.maxFrameSize)
+	}
+
+	if c.compression != CompressionNone && c.sendBuf.Len()-prefixSize >= c.compressionThreshold {
+		compressed, err := c.compressFrame(prefixSize)
+		if err != nil {
+			return err
+		} else if compressed {
+			hdr.flags |= frameFlagCompressed
+		}
+	}
+
+	frame := c.sendBuf.Bytes()
+	if hdr.correlated() {
+		binary.BigEndian.PutUint32(frame[frameHeaderSize:], hdr.id)
+	}
+
+	if c.encryption != nil {
+		frame = c.encryption.seal(hdr.flags, frame[frameHeaderSize:])
+	} else {
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-frameHeaderSize))
+		frame[4] = hdr.flags
+	}
+
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// compressFrame replaces the message in the send buffer, following the prefix,
+// with its compressed form. It returns false if compressing does not shrink the
+// message, leaving it as is.
+func (c *Conn) compressFrame(prefixSize int) (bool, error) {
+	msg := c.sendBuf.Bytes()[prefixSize:]
+
+	var err error
+	c.compressBuf.Reset()
+	if c.compressor == nil {
+		if c.compressor, err = c.compression.newCompressor(&c.compressBuf); err != nil {
+			return false, err
+		}
+	} else {
+		c.compressor.Reset(&c.compressBuf)
+	}
+
+	if _, err = c.compressor.Write(msg); err != nil {
+		return false, err
+	} else if err = c.compressor.Close(); err != nil {
+		return false, err
+	}
+
+	if c.compressBuf.Len() >= len(msg) {
+		return false, nil
+	}
+	c.sendBuf.Truncate(prefixSize)
+	c.sendBuf.Write(c.compressBuf.Bytes())
+	return true, nil
+}
+
+// readFrame reads the next frame and returns its header and the encoded
+// message. Any error returned leaves the stream out of sync.
+func (c *Conn) readFrame() (frameHeader, []byte, error) {
+	var hdr frameHeader
+	if err := c.Handshake(); err != nil {
+		return hdr, nil, err
+	}
+
+	c.recvMu.Lock()
+	defer c.recvMu.Unlock()
+
+	frame, err := c.readSealedFrame(&hdr)
+	if err != nil {
+		return hdr, nil, err
+	}
+
+	if hdr.correlated() {
+		hdr.id = binary.BigEndian.Uint32(frame)
+		frame = frame[4:]
+	}
+
+	if hdr.compressed() {
+		if c.compression == CompressionNone {
+			return hdr, nil, fmt.Errorf("bytocol: %w, compressed without compression enabled", ErrInvalidFrame)
+		}
+
+		if frame, err = c.compression.decompress(frame, c.maxFrameSize); err != nil {
+			return hdr, nil, err
+		} else if len(frame) == 0 {
+			return hdr, nil, fmt.Errorf("bytocol: %w, missing type indicator", ErrInvalidFrame)
+		}
+	}
+	return hdr, frame, nil
+}
+
+// readSealedFrame reads the next frame, opening it if encrypted, and returns
+// the correlation ID and message. Key rotations are handled and skipped.
+func (c *Conn) readSealedFrame(hdr *frameHeader) ([]byte, error) {
+	for {
+		if _, err := io.ReadFull(c.rwc, c.recvHeader[:]); err != nil {
+			return nil, err
+		}
+
+		length := binary.BigEndian.Uint32(c.recvHeader[:])
+		hdr.flags = c.recvHeader[4]
+		if length > c.maxFrameSize {
+			return nil, fmt.Errorf("bytocol: cannot receive %d bytes, %w of %d bytes", length, ErrFrameTooLarge, c.maxFrameSize)
+		} else if hdr.flags&^frameKnownFlags != 0 {
+			return nil, fmt.Errorf("bytocol: %w, unknown flags %08b", ErrInvalidFrame, hdr.flags)
+		} else if hdr.encrypted() != (c.encryption != nil) {
+			return nil, fmt.Errorf("bytocol: %w, encryption flag %t while encryption enabled is %t", ErrInvalidFrame, hdr.encrypted(), c.encryption != nil)
+		} else if hdr.flags&frameFlagRotate != 0 && hdr.flags != frameFlagEncrypted|frameFlagRotate {
+			return nil, fmt.Errorf("bytocol: %w, key rotation with flags %08b", ErrInvalidFrame, hdr.flags)
+		} else if length <= uint32(hdr.size()-frameHeaderSize) {
+			return nil, fmt.Errorf("bytocol: %w, missing type indicator", ErrInvalidFrame)
+		}
+
+		frame := make([]byte, length)
+		if err := readFull(c.rwc, frame); err != nil {
+			return nil, err
+		}
+		if !hdr.encrypted() {
+			return frame, nil
+		}
+
+		plaintext, err := c.encryption.open(c.recvHeader[:], frame)
+		if err != nil {
+			return nil, err
+		} else if hdr.flags&frameFlagRotate == 0 {
+			if len(plaintext) <= hdr.size()-frameHeaderSize {
+				return nil, fmt.Errorf("bytocol: %w, missing type indicator", ErrInvalidFrame)
+			}
+			return plaintext, nil
+		} else if err = c.encryption.rotateRecv(plaintext); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodeFrame decodes the message of a frame using the registry. As the frame
+// holds the whole message, its checksum is verified before decoding anything.
+func (c *Conn) decodeFrame(frame []byte) (Message, error) {
+	if plan, ok := c.reg.Plan(frame[0]); ok && plan.checksum != ChecksumNone {
+		if err := verifyChecksum(plan.checksum, frame, plan.byteOrder); err != nil {
+			return nil, err
+		}
+	}
+
+	body := bytes.NewReader(frame)
+	msg, err := c.reg.readLimited(body, c.decodeLimits)
+	if err == nil && body.Len() > 0 {
+		return nil, fmt.Errorf("bytocol: %w, %d bytes remaining after message", ErrInvalidFrame, body.Len())
+	}
+	return msg, err
+}
+
+// Close closes the underlying stream.
+func (c *Conn) Close() error {
+	return c.rwc.Close()
+}
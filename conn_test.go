@@ -0,0 +1,123 @@
+package bytocol
+
+import (
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestConnPair(opts ...ConnOption) (*Conn, *Conn) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		panic(err)
+	}
+
+	opts = append([]ConnOption{WithRegistry(reg)}, opts...)
+	a, b := net.Pipe()
+	return NewConn(a, opts...), NewConn(b, opts...)
+}
+
+func TestConnSendReceive(t *testing.T) {
+	client, server := newTestConnPair()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		if err := client.Send(testMessageObj); err != nil {
+			t.Error(err)
+		}
+		if err := client.Send(NewError("failed")); err != nil {
+			t.Error(err)
+		}
+		client.Close()
+	}()
+
+	msg, err := server.Receive()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if !reflect.DeepEqual(msg, testMessageObj) {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	var errMsg ErrorMessage
+	if _, err = server.Receive(); !errors.As(err, &errMsg) || errMsg.Message != "failed" {
+		t.Errorf("expected error message, got %v", err)
+	}
+
+	if _, err = server.Receive(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestConnFrameTooLarge(t *testing.T) {
+	client, server := newTestConnPair(WithMaxFrameSize(testMessageLength - 1))
+	defer client.Close()
+	defer server.Close()
+
+	if err := client.Send(testMessageObj); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("expected frame too large, got %v", err)
+	}
+
+	// Receiving checks the header before reading the frame
+	a, b := net.Pipe()
+	defer a.Close()
+	go a.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0})
+
+	conn := NewConn(b, WithMaxFrameSize(1024))
+	if _, err := conn.Receive(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("expected frame too large, got %v", err)
+	}
+}
+
+func TestConnInvalidFrame(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	go func() {
+		a.Write([]byte{0, 0, 0, 0, 0})
+		a.Write([]byte{0, 0, 0, 1, 0x80, 1})
+	}()
+
+	conn := NewConn(b)
+	if _, err := conn.Receive(); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("expected invalid frame for empty frame, got %v", err)
+	}
+	if _, err := conn.Receive(); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("expected invalid frame for unknown flags, got %v", err)
+	}
+}
+
+func TestConnConcurrent(t *testing.T) {
+	client, server := newTestConnPair()
+	defer client.Close()
+	defer server.Close()
+
+	const count = 32
+
+	var wg sync.WaitGroup
+	for _, conn := range []*Conn{client, server} {
+		wg.Add(2)
+		go func(conn *Conn) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				if err := conn.Send(testMessageObj); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(conn)
+		go func(conn *Conn) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				if _, err := conn.Receive(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(conn)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,276 @@
+package bytocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Encryption is the AEAD sealing the frames of a [Conn], enabled with
+// [WithEncryption].
+type Encryption byte
+
+const (
+	// EncryptionAESGCM seals frames with AES-GCM, see [cipher.NewGCM]. Keys of
+	// 16, 24, or 32 bytes select AES-128, AES-192, or AES-256.
+	EncryptionAESGCM Encryption = iota
+
+	// EncryptionChaCha20Poly1305 seals frames with ChaCha20-Poly1305, see
+	// [chacha20poly1305.New], which is faster than AES-GCM on devices without
+	// AES instructions. Keys must be 32 bytes.
+	EncryptionChaCha20Poly1305
+)
+
+var encryptionNames = []string{"aes-gcm", "chacha20-poly1305"}
+
+func (e Encryption) String() string {
+	if int(e) < len(encryptionNames) {
+		return encryptionNames[e]
+	}
+	return fmt.Sprintf("Encryption(%d)", e)
+}
+
+// encryptionHelloSize is the size of the hello sent by each peer before the
+// first encrypted frame: a random 16 byte salt, the [Encryption] of the frames,
+// and the ID of the key used for the frames sent.
+const encryptionHelloSize = 16 + 1 + 1
+
+// sealedPrefixSize is the size of the 64-bit big-endian sequence number
+// following the header of encrypted frames.
+const sealedPrefixSize = 8
+
+// frameKeyLabel separates the keys derived for frames from other uses of the
+// pre-shared keys.
+var frameKeyLabel = []byte("bytocol frame key")
+
+// FrameKeys are the pre-shared keys of [WithEncryption] indexed by their ID.
+// Their length depends on the [Encryption]: 16, 24, or 32 bytes for AES-GCM,
+// and 32 bytes for ChaCha20-Poly1305.
+type FrameKeys map[byte][]byte
+
+// WithEncryption seals every frame sent and received with the AEAD, using the
+// pre-shared keys and starting with the key of the ID provided. It provides
+// confidentiality and integrity where TLS cannot be used, but unlike TLS it
+// does not provide forward secrecy: anyone learning a key can decrypt the
+// recorded connections that used it.
+//
+// Before the first frame, the peers exchange random salts along with their AEAD
+// and the ID of the key they start with, failing with [ErrInvalidHandshake] if
+// the AEADs differ. Each direction of the connection then uses its own
+// key, derived from the pre-shared key and both salts with HMAC-SHA256, so that
+// nonces are never reused across directions or connections. Encrypted frames
+// set the third lowest flag bit and carry a 64-bit sequence number, used as the
+// nonce and authenticated along with the header, after which the correlation ID
+// and the message, compressed if enabled, are sealed. Frames are rejected with
+// [ErrReplayedFrame] unless their sequence number is the one expected next, and
+// with [ErrFrameAuthentication] if they were not sealed by the peer.
+//
+// Both peers must enable encryption, plaintext frames are rejected. Use
+// [Conn.RotateKey] to switch keys without reconnecting.
+func WithEncryption(alg Encryption, keys FrameKeys, keyID byte) ConnOption {
+	return func(c *Conn) {
+		c.encryption = &frameCipher{alg: alg, keys: keys, sendID: keyID}
+	}
+}
+
+// frameCipher holds the encryption state of a [Conn]. The sending state is
+// guarded by the send mutex of the connection, and the receiving state by its
+// receive mutex.
+type frameCipher struct {
+	alg                 Encryption
+	keys                FrameKeys
+	localSalt, peerSalt [16]byte
+
+	sendID  byte
+	send    cipher.AEAD
+	sendSeq uint64
+	sealBuf []byte
+
+	recv    cipher.AEAD
+	recvSeq uint64
+}
+
+// overhead returns the number of bytes added to the frames by the encryption,
+// or 0 if it is not enabled.
+func (fc *frameCipher) overhead() int {
+	if fc == nil {
+		return 0
+	}
+	return sealedPrefixSize + fc.send.Overhead()
+}
+
+// exchangeHello sends the salt and key ID of the connection while reading the
+// ones of the peer, then derives the keys of both directions.
+func (fc *frameCipher) exchangeHello(rw io.ReadWriteCloser) error {
+	if _, err := rand.Read(fc.localSalt[:]); err != nil {
+		return fmt.Errorf("bytocol: generating encryption salt: %w", err)
+	}
+
+	var local, peer [encryptionHelloSize]byte
+	copy(local[:], fc.localSalt[:])
+	local[16] = byte(fc.alg)
+	local[17] = fc.sendID
+
+	err := exchange(rw, local[:], peer[:], func() error {
+		if [16]byte(peer[:16]) == fc.localSalt {
+			return fmt.Errorf("bytocol: %w, peer sent back the encryption salt", ErrInvalidHandshake)
+		} else if alg := Encryption(peer[16]); alg != fc.alg {
+			return fmt.Errorf("bytocol: %w, peer seals frames with %s instead of %s", ErrInvalidHandshake, alg, fc.alg)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fc.peerSalt = [16]byte(peer[:16])
+
+	if fc.send, err = fc.aead(fc.sendID, fc.localSalt, fc.peerSalt); err != nil {
+		return err
+	} else if fc.recv, err = fc.aead(peer[17], fc.peerSalt, fc.localSalt); err != nil {
+		return fmt.Errorf("bytocol: peer encryption: %w", err)
+	}
+	return nil
+}
+
+// aead returns the cipher of the frames sent by the peer with the salt from to
+// the peer with the salt to, using the key of the ID.
+func (fc *frameCipher) aead(id byte, from, to [16]byte) (cipher.AEAD, error) {
+	if err := fc.checkKey(id); err != nil {
+		return nil, err
+	}
+
+	key := fc.keys[id]
+	mac := hmac.New(sha256.New, key)
+	mac.Write(frameKeyLabel)
+	mac.Write(from[:])
+	mac.Write(to[:])
+	derived := mac.Sum(nil)[:len(key)]
+
+	if fc.alg == EncryptionChaCha20Poly1305 {
+		return chacha20poly1305.New(derived)
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// checkKey checks the pre-shared key of the ID is known and can be used with
+// the AEAD.
+func (fc *frameCipher) checkKey(id byte) error {
+	key, ok := fc.keys[id]
+	switch {
+	case !ok:
+		return fmt.Errorf("bytocol: unknown frame key %d", id)
+	case fc.alg == EncryptionAESGCM && len(key) != 16 && len(key) != 24 && len(key) != 32:
+		return fmt.Errorf("bytocol: frame key %d has %d bytes, expected 16, 24, or 32", id, len(key))
+	case fc.alg == EncryptionChaCha20Poly1305 && len(key) != chacha20poly1305.KeySize:
+		return fmt.Errorf("bytocol: frame key %d has %d bytes, expected %d", id, len(key), chacha20poly1305.KeySize)
+	case fc.alg > EncryptionChaCha20Poly1305:
+		return fmt.Errorf("bytocol: unsupported encryption %s", fc.alg)
+	}
+	return nil
+}
+
+// frameNonce returns the nonce of the sequence number.
+func frameNonce(seq uint64) []byte {
+	var nonce [12]byte
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce[:]
+}
+
+// seal returns the whole frame, starting with its header, sealing the plaintext
+// with the next sequence number. The frame is only valid until the next call.
+func (fc *frameCipher) seal(flags byte, plaintext []byte) []byte {
+	length := sealedPrefixSize + len(plaintext) + fc.send.Overhead()
+
+	frame := binary.BigEndian.AppendUint32(fc.sealBuf[:0], uint32(length))
+	frame = append(frame, flags|frameFlagEncrypted)
+	frame = binary.BigEndian.AppendUint64(frame, fc.sendSeq)
+	frame = fc.send.Seal(frame, frameNonce(fc.sendSeq), plaintext, frame)
+
+	fc.sendSeq++
+	fc.sealBuf = frame
+	return frame
+}
+
+// open authenticates the frame following the header and returns its plaintext.
+func (fc *frameCipher) open(header, frame []byte) ([]byte, error) {
+	if len(frame) < sealedPrefixSize+fc.recv.Overhead() {
+		return nil, fmt.Errorf("bytocol: %w, %d bytes cannot hold an encrypted frame", ErrInvalidFrame, len(frame))
+	}
+
+	seq := binary.BigEndian.Uint64(frame)
+	if seq != fc.recvSeq {
+		return nil, fmt.Errorf("bytocol: %w, received sequence number %d while expecting %d", ErrReplayedFrame, seq, fc.recvSeq)
+	}
+
+	var additional [frameHeaderSize + sealedPrefixSize]byte
+	copy(additional[:], header)
+	copy(additional[frameHeaderSize:], frame)
+
+	plaintext, err := fc.recv.Open(frame[sealedPrefixSize:sealedPrefixSize], frameNonce(seq), frame[sealedPrefixSize:], additional[:])
+	if err != nil {
+		return nil, fmt.Errorf("bytocol: %w, sequence number %d", ErrFrameAuthentication, seq)
+	}
+
+	fc.recvSeq++
+	return plaintext, nil
+}
+
+// rotateRecv switches the keys of the frames received, as requested by the
+// plaintext of a rotation frame.
+func (fc *frameCipher) rotateRecv(plaintext []byte) error {
+	if len(plaintext) != 1 {
+		return fmt.Errorf("bytocol: %w, key rotation of %d bytes", ErrInvalidFrame, len(plaintext))
+	}
+
+	recv, err := fc.aead(plaintext[0], fc.peerSalt, fc.localSalt)
+	if err != nil {
+		return fmt.Errorf("bytocol: peer key rotation: %w", err)
+	}
+	fc.recv = recv
+	return nil
+}
+
+// RotateKey switches the frames sent to the pre-shared key of the ID, which
+// the peer must know as well. The switch is announced to the peer with a
+// rotation frame sealed with the previous key, after which it opens the frames
+// with the new key. The sequence numbers carry on, so rotating back to a
+// previous key never reuses a nonce.
+//
+// Each direction rotates on its own, so both peers rotate to retire a key.
+func (c *Conn) RotateKey(id byte) error {
+	fc := c.encryption
+	if fc == nil {
+		return errors.New("bytocol: cannot rotate keys without encryption")
+	} else if err := fc.checkKey(id); err != nil {
+		return err
+	} else if err = c.Handshake(); err != nil {
+		return err
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	send, err := fc.aead(id, fc.localSalt, fc.peerSalt)
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.rwc.Write(fc.seal(frameFlagRotate, []byte{id})); err != nil {
+		return err
+	}
+	fc.sendID = id
+	fc.send = send
+	return nil
+}
@@ -0,0 +1,297 @@
+package bytocol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var (
+	testFrameKeys = FrameKeys{
+		1: bytes.Repeat([]byte{0x11}, 16),
+		2: bytes.Repeat([]byte{0x22}, 32),
+	}
+	testOtherFrameKeys = FrameKeys{
+		1: bytes.Repeat([]byte{0x33}, 16),
+	}
+)
+
+func TestEncryptionString(t *testing.T) {
+	if s := EncryptionChaCha20Poly1305.String(); s != "chacha20-poly1305" {
+		t.Errorf("unexpected name %s", s)
+	} else if s = Encryption(9).String(); s != "Encryption(9)" {
+		t.Errorf("unexpected name %s", s)
+	}
+}
+
+// testTapConn records the last write of a stream, optionally corrupting it.
+type testTapConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	last    []byte
+	corrupt bool
+}
+
+func (c *testTapConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	c.last = append(c.last[:0], p...)
+	if c.corrupt {
+		c.last[len(c.last)-1] ^= 1
+		p = c.last
+	}
+	c.mu.Unlock()
+	return c.Conn.Write(p)
+}
+
+// newTestEncryptedPair returns connections encrypted with the keys, the first
+// one writing through the tap returned.
+func newTestEncryptedPair(t *testing.T, keysA, keysB FrameKeys, idA, idB byte) (*Conn, *Conn, *testTapConn) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	pipeA, pipeB := net.Pipe()
+	tap := &testTapConn{Conn: pipeA}
+	a := NewConn(tap, WithRegistry(reg), WithEncryption(EncryptionAESGCM, keysA, idA))
+	b := NewConn(pipeB, WithRegistry(reg), WithEncryption(EncryptionAESGCM, keysB, idB))
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return a, b, tap
+}
+
+// sendReceive sends the message from a while b receives it.
+func sendReceive(a, b *Conn, msg Message) (Message, error) {
+	sent := make(chan error, 1)
+	go func() {
+		sent <- a.Send(msg)
+	}()
+
+	received, err := b.Receive()
+	if err != nil {
+		// The sender may be blocked on the frame being read
+		b.Close()
+	}
+	if sendErr := <-sent; err == nil {
+		err = sendErr
+	}
+	return received, err
+}
+
+// rotateSendReceive rotates the key of a before sending the message, while b
+// receives it.
+func rotateSendReceive(a, b *Conn, id byte, msg Message) (Message, error) {
+	type result struct {
+		msg Message
+		err error
+	}
+	received := make(chan result, 1)
+	go func() {
+		msg, err := b.Receive()
+		received <- result{msg, err}
+	}()
+
+	err := a.RotateKey(id)
+	if err == nil {
+		err = a.Send(msg)
+	}
+	if err != nil {
+		b.Close()
+	}
+
+	res := <-received
+	if err == nil {
+		err = res.err
+	}
+	return res.msg, err
+}
+
+func TestConnEncryption(t *testing.T) {
+	a, b, tap := newTestEncryptedPair(t, testFrameKeys, testFrameKeys, 1, 2)
+	if errA, errB := handshakePair(a, b); errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+
+	raw, err := Marshal(testMessageObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		received, err := sendReceive(a, b, testMessageObj)
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(received, testMessageObj) {
+			t.Errorf("unexpected message %+v", received)
+		}
+
+		frame := tap.last
+		if frame[4] != frameFlagEncrypted || bytes.Contains(frame, raw[1:]) {
+			t.Errorf("expected encrypted frame, got flags %08b and % x", frame[4], frame)
+		} else if seq := frame[frameHeaderSize+7]; seq != byte(i) {
+			t.Errorf("expected sequence number %d, got %d", i, seq)
+		}
+	}
+
+	// Replaying a frame is detected
+	go tap.Conn.Write(bytes.Clone(tap.last))
+	if _, err = b.Receive(); !errors.Is(err, ErrReplayedFrame) {
+		t.Errorf("expected replayed frame, got %v", err)
+	}
+}
+
+func TestConnEncryptionRotate(t *testing.T) {
+	a, b, tap := newTestEncryptedPair(t, testFrameKeys, testFrameKeys, 1, 1)
+	if errA, errB := handshakePair(a, b); errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+	if err := a.RotateKey(3); err == nil {
+		t.Error("expected rotating to an unknown key to fail")
+	}
+
+	// The rotation frame is skipped by the peer
+	if received, err := rotateSendReceive(a, b, 2, testMessageObj); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(received, testMessageObj) {
+		t.Errorf("unexpected message %+v", received)
+	} else if a.encryption.sendID != 2 || a.encryption.sendSeq != 2 {
+		t.Errorf("expected key 2 at sequence number 2, got key %d at %d", a.encryption.sendID, a.encryption.sendSeq)
+	}
+
+	// Back to the first key, the sequence numbers carry on
+	if received, err := rotateSendReceive(a, b, 1, testMessageObj); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(received, testMessageObj) {
+		t.Errorf("unexpected message %+v", received)
+	} else if seq := tap.last[frameHeaderSize+7]; seq != 3 {
+		t.Errorf("expected sequence number 3, got %d", seq)
+	}
+
+	if err := NewConn(tap).RotateKey(1); err == nil {
+		t.Error("expected rotating without encryption to fail")
+	}
+}
+
+func TestConnEncryptionInvalid(t *testing.T) {
+	// Different keys
+	a, b, _ := newTestEncryptedPair(t, testFrameKeys, testOtherFrameKeys, 1, 1)
+	if _, err := sendReceive(a, b, testMessageObj); !errors.Is(err, ErrFrameAuthentication) {
+		t.Errorf("expected authentication to fail with another key, got %v", err)
+	}
+
+	// Tampered frames
+	a, b, tap := newTestEncryptedPair(t, testFrameKeys, testFrameKeys, 1, 1)
+	if errA, errB := handshakePair(a, b); errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+	tap.corrupt = true
+	if _, err := sendReceive(a, b, testMessageObj); !errors.Is(err, ErrFrameAuthentication) {
+		t.Errorf("expected authentication to fail when tampered, got %v", err)
+	}
+
+	// Plaintext frames
+	a, b, tap = newTestEncryptedPair(t, testFrameKeys, testFrameKeys, 1, 1)
+	if errA, errB := handshakePair(a, b); errA != nil || errB != nil {
+		t.Fatal(errA, errB)
+	}
+	go NewConn(tap.Conn).Send(testMessageObj)
+	if _, err := b.Receive(); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("expected plaintext frame to be invalid, got %v", err)
+	}
+
+	// Keys unknown to the peer fail the hello
+	a, b, _ = newTestEncryptedPair(t, testFrameKeys, testOtherFrameKeys, 2, 1)
+	if _, errB := handshakePair(a, b); errB == nil {
+		t.Error("expected unknown key to fail the hello")
+	}
+
+	// Keys of invalid size
+	a, b, _ = newTestEncryptedPair(t, FrameKeys{1: []byte("short")}, testFrameKeys, 1, 1)
+	if errA, _ := handshakePair(a, b); errA == nil {
+		t.Error("expected short key to fail")
+	}
+}
+
+func TestConnEncryptionChaCha20Poly1305(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	newPair := func(algB Encryption, keys FrameKeys) (*Conn, *Conn) {
+		pipeA, pipeB := net.Pipe()
+		a := NewConn(pipeA, WithRegistry(reg), WithEncryption(EncryptionChaCha20Poly1305, keys, 2))
+		b := NewConn(pipeB, WithRegistry(reg), WithEncryption(algB, keys, 2))
+		t.Cleanup(func() {
+			a.Close()
+			b.Close()
+		})
+		return a, b
+	}
+
+	a, b := newPair(EncryptionChaCha20Poly1305, testFrameKeys)
+	for range 3 {
+		if received, err := sendReceive(a, b, testMessageObj); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(received, testMessageObj) {
+			t.Errorf("unexpected message %+v", received)
+		}
+	}
+	if a.encryption.send.Overhead() != 16 || a.encryption.send.NonceSize() != 12 {
+		t.Errorf("unexpected AEAD %T", a.encryption.send)
+	}
+
+	// Both peers must use the same AEAD, the first to notice closing the
+	// connection
+	a, b = newPair(EncryptionAESGCM, testFrameKeys)
+	if errA, errB := handshakePair(a, b); errA == nil || errB == nil || !errors.Is(errA, ErrInvalidHandshake) && !errors.Is(errB, ErrInvalidHandshake) {
+		t.Errorf("expected invalid handshake with different AEADs, got %v and %v", errA, errB)
+	}
+
+	// Keys must be 32 bytes
+	a, b = newPair(EncryptionChaCha20Poly1305, FrameKeys{2: bytes.Repeat([]byte{0x44}, 16)})
+	if errA, _ := handshakePair(a, b); errA == nil {
+		t.Error("expected short key to fail")
+	}
+}
+
+func TestConnEncryptionServer(t *testing.T) {
+	opts := []ConnOption{
+		WithHandshake(Handshake{Version: 1}),
+		WithCompression(CompressionFlate, 0),
+		WithEncryption(EncryptionAESGCM, testFrameKeys, 1),
+	}
+
+	srv := NewServer(nil)
+	srv.ConnOptions = opts
+	defer srv.Close()
+
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		return s.Reply(ctx, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, srv)
+
+	client, err := Dial("tcp", addr, append(opts, WithRegistry(srv.Registry()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for range 3 {
+		if reply, err := client.Call(context.Background(), testMessageObj); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(reply, testMessageObj) {
+			t.Errorf("unexpected reply %+v", reply)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package bytocol
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	// Error indicating that a read succeeded, but did not fill the expected content
@@ -25,28 +29,187 @@ var (
 
 	// Error indicating that a nil pointer was supplied for unmarshaling
 	ErrNilTarget = errors.New("target for unmarshaling is nil")
+
+	// Error indicating that a string or byte slice is too long to be represented
+	// by the length prefix declared for the field, or that a count decoded is
+	// too large to allocate the elements.
+	ErrLengthOverflow = errors.New("content length overflows length prefix")
+
+	// Error indicating that a null-terminated string or byte slice contains a
+	// NUL byte within its content, which would terminate it early.
+	ErrNullInContent = errors.New("null-terminated content contains a NUL byte")
+
+	// Error indicating that a variable-length integer read is too large for
+	// 64 bits, or for the field it is decoded into.
+	ErrVarintOverflow = errors.New("variable-length integer overflows")
+
+	// Error indicating that the presence byte of an optional (pointer) field was
+	// neither 0 nor 1.
+	ErrInvalidPresence = errors.New("invalid presence byte for optional field")
+
+	// Error indicating a message type uses the type indicator 0 which is
+	// reserved for the built-in [ErrorMessage].
+	ErrReservedTypeIndicator = errors.New("type indicator 0 is reserved for error messages")
+
+	// Error indicating a message type was registered with a type indicator that
+	// is already used by another message type.
+	ErrDuplicateTypeIndicator = errors.New("duplicate type indicator")
+
+	// Error indicating a message was read with a type indicator that has not
+	// been registered.
+	ErrUnknownTypeIndicator = errors.New("unknown type indicator")
+
+	// Error indicating a time or duration does not fit in the integer of its
+	// unit, or a decoded integer does not fit in a [time.Duration].
+	ErrTimeOverflow = errors.New("time or duration overflows its unit")
+
+	// Error indicating the checksum trailer of a message does not match the
+	// checksum computed over its bytes.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// Error indicating a frame sent or received by a [Conn] is larger than the
+	// maximum frame size configured.
+	ErrFrameTooLarge = errors.New("frame exceeds maximum size")
+
+	// Error indicating a frame received by a [Conn] is malformed, such as being
+	// empty or having bytes left over after the message.
+	ErrInvalidFrame = errors.New("invalid frame")
+
+	// Error returned by [Server.Serve] once the server is shutdown or closed.
+	ErrServerClosed = errors.New("server closed")
+
+	// Error returned by [Client.Call] once the client is closed.
+	ErrClientClosed = errors.New("client closed")
+
+	// Error indicating the peer of a [Conn] did not send a valid handshake, such
+	// as not being a bytocol peer at all.
+	ErrInvalidHandshake = errors.New("invalid handshake")
+
+	// Error indicating the peers of a [Conn] have no protocol version in common.
+	ErrVersionMismatch = errors.New("no common protocol version")
+
+	// Error indicating the peers of a [Conn] negotiated the same version but
+	// their schema fingerprints differ.
+	ErrSchemaMismatch = errors.New("schema fingerprint mismatch")
+
+	// Error indicating an encrypted frame received by a [Conn] was not sealed by
+	// the peer with the expected key, or was tampered with.
+	ErrFrameAuthentication = errors.New("frame authentication failed")
+
+	// Error indicating an encrypted frame received by a [Conn] does not have the
+	// next sequence number, as it was replayed, reordered, or dropped.
+	ErrReplayedFrame = errors.New("replayed or out of order frame")
+
+	// Error indicating a message being decoded exceeds one of its
+	// [DecodeLimits], or the max option of a field.
+	ErrLimitExceeded = errors.New("decode limit exceeded")
 )
 
+// DecodeError is returned by [TypePlan.Read] when a field of the message could
+// not be decoded. It describes which field failed and where, and wraps the
+// underlying error such as [io.ErrUnexpectedEOF] or [ErrReadInvariance] so it
+// can be inspected with [errors.Is] and [errors.As].
+type DecodeError struct {
+	// Message is the debug name of the message being decoded.
+	Message string
+
+	// Field is the path of the field that failed. Nested struct fields are
+	// separated by dots, and slice, array, or map elements are in brackets,
+	// such as "Positions[2].X".
+	Field string
+
+	// Order is the field order from the tag of the top-level message field that
+	// failed.
+	Order uint
+
+	// Offset is the byte offset from the start of the message, including the
+	// type indicator, where the failing field started.
+	Offset int64
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	var str strings.Builder
+
+	str.WriteString("bytocol: cannot decode ")
+	if e.Message != "" {
+		str.WriteString(e.Message)
+		str.WriteByte(' ')
+	}
+	str.WriteString(fmt.Sprintf("field %s (order %d) at offset %d: %s", e.Field, e.Order, e.Offset, e.Err))
+
+	return str.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// prefixDecodeError prepends the path prefix to the field of a [DecodeError].
+// Other errors are wrapped into a new [DecodeError] using the prefix as the
+// field path and the offset provided.
+func prefixDecodeError(prefix string, offset int64, err error) *DecodeError {
+	var decErr *DecodeError
+	if !errors.As(err, &decErr) {
+		return &DecodeError{Field: prefix, Offset: offset, Err: err}
+	}
+
+	if strings.HasPrefix(decErr.Field, "[") {
+		decErr.Field = prefix + decErr.Field
+	} else {
+		decErr.Field = prefix + "." + decErr.Field
+	}
+	return decErr
+}
+
+// maxErrorMessageLength is the longest message an [ErrorMessage] can hold, as
+// limited by its 16-bit length prefix.
+const maxErrorMessageLength = 1<<16 - 1
+
 // ErrorMessage is a provided message type built-in for bytocol that wraps a
 // standard error message. It uses the reserved type-indicator of 0. The error
 // message is transmitted as a 16-bit length-prefixed string allowing for a
 // maximum error message size of 65,535 bytes. It uses the debug name "error".
+//
+// ErrorMessage implements the [error] interface itself, and when decoded by a
+// [Registry] it is returned as the error rather than the message.
 type ErrorMessage struct {
-	err error `bytocol:"0,length-prefix=2"`
+	// Message is the error string transmitted over the wire.
+	Message string `bytocol:"0,length-prefix=16"`
+
+	// err is the original error when wrapped locally using [Error], it is not
+	// transmitted.
+	err error
 }
 
 func (e ErrorMessage) BytocolMessage() MessageInfo {
-	return MessageInfo{0, "error"}
+	return MessageInfo{TypeIndicator: 0, DebugName: "error"}
+}
+
+// Error returns the error message, implementing the [error] interface.
+func (e ErrorMessage) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the original error if this [ErrorMessage] was created locally
+// using [Error]. Decoded error messages have no original error and return nil.
+func (e ErrorMessage) Unwrap() error {
+	return e.err
 }
 
 // Error wraps a standard error into an [ErrorMessage] for transmission as a
-// message.
+// message. A nil error results in an empty [ErrorMessage].
 func Error(err error) ErrorMessage {
-	return ErrorMessage{err}
+	if err == nil {
+		return ErrorMessage{}
+	}
+	return ErrorMessage{err.Error(), err}
 }
 
 // NewError constructs a new [ErrorMessage] and as such an [error] using the
 // given message contents.
 func NewError(msg string) ErrorMessage {
-	return ErrorMessage{errors.New(msg)}
+	return ErrorMessage{Message: msg}
 }
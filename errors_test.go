@@ -0,0 +1,45 @@
+package bytocol
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorMessage(t *testing.T) {
+	original := errors.New("original")
+	wrapped := Error(original)
+	if wrapped.Error() != "original" {
+		t.Errorf("unexpected error string %q", wrapped.Error())
+	} else if !errors.Is(wrapped, original) {
+		t.Error("expected wrapped error to unwrap to original")
+	}
+
+	if empty := Error(nil); empty.Error() != "" || empty.Unwrap() != nil {
+		t.Errorf("unexpected nil error message %#v", empty)
+	}
+
+	data, err := Marshal(NewError("hello"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Type indicator, 16-bit length prefix, then the message
+	expected := []byte{0, 0, 5, 'h', 'e', 'l', 'l', 'o'}
+	if string(data) != string(expected) {
+		t.Errorf("unexpected encoding %v", data)
+	}
+
+	plan, err := PlanType[ErrorMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var result ErrorMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if result.Error() != "hello" {
+		t.Errorf("unexpected decoded message %q", result.Error())
+	}
+}
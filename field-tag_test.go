@@ -1,6 +1,10 @@
 package bytocol
 
-import "testing"
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
 
 func TestParseFieldTag(t *testing.T) {
 	// Order only
@@ -27,8 +31,10 @@ func TestParseFieldTag(t *testing.T) {
 	tag, err = parseFieldTag("3, null-terminated")
 	if err != nil {
 		t.Error(err)
-	} else if tag.StringLengthPrefix {
+	} else if tag.StringLengthPrefix || !tag.NullTerminated {
 		t.Errorf("expected null-terminated string")
+	} else if tag.Order != 3 {
+		t.Errorf("expected order to be 3 with options, got %d", tag.Order)
 	}
 
 	// With length-prefix, space escaped
@@ -41,6 +47,64 @@ func TestParseFieldTag(t *testing.T) {
 		t.Errorf("expected length size to be 8, instead got %d", tag.StringLengthSize)
 	}
 
+	// With varint
+	tag, err = parseFieldTag("2,varint")
+	if err != nil {
+		t.Error(err)
+	} else if !tag.Varint {
+		t.Error("expected varint")
+	}
+
+	// Catch varint combined with other length options
+	_, err = parseFieldTag("2,varint,length-prefix=8")
+	if err == nil {
+		t.Error("expected error for varint with length-prefix")
+	}
+
+	// With endian
+	tag, err = parseFieldTag("2, endian=little")
+	if err != nil {
+		t.Error(err)
+	} else if tag.ByteOrder != binary.LittleEndian {
+		t.Error("expected little endian")
+	}
+
+	// Catch invalid endian
+	_, err = parseFieldTag("2,endian=middle")
+	if err == nil {
+		t.Error("expected error for invalid endian")
+	}
+
+	// With time unit and zone
+	tag, err = parseFieldTag("4,unit=ms,tz")
+	if err != nil {
+		t.Error(err)
+	} else if tag.Unit != time.Millisecond || !tag.TimeZone {
+		t.Errorf("expected millisecond unit with zone, instead got %s %t", tag.Unit, tag.TimeZone)
+	}
+
+	// Catch invalid unit
+	_, err = parseFieldTag("4,unit=days")
+	if err == nil {
+		t.Error("expected error for invalid unit")
+	}
+
+	// With max
+	tag, err = parseFieldTag("5,length-prefix=16,max=1024")
+	if err != nil {
+		t.Error(err)
+	} else if tag.Max != 1024 || tag.StringLengthSize != 16 {
+		t.Errorf("expected max of 1024 with 16-bit prefix, instead got %d %d", tag.Max, tag.StringLengthSize)
+	}
+
+	// Catch zero or non-number max
+	if _, err = parseFieldTag("5,max=0"); err == nil {
+		t.Error("expected error for zero max")
+	}
+	if _, err = parseFieldTag("5,max=-1"); err == nil {
+		t.Error("expected error for negative max")
+	}
+
 	// Catch length-prefix non-number
 	_, err = parseFieldTag("3, length-prefix=foo")
 	if err == nil {
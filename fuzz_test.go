@@ -0,0 +1,196 @@
+package bytocol
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fuzzMessages cover every kind of field, their encodings seeding the corpus
+// of the fuzz targets.
+func fuzzMessages() []Message {
+	owner := testColor(0x445566)
+	expires := time.Unix(1700000000, 0).UTC()
+	limit := -7
+	return []Message{
+		testMessageObj,
+		testCodecMessage{
+			Color:   0x112233,
+			Addr:    netip.MustParseAddr("10.0.0.1"),
+			Version: testVersion{1, 2},
+			Words:   testWords{"hello", "world"},
+			Points:  []testPoint{{-1, 1}},
+			Owner:   &owner,
+		},
+		testListMessage{
+			Numbers:   []uint32{1, 2, 3},
+			Names:     []string{"a", "bc"},
+			Positions: []testPosition{{1, 2, 3}},
+			Headers:   [2]testHeader{{1, "x"}, {2, "yz"}},
+			Nested:    [][]byte{{1}, {2, 3}},
+		},
+		testMapMessage{
+			Counters: map[string]uint16{"zeta": 1, "alpha": 2},
+			Lookup:   map[int8]testPosition{-5: {1, 2, 3}},
+			Tags:     map[uint32][]string{1: {"a", "aa"}},
+			Flags:    map[bool]bool{true: false},
+		},
+		testOptionalMessage{Name: new(string), Position: &testPosition{1, 2, 3}},
+		testVarintMessage{Counter: 300, Delta: -2, Name: "abc", Samples: []int16{1, 2}, Labels: map[string]string{"k": "v"}, Limit: &limit},
+		testTimeMessage{
+			Created:  time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC),
+			Zoned:    time.Date(2024, 5, 6, 9, 8, 9, 500000000, time.FixedZone("", 3600)),
+			Timeout:  1500 * time.Millisecond,
+			Expires:  &expires,
+			History:  []time.Time{{}, time.Unix(1, 0).UTC()},
+			Spans:    []time.Duration{time.Hour},
+			Interval: -time.Minute,
+		},
+		testSchemaMessage{ID: 1, Name: "name", Label: "label", Tags: map[string]int16{"t": -1}},
+		testChecksumMessage{ID: 1, Name: "sum"},
+		testLimitsMessage{Data: []byte("data"), Items: []uint32{1}, Nested: [][]testItem{{}}},
+		testEmptyMessage{},
+	}
+}
+
+// newFuzzRegistry registers every fuzzed message, returning the registry and
+// the encoding of each message.
+func newFuzzRegistry(f *testing.F) (*Registry, [][]byte) {
+	reg := NewRegistry()
+	var corpus [][]byte
+	for _, msg := range fuzzMessages() {
+		plan, err := PlanObject(msg)
+		if err != nil {
+			f.Fatal(err)
+		}
+		reg.types[plan.typeIndicator] = registryEntry{plan, plan.typeOf}
+
+		data, err := plan.Marshal(msg)
+		if err != nil {
+			f.Fatal(err)
+		}
+		corpus = append(corpus, data)
+	}
+	return reg, corpus
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	reg, corpus := newFuzzRegistry(f)
+	for _, data := range corpus {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		plan, ok := reg.Plan(data[0])
+		if !ok {
+			return
+		}
+
+		// Anything decoded can be encoded again
+		target := reflect.New(plan.typeOf)
+		if err := plan.Unmarshal(data[1:], target.Interface().(Message)); err != nil {
+			return
+		}
+		if _, err := plan.Marshal(target.Elem().Interface().(Message)); err != nil {
+			t.Errorf("cannot encode decoded message: %v", err)
+		}
+	})
+}
+
+func FuzzExplain(f *testing.F) {
+	reg, corpus := newFuzzRegistry(f)
+	for _, data := range corpus {
+		f.Add(data)
+	}
+
+	// String lengths past the end of the data, which do not fit in an int
+	overflow := bytes.Clone(corpus[0])
+	copy(overflow[16:24], bytes.Repeat([]byte{0xff}, 8))
+	f.Add(overflow)
+
+	// No type indicator at all
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, plan := range reg.Plans() {
+			_ = plan.Explain(data)
+		}
+	})
+}
+
+func FuzzParseFieldTag(f *testing.F) {
+	for _, tag := range []string{
+		"0",
+		"1,length-prefix=16",
+		"2,null-terminated,max=4",
+		"3,varint,endian=little",
+		"4,unit=ms,tz",
+		" 5 , length-prefix=64 , endian=big",
+		"6,max=18446744073709551615",
+	} {
+		f.Add(tag)
+	}
+
+	f.Fuzz(func(t *testing.T, tag string) {
+		info, err := parseFieldTag(tag)
+		if err != nil {
+			return
+		}
+
+		if info.Varint && (info.StringLengthPrefix || info.NullTerminated) {
+			t.Errorf("tag %q combines varint with length options", tag)
+		} else if info.StringLengthPrefix && info.NullTerminated {
+			t.Errorf("tag %q is both length prefixed and null-terminated", tag)
+		}
+	})
+}
+
+// fuzzStream joins a reader and a writer into a connection stream.
+type fuzzStream struct {
+	io.Reader
+	io.Writer
+}
+
+func (fuzzStream) Close() error {
+	return nil
+}
+
+func FuzzConnReceive(f *testing.F) {
+	reg, _ := newFuzzRegistry(f)
+	opts := []ConnOption{
+		WithRegistry(reg),
+		WithMaxFrameSize(1 << 16),
+		WithCompression(CompressionFlate, 16),
+	}
+
+	// The corpus holds frames of every message, sent both plain and
+	// compressed, and correlated as requests
+	var frames bytes.Buffer
+	sender := NewConn(fuzzStream{Writer: &frames}, opts...)
+	for _, msg := range fuzzMessages() {
+		frames.Reset()
+		if err := sender.Send(msg); err != nil {
+			f.Fatal(err)
+		} else if err = sender.sendFrame(frameHeader{flags: frameFlagCorrelated, id: 7}, msg); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(bytes.Clone(frames.Bytes()))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		conn := NewConn(fuzzStream{bytes.NewReader(data), io.Discard}, opts...)
+
+		// Every frame consumes at least its header
+		for i := 0; i <= len(data)/frameHeaderSize; i++ {
+			if _, err := conn.Receive(); err == io.EOF {
+				return
+			}
+		}
+	})
+}
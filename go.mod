@@ -1,3 +1,7 @@
 module github.com/maple-tech/bytocol
 
 go 1.22.2
+
+require golang.org/x/crypto v0.31.0
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,166 @@
+package bytocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// handshakeMagic starts every handshake, identifying bytocol peers.
+var handshakeMagic = [4]byte{'B', 'Y', 'T', 'C'}
+
+// handshakeFormat is the version of the handshake itself, following the magic.
+const handshakeFormat = 1
+
+// handshakeSize is the size of the handshake sent by each peer: the magic, the
+// handshake format, the lowest and highest protocol versions supported as
+// 16-bit integers, the 64-bit schema fingerprint, all big-endian, and the
+// [Compression] enabled.
+const handshakeSize = 4 + 1 + 2 + 2 + 8 + 1
+
+// Handshake configures the handshake exchanged by the peers of a [Conn] before
+// any frame, enabled with [WithHandshake]. Both peers send their supported
+// protocol versions, schema fingerprint, and compression at the same time, and
+// agree on the highest version they have in common without any further round
+// trip.
+//
+// The fingerprints are only compared when both peers use their highest
+// version, as peers negotiating down to an older version are expected to have
+// a different schema.
+type Handshake struct {
+	// Version is the highest protocol version supported, and MinVersion the
+	// lowest. When MinVersion is zero only Version is supported.
+	Version    uint16
+	MinVersion uint16
+
+	// Fingerprint identifies the schema of the peer. When zero the
+	// [Registry.Fingerprint] of the connection is used.
+	Fingerprint uint64
+}
+
+// WithHandshake enables the handshake on the connection, which is exchanged
+// before the first frame is sent or received, or explicitly with
+// [Conn.Handshake].
+func WithHandshake(hs Handshake) ConnOption {
+	return func(c *Conn) {
+		c.handshake = &hs
+	}
+}
+
+// Handshake exchanges the handshake with the peer if enabled with
+// [WithHandshake], followed by the encryption hello if enabled with
+// [WithEncryption], and does nothing otherwise. It only runs once, later calls
+// return the same result. It fails with [ErrInvalidHandshake] if the peer did
+// not send a valid handshake, in which case the connection is closed,
+// [ErrVersionMismatch] if the peers have no version in common, and
+// [ErrSchemaMismatch] if their schemas differ.
+//
+// Sending and receiving frames runs the handshake first, so calling it is only
+// needed to fail fast before any message.
+func (c *Conn) Handshake() error {
+	if c.handshake == nil && c.encryption == nil {
+		return nil
+	}
+
+	c.handshakeOnce.Do(func() {
+		if c.handshake != nil {
+			c.handshakeErr = c.exchangeHandshake(*c.handshake)
+		}
+		if c.handshakeErr == nil && c.encryption != nil {
+			c.handshakeErr = c.encryption.exchangeHello(c.rwc)
+		}
+	})
+	return c.handshakeErr
+}
+
+// Version returns the protocol version negotiated by the handshake, running it
+// first if needed, or 0 if there is none or it failed.
+func (c *Conn) Version() uint16 {
+	if c.Handshake() != nil {
+		return 0
+	}
+	return c.version
+}
+
+// exchangeHandshake sends the handshake while reading the one of the peer,
+// then negotiates the version.
+func (c *Conn) exchangeHandshake(hs Handshake) error {
+	minVersion := hs.MinVersion
+	if minVersion == 0 {
+		minVersion = hs.Version
+	} else if minVersion > hs.Version {
+		return fmt.Errorf("bytocol: handshake minimum version %d above version %d", minVersion, hs.Version)
+	}
+
+	fingerprint := hs.Fingerprint
+	if fingerprint == 0 {
+		fingerprint = c.reg.Fingerprint()
+	}
+
+	var local [handshakeSize]byte
+	copy(local[:], handshakeMagic[:])
+	local[4] = handshakeFormat
+	binary.BigEndian.PutUint16(local[5:], minVersion)
+	binary.BigEndian.PutUint16(local[7:], hs.Version)
+	binary.BigEndian.PutUint64(local[9:], fingerprint)
+	local[17] = byte(c.compression)
+
+	var peer [handshakeSize]byte
+	err := exchange(c.rwc, local[:], peer[:], func() error {
+		if !bytes.Equal(peer[:4], handshakeMagic[:]) {
+			return fmt.Errorf("bytocol: %w, unexpected magic %q", ErrInvalidHandshake, peer[:4])
+		} else if peer[4] != handshakeFormat {
+			return fmt.Errorf("bytocol: %w, unsupported format %d", ErrInvalidHandshake, peer[4])
+		} else if peer[17] > byte(CompressionGzip) {
+			return fmt.Errorf("bytocol: %w, unknown compression %d", ErrInvalidHandshake, peer[17])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	peerMin := binary.BigEndian.Uint16(peer[5:])
+	peerVersion := binary.BigEndian.Uint16(peer[7:])
+	peerFingerprint := binary.BigEndian.Uint64(peer[9:])
+
+	version := min(hs.Version, peerVersion)
+	if version < max(minVersion, peerMin) {
+		return fmt.Errorf("bytocol: %w, supporting %d to %d while the peer supports %d to %d", ErrVersionMismatch, minVersion, hs.Version, peerMin, peerVersion)
+	} else if version == hs.Version && version == peerVersion && fingerprint != peerFingerprint {
+		return fmt.Errorf("bytocol: %w for version %d, %016x while the peer has %016x", ErrSchemaMismatch, version, fingerprint, peerFingerprint)
+	}
+
+	c.version = version
+	c.compression = negotiateCompression(c.compression, Compression(peer[17]))
+	return nil
+}
+
+// exchange sends the local bytes while reading the peer ones, which are checked
+// by validate. The stream is closed if the peer ones cannot be read or are
+// invalid.
+func exchange(rwc io.ReadWriteCloser, local, peer []byte, validate func() error) error {
+	// Unbuffered streams such as net.Pipe block writes until they are read
+	sent := make(chan error, 1)
+	go func() {
+		_, err := rwc.Write(local)
+		sent <- err
+	}()
+
+	_, err := io.ReadFull(rwc, peer)
+	if err != nil {
+		err = fmt.Errorf("bytocol: reading handshake: %w", err)
+	} else {
+		err = validate()
+	}
+
+	// Invalid peers may never read the handshake, so close the stream to
+	// unblock the write rather than leaking it
+	if err != nil {
+		rwc.Close()
+		<-sent
+		return err
+	}
+	return <-sent
+}
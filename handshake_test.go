@@ -0,0 +1,145 @@
+package bytocol
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// handshakePair runs the handshake of both connections at the same time.
+func handshakePair(a, b *Conn) (error, error) {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- b.Handshake()
+	}()
+
+	errA := a.Handshake()
+	if errA != nil {
+		// The peer may be waiting on the handshake being read
+		a.Close()
+	}
+	return errA, <-errs
+}
+
+func TestHandshake(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+	other := NewRegistry()
+	if _, err := Register[testBlobMessage](other); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		a, b     Handshake
+		otherReg bool
+		version  uint16
+		expected error
+	}{
+		{"same", Handshake{Version: 2}, Handshake{Version: 2}, false, 2, nil},
+		{"negotiate down", Handshake{Version: 3, MinVersion: 1}, Handshake{Version: 2, MinVersion: 1}, true, 2, nil},
+		{"no common version", Handshake{Version: 3, MinVersion: 3}, Handshake{Version: 2, MinVersion: 1}, false, 0, ErrVersionMismatch},
+		{"schema mismatch", Handshake{Version: 2}, Handshake{Version: 2, MinVersion: 1}, true, 0, ErrSchemaMismatch},
+		{"explicit fingerprint", Handshake{Version: 1, Fingerprint: 7}, Handshake{Version: 1, Fingerprint: 7}, true, 1, nil},
+	} {
+		bReg := reg
+		if test.otherReg {
+			bReg = other
+		}
+
+		pipeA, pipeB := net.Pipe()
+		a := NewConn(pipeA, WithRegistry(reg), WithHandshake(test.a))
+		b := NewConn(pipeB, WithRegistry(bReg), WithHandshake(test.b))
+
+		// Both peers reach the same conclusion
+		errA, errB := handshakePair(a, b)
+		if !errors.Is(errA, test.expected) || !errors.Is(errB, test.expected) {
+			t.Errorf("%s: expected %v, got %v and %v", test.name, test.expected, errA, errB)
+		} else if a.Version() != test.version || b.Version() != test.version {
+			t.Errorf("%s: expected version %d, got %d and %d", test.name, test.version, a.Version(), b.Version())
+		}
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestHandshakeInvalid(t *testing.T) {
+	pipeA, pipeB := net.Pipe()
+	conn := NewConn(pipeA, WithHandshake(Handshake{Version: 1}))
+	defer conn.Close()
+
+	// A peer speaking frames without handshake
+	go NewConn(pipeB).Send(testMessageObj)
+	if err := conn.Handshake(); !errors.Is(err, ErrInvalidHandshake) {
+		t.Errorf("expected invalid handshake, got %v", err)
+	}
+
+	// Frames fail with the handshake error
+	if err := conn.Send(testMessageObj); !errors.Is(err, ErrInvalidHandshake) {
+		t.Errorf("expected send to fail with the handshake, got %v", err)
+	}
+
+	// The connection is closed rather than left writing the handshake
+	if _, err := pipeB.Read(make([]byte, handshakeSize)); !errors.Is(err, io.EOF) {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+	pipeB.Close()
+}
+
+func TestHandshakeServer(t *testing.T) {
+	srv := NewServer(nil)
+	srv.ConnOptions = []ConnOption{WithHandshake(Handshake{Version: 2, MinVersion: 1})}
+	defer srv.Close()
+
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		return s.Reply(ctx, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, srv)
+
+	client, err := Dial("tcp", addr, WithRegistry(srv.Registry()), WithHandshake(Handshake{Version: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err = client.Call(context.Background(), testMessageObj); err != nil {
+		t.Error(err)
+	}
+
+	// A different schema fails before any message
+	if _, err = Dial("tcp", addr, WithHandshake(Handshake{Version: 2})); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("expected schema mismatch, got %v", err)
+	}
+	if _, err = Dial("tcp", addr, WithHandshake(Handshake{Version: 3, MinVersion: 3})); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("expected version mismatch, got %v", err)
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	previous, err := ParseIDL("message A 1 {\n\t0 X uint32\n\t1 P Point\n}\nstruct Point {\n\t0 X int16\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Names do not change the fingerprint
+	renamed, err := ParseIDL("message B 1 {\n\t0 Y uint32\n\t1 Q Pos\n}\nstruct Pos {\n\t0 Z int16\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	} else if previous.Fingerprint() != renamed.Fingerprint() {
+		t.Error("expected renamed schema to have the same fingerprint")
+	}
+
+	changed, err := ParseIDL("message A 1 {\n\t0 X uint32\n\t1 P Point\n}\nstruct Point {\n\t0 X int32\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	} else if previous.Fingerprint() == changed.Fingerprint() {
+		t.Error("expected nested change to change the fingerprint")
+	}
+}
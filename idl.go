@@ -0,0 +1,632 @@
+package bytocol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/maple-tech/bytocol/internal/tags"
+)
+
+// The IDL text describes a schema with one block per message or struct. Lines
+// starting with // are comments. Every field is a line of its order, name,
+// type, and optionally the options of its bytocol tag:
+//
+//	message Move 2 "move" endian=little checksum=crc32 {
+//		0 Entity uint32 varint
+//		1 To Position
+//		2 Path []Position length-prefix=16
+//	}
+//
+//	struct Position {
+//		0 X float32
+//		1 Y float32
+//	}
+//
+// The types are the kinds of numbers, bool, string, bytes, time, duration,
+// codec for variable size codecs and codec(N) for fixed size ones, []T for
+// slices, [N]T for arrays, map[K]V for maps, *T for optional values, and the
+// names of structs.
+
+// IDL returns the schema as IDL text, which [ParseIDL] parses back into the
+// same schema.
+func (s *Schema) IDL() string {
+	var str strings.Builder
+
+	for i, msg := range s.Messages {
+		if i > 0 {
+			str.WriteByte('\n')
+		}
+
+		str.WriteString("message ")
+		str.WriteString(msg.Name)
+		str.WriteByte(' ')
+		str.WriteString(strconv.FormatUint(uint64(msg.TypeIndicator), 10))
+		if msg.DebugName != "" {
+			str.WriteByte(' ')
+			str.WriteString(strconv.Quote(msg.DebugName))
+		}
+		if msg.ByteOrder == "little" {
+			str.WriteString(" endian=little")
+		}
+		if msg.Checksum != "" {
+			str.WriteString(" checksum=")
+			str.WriteString(msg.Checksum)
+		}
+		str.WriteString(" {\n")
+		writeIDLFields(&str, msg.Fields)
+		str.WriteString("}\n")
+	}
+
+	for i, st := range s.Structs {
+		if i > 0 || len(s.Messages) > 0 {
+			str.WriteByte('\n')
+		}
+
+		str.WriteString("struct ")
+		str.WriteString(st.Name)
+		str.WriteString(" {\n")
+		writeIDLFields(&str, st.Fields)
+		str.WriteString("}\n")
+	}
+
+	return str.String()
+}
+
+func writeIDLFields(str *strings.Builder, fields []FieldSchema) {
+	for _, field := range fields {
+		str.WriteByte('\t')
+		str.WriteString(strconv.FormatUint(uint64(field.Order), 10))
+		str.WriteByte(' ')
+		str.WriteString(field.Name)
+		str.WriteByte(' ')
+		str.WriteString(field.Type.idlType())
+		if options := field.Type.idlOptions(); options != "" {
+			str.WriteByte(' ')
+			str.WriteString(options)
+		}
+		str.WriteByte('\n')
+	}
+}
+
+// idlType returns the IDL type expression.
+func (ts TypeSchema) idlType() string {
+	switch ts.Kind {
+	case KindCodec:
+		if ts.Variable {
+			return "codec"
+		}
+		return "codec(" + strconv.FormatUint(uint64(ts.Size), 10) + ")"
+	case KindSlice:
+		return "[]" + ts.Elem.idlType()
+	case KindArray:
+		return "[" + strconv.Itoa(ts.Length) + "]" + ts.Elem.idlType()
+	case KindMap:
+		return "map[" + ts.Key.idlType() + "]" + ts.Elem.idlType()
+	case KindOptional:
+		return "*" + ts.Elem.idlType()
+	case KindStruct:
+		return ts.Struct
+	}
+	return string(ts.Kind)
+}
+
+// idlOptions returns the tag options of the field type, which apply to the
+// value being pointed to of optional types.
+func (ts TypeSchema) idlOptions() string {
+	options := make([]string, 0)
+	if ts.ByteOrder != "" {
+		options = append(options, "endian="+ts.ByteOrder)
+	}
+
+	value := ts
+	for value.Kind == KindOptional {
+		value = *value.Elem
+	}
+
+	switch {
+	case value.Varint:
+		options = append(options, "varint")
+	case value.NullTerminated:
+		options = append(options, "null-terminated")
+	case value.Prefix != 0 && value.Prefix != 64:
+		options = append(options, "length-prefix="+strconv.Itoa(int(value.Prefix)))
+	}
+	if value.Unit != "" && value.Unit != "ns" {
+		options = append(options, "unit="+value.Unit)
+	}
+	if value.TimeZone {
+		options = append(options, "tz")
+	}
+
+	return strings.Join(options, ",")
+}
+
+// ParseIDL parses the IDL text written by [Schema.IDL], or by hand, into a
+// schema. The sizes of every type are computed following the same rules as the
+// planner.
+func ParseIDL(src string) (*Schema, error) {
+	p := idlParser{
+		resolved: make(map[string]*StructSchema),
+	}
+	if err := p.parse(src); err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{
+		Messages: make([]MessageSchema, 0, len(p.messages)),
+	}
+	for _, block := range p.structs {
+		st, err := p.resolveStruct(block.name, nil)
+		if err != nil {
+			return nil, err
+		}
+		schema.Structs = append(schema.Structs, *st)
+	}
+
+	for _, block := range p.messages {
+		st, err := p.resolveFields(block, block.byteOrder, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		msg := MessageSchema{
+			StructSchema:  st,
+			DebugName:     block.debugName,
+			TypeIndicator: block.typeIndicator,
+			ByteOrder:     byteOrderName(block.byteOrder),
+		}
+		if block.checksum != ChecksumNone {
+			msg.Size += uint(block.checksum.Size())
+			msg.Checksum = block.checksum.String()
+		}
+		schema.Messages = append(schema.Messages, msg)
+	}
+
+	return schema, nil
+}
+
+// idlBlock is a message or struct block as written, before its types are
+// resolved.
+type idlBlock struct {
+	line   int
+	name   string
+	fields []idlField
+
+	// Only set for messages
+	debugName     string
+	typeIndicator byte
+	byteOrder     binary.ByteOrder
+	checksum      Checksum
+}
+
+type idlField struct {
+	line     int
+	name     string
+	typeExpr string
+	tag      tags.Tag
+}
+
+type idlParser struct {
+	messages []*idlBlock
+	structs  []*idlBlock
+	resolved map[string]*StructSchema
+}
+
+// idlLineError is an error at a line of the IDL text.
+type idlLineError struct {
+	line int
+	err  error
+}
+
+func idlError(line int, format string, args ...any) error {
+	return &idlLineError{line: line, err: fmt.Errorf(format, args...)}
+}
+
+func (e *idlLineError) Error() string {
+	return fmt.Sprintf("bytocol: idl line %d: %s", e.line, e.err)
+}
+
+func (e *idlLineError) Unwrap() error {
+	return e.err
+}
+
+// parse reads the blocks and their fields from the source.
+func (p *idlParser) parse(src string) error {
+	var block *idlBlock
+	names := make(map[string]bool)
+	indicators := make(map[byte]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if comment := strings.Index(text, "//"); comment != -1 {
+			text = text[:comment]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		if block != nil {
+			if text == "}" {
+				block = nil
+				continue
+			}
+
+			field, err := parseIDLField(line, text)
+			if err != nil {
+				return err
+			}
+			block.fields = append(block.fields, field)
+			continue
+		}
+
+		var err error
+		block, err = parseIDLHeader(line, text)
+		if err != nil {
+			return err
+		}
+
+		if block.byteOrder == nil {
+			if names[block.name] {
+				return idlError(line, "duplicate struct %s", block.name)
+			}
+			names[block.name] = true
+			p.structs = append(p.structs, block)
+		} else {
+			if indicators[block.typeIndicator] {
+				return idlError(line, "%w %d", ErrDuplicateTypeIndicator, block.typeIndicator)
+			}
+			indicators[block.typeIndicator] = true
+			p.messages = append(p.messages, block)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	} else if block != nil {
+		return idlError(block.line, "missing } of %s", block.name)
+	}
+	return nil
+}
+
+// parseIDLHeader parses the first line of a message or struct block. Messages
+// always have a byte order, while structs inherit theirs.
+func parseIDLHeader(line int, text string) (*idlBlock, error) {
+	rest, ok := strings.CutSuffix(text, "{")
+	if !ok {
+		return nil, idlError(line, "expected { at the end of %q", text)
+	}
+
+	words := strings.Fields(rest)
+	if len(words) < 2 {
+		return nil, idlError(line, "expected message or struct name")
+	} else if !isIDLName(words[1]) {
+		return nil, idlError(line, "invalid name %q", words[1])
+	}
+
+	block := &idlBlock{line: line, name: words[1]}
+	switch words[0] {
+	case "struct":
+		if len(words) > 2 {
+			return nil, idlError(line, "unexpected %q after struct name", strings.Join(words[2:], " "))
+		}
+		return block, nil
+	case "message":
+	default:
+		return nil, idlError(line, "expected message or struct, got %q", words[0])
+	}
+
+	// Messages have a type indicator, optionally followed by the debug name
+	// and byte order
+	rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), "message"))
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, block.name))
+	indicator := rest
+	if end := strings.IndexFunc(rest, unicode.IsSpace); end != -1 {
+		indicator, rest = rest[:end], rest[end:]
+	} else {
+		rest = ""
+	}
+	u64, err := strconv.ParseUint(indicator, 10, 8)
+	if err != nil {
+		return nil, idlError(line, "invalid type indicator %q", indicator)
+	}
+	block.typeIndicator = byte(u64)
+	block.byteOrder = binary.BigEndian
+
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, `"`) {
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, idlError(line, "invalid debug name %s", rest)
+		}
+		block.debugName, _ = strconv.Unquote(quoted)
+		rest = strings.TrimSpace(rest[len(quoted):])
+	}
+
+	for _, option := range strings.Fields(rest) {
+		key, value, _ := strings.Cut(option, "=")
+		switch {
+		case option == "endian=big":
+		case option == "endian=little":
+			block.byteOrder = binary.LittleEndian
+		case key == "checksum":
+			var ok bool
+			if block.checksum, ok = parseChecksum(value); !ok {
+				return nil, idlError(line, "unknown checksum %q", value)
+			}
+		default:
+			return nil, idlError(line, "unexpected %q in message header", option)
+		}
+	}
+	return block, nil
+}
+
+// parseIDLField parses a field line, the options are parsed as a bytocol tag
+// and may be separated by commas or spaces.
+func parseIDLField(line int, text string) (idlField, error) {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(words) < 3 {
+		return idlField{}, idlError(line, "expected order, name, and type of field")
+	} else if !isIDLName(words[1]) {
+		return idlField{}, idlError(line, "invalid field name %q", words[1])
+	}
+
+	tag, err := tags.Parse(strings.Join(append([]string{words[0]}, words[3:]...), ","))
+	if err != nil {
+		return idlField{}, idlError(line, "field %s: %w", words[1], err)
+	}
+
+	return idlField{line: line, name: words[1], typeExpr: words[2], tag: tag}, nil
+}
+
+func isIDLName(name string) bool {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// resolveStruct returns the struct with the name, resolving it the first time.
+// The stack holds the structs being resolved to catch recursion.
+func (p *idlParser) resolveStruct(name string, stack []string) (*StructSchema, error) {
+	if st, ok := p.resolved[name]; ok {
+		return st, nil
+	} else if slices.Contains(stack, name) {
+		return nil, fmt.Errorf("recursive struct %s is not supported", name)
+	}
+
+	i := slices.IndexFunc(p.structs, func(block *idlBlock) bool {
+		return block.name == name
+	})
+	if i == -1 {
+		return nil, nil
+	}
+
+	st, err := p.resolveFields(p.structs[i], nil, append(stack, name))
+	if err != nil {
+		return nil, err
+	}
+	p.resolved[name] = &st
+	return &st, nil
+}
+
+// resolveFields resolves the types of the fields of the block, sorted by their
+// order. The byte order is only known for messages.
+func (p *idlParser) resolveFields(block *idlBlock, order binary.ByteOrder, stack []string) (StructSchema, error) {
+	st := StructSchema{
+		Name:   block.name,
+		Fields: make([]FieldSchema, 0, len(block.fields)),
+	}
+
+	for _, field := range block.fields {
+		ts, err := p.resolveType(field.typeExpr, field.tag, stack)
+		var lineErr *idlLineError
+		if errors.As(err, &lineErr) {
+			// Errors within nested structs are reported at their own line
+			return st, err
+		} else if err != nil {
+			return st, idlError(field.line, "field %s: %w", field.name, err)
+		}
+
+		// Redundant byte orders of messages are dropped like the planner does
+		if order != nil && ts.ByteOrder == byteOrderName(order) {
+			ts.ByteOrder = ""
+		}
+
+		st.Size += ts.Size
+		st.Variable = st.Variable || ts.Variable
+		st.Fields = append(st.Fields, FieldSchema{Name: field.name, Order: field.tag.Order, Type: ts})
+	}
+
+	if len(st.Fields) == 0 {
+		return st, idlError(block.line, "%s has no fields", block.name)
+	}
+
+	slices.SortStableFunc(st.Fields, func(a, b FieldSchema) int {
+		return int(a.Order) - int(b.Order)
+	})
+	for i := 1; i < len(st.Fields); i++ {
+		if st.Fields[i].Order == st.Fields[i-1].Order {
+			return st, idlError(block.line, "duplicate field order %d on field %s", st.Fields[i].Order, st.Fields[i].Name)
+		}
+	}
+	return st, nil
+}
+
+// fixedIDLSizes are the sizes of the kinds with a fixed size.
+var fixedIDLSizes = map[Kind]uint{
+	KindBool: 1, KindUint8: 1, KindInt8: 1,
+	KindUint16: 2, KindInt16: 2,
+	KindUint32: 4, KindInt32: 4, KindFloat32: 4,
+	KindUint64: 8, KindInt64: 8, KindFloat64: 8,
+}
+
+// resolveType resolves the type expression with the tag options into its
+// schema, following the same rules as [planEntry.plan].
+func (p *idlParser) resolveType(expr string, tag tags.Tag, stack []string) (TypeSchema, error) {
+	var ts TypeSchema
+	var err error
+	if tag.ByteOrder != nil {
+		ts.ByteOrder = byteOrderName(tag.ByteOrder)
+	}
+
+	// The options left over once applied are not supported by the type
+	lengthOptions := tag.StringLengthPrefix || tag.NullTerminated || tag.Varint
+	timeOptions := tag.Unit != 0 || tag.TimeZone
+
+	switch {
+	case strings.HasPrefix(expr, "*"):
+		// Options apply to the value being pointed to
+		valueTag := tag
+		valueTag.ByteOrder = nil
+		var elem TypeSchema
+		if elem, err = p.resolveType(expr[1:], valueTag, stack); err != nil {
+			return ts, err
+		}
+		ts.Kind, ts.Size, ts.Variable, ts.Elem = KindOptional, 1, true, &elem
+		return ts, nil
+
+	case strings.HasPrefix(expr, "[]"):
+		if tag.NullTerminated {
+			return ts, fmt.Errorf("null-terminated is not supported on slice type %s", expr)
+		}
+		ts.Kind = KindSlice
+		ts.prefix(tag)
+		ts.Elem, err = p.resolveElem(expr[2:], stack)
+		lengthOptions = false
+
+	case strings.HasPrefix(expr, "["):
+		length, elemExpr, ok := strings.Cut(expr[1:], "]")
+		if ts.Length, err = strconv.Atoi(length); !ok || err != nil || ts.Length < 0 {
+			return ts, fmt.Errorf("invalid array type %s", expr)
+		}
+		ts.Kind = KindArray
+		if ts.Elem, err = p.resolveElem(elemExpr, stack); err == nil {
+			ts.Size = uint(ts.Length) * ts.Elem.Size
+			ts.Variable = ts.Elem.Variable
+		}
+
+	case strings.HasPrefix(expr, "map["):
+		keyExpr, elemExpr, ok := strings.Cut(expr[4:], "]")
+		if !ok {
+			return ts, fmt.Errorf("invalid map type %s", expr)
+		} else if tag.NullTerminated {
+			return ts, fmt.Errorf("null-terminated is not supported on map type %s", expr)
+		}
+		ts.Kind = KindMap
+		ts.prefix(tag)
+		if ts.Key, err = p.resolveElem(keyExpr, stack); err == nil {
+			if ts.Key.Kind != KindString && fixedIDLSizes[ts.Key.Kind] == 0 {
+				return ts, fmt.Errorf("unsupported map key type %s", keyExpr)
+			}
+			ts.Elem, err = p.resolveElem(elemExpr, stack)
+		}
+		lengthOptions = false
+
+	case expr == "codec":
+		if tag.NullTerminated {
+			return ts, fmt.Errorf("null-terminated is not supported on codec type")
+		}
+		ts.Kind = KindCodec
+		ts.prefix(tag)
+		lengthOptions = false
+
+	case strings.HasPrefix(expr, "codec("):
+		size, ok := strings.CutSuffix(expr[6:], ")")
+		u64, err := strconv.ParseUint(size, 10, 32)
+		if !ok || err != nil || u64 == 0 {
+			return ts, fmt.Errorf("invalid codec size %s", expr)
+		}
+		ts.Kind, ts.Size = KindCodec, uint(u64)
+
+	case expr == string(KindString) || expr == string(KindBytes):
+		ts.Kind = Kind(expr)
+		if tag.NullTerminated {
+			ts.NullTerminated, ts.Size, ts.Variable = true, 1, true
+		} else {
+			ts.prefix(tag)
+		}
+		lengthOptions = false
+
+	case expr == string(KindTime) || expr == string(KindDuration):
+		if tag.StringLengthPrefix || tag.NullTerminated {
+			return ts, fmt.Errorf("length options are not supported on type %s", expr)
+		} else if tag.TimeZone && expr != string(KindTime) {
+			return ts, fmt.Errorf("tz is not supported on type %s", expr)
+		}
+		ts.Kind, ts.Size, ts.TimeZone = Kind(expr), 8, tag.TimeZone
+		ts.Unit = unitName(time.Nanosecond)
+		if tag.Unit != 0 {
+			ts.Unit = unitName(tag.Unit)
+		}
+		if tag.Varint {
+			ts.Varint, ts.Variable, ts.Size = true, true, 1
+		}
+		if ts.TimeZone {
+			ts.Size += min(ts.Size, 4)
+		}
+		lengthOptions, timeOptions = false, false
+
+	case fixedIDLSizes[Kind(expr)] != 0:
+		ts.Kind, ts.Size = Kind(expr), fixedIDLSizes[Kind(expr)]
+		if tag.Varint && ts.Kind != KindBool && ts.Kind != KindFloat32 && ts.Kind != KindFloat64 {
+			ts.Varint, ts.Variable, ts.Size = true, true, 1
+			lengthOptions = false
+		}
+
+	default:
+		var st *StructSchema
+		if st, err = p.resolveStruct(expr, stack); err == nil && st == nil {
+			err = fmt.Errorf("unknown type %s", expr)
+		} else if err == nil {
+			ts.Kind, ts.Struct, ts.Size, ts.Variable = KindStruct, expr, st.Size, st.Variable
+		}
+	}
+
+	if err == nil && lengthOptions {
+		err = fmt.Errorf("length options are not supported on type %s", expr)
+	} else if err == nil && timeOptions {
+		err = fmt.Errorf("time options are not supported on type %s", expr)
+	}
+	return ts, err
+}
+
+// resolveElem resolves the type of elements, keys, and values which use the
+// default options.
+func (p *idlParser) resolveElem(expr string, stack []string) (*TypeSchema, error) {
+	if expr == "" {
+		return nil, errors.New("missing element type")
+	}
+	elem, err := p.resolveType(expr, tags.Tag{}, stack)
+	if err != nil {
+		return nil, err
+	}
+	return &elem, nil
+}
+
+// prefix sets the length or count prefix from the tag, defaulting to 64-bit.
+func (ts *TypeSchema) prefix(tag tags.Tag) {
+	ts.Variable = true
+	if tag.Varint {
+		ts.Varint, ts.Size = true, 1
+		return
+	}
+
+	ts.Prefix = 64
+	if tag.StringLengthPrefix {
+		ts.Prefix = tag.StringLengthSize
+	}
+	ts.Size = uint(ts.Prefix / 8)
+}
@@ -0,0 +1,115 @@
+package bytocol
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaIDL(t *testing.T) {
+	reg := NewRegistry()
+	expected := "message ErrorMessage 0 \"error\" {\n\t0 Message string length-prefix=16\n}\n"
+	if idl := reg.Schema().IDL(); idl != expected {
+		t.Errorf("expected IDL:\n%s\ngot:\n%s", expected, idl)
+	}
+
+	plan, err := PlanType[testSchemaMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := plan.Schema()
+
+	idl := schema.IDL()
+	for _, line := range []string{
+		"message testSchemaMessage 17 \"schema\" endian=little {",
+		"\t5 Tags map[string]int16 varint",
+		"\t6 Grid [2][3]uint8",
+		"\t8 Color codec(3)",
+		"\t9 Addr codec length-prefix=8",
+		"\t10 Seen time unit=ms,tz",
+		"\t11 Wait *duration varint,unit=s",
+		"\t12 Big uint64 endian=big",
+		"struct testSchemaPosition {",
+		"\t1 Y float32 endian=big",
+	} {
+		if !strings.Contains(idl, line+"\n") {
+			t.Errorf("expected IDL line %q, got:\n%s", line, idl)
+		}
+	}
+
+	parsed, err := ParseIDL(idl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(schema, parsed) {
+		t.Errorf("expected parsed IDL to match the schema:\n%s", idl)
+	}
+}
+
+func TestParseIDL(t *testing.T) {
+	schema, err := ParseIDL(`
+// Structs may be declared after being used
+message Move 2 endian=little {
+	1 Path   []Point  length-prefix=16
+	0 Entity uint32   varint
+	2 Note   *string  null-terminated
+}
+
+struct Point {
+	0 X int16
+	1 Y int16, endian=big
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	move, ok := schema.Message(2)
+	if !ok {
+		t.Fatal("expected message 2")
+	}
+	if move.Size != 4 || !move.Variable || move.ByteOrder != "little" {
+		t.Errorf("expected variable little endian size 4, got %+v", move.StructSchema)
+	}
+	if move.Fields[0].Name != "Entity" || move.Fields[1].Name != "Path" {
+		t.Errorf("expected fields sorted by order, got %+v", move.Fields)
+	}
+	if note := move.Fields[2].Type; note.Kind != KindOptional || !note.Elem.NullTerminated {
+		t.Errorf("expected optional null-terminated string, got %+v", note)
+	}
+
+	point, ok := schema.Struct("Point")
+	if !ok || point.Size != 4 || point.Fields[1].Type.ByteOrder != "big" {
+		t.Errorf("expected 4 byte point, got %+v", point)
+	}
+}
+
+func TestParseIDLErrors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{"missing brace", "message A 1 {\n\t0 X uint8\n", "line 1: missing }"},
+		{"unknown block", "enum A {\n}\n", "expected message or struct"},
+		{"invalid indicator", "message A 300 {\n\t0 X uint8\n}\n", "invalid type indicator"},
+		{"unknown type", "message A 1 {\n\t0 X Missing\n}\n", "line 2: field X: unknown type Missing"},
+		{"duplicate order", "message A 1 {\n\t0 X uint8\n\t0 Y uint8\n}\n", "duplicate field order 0"},
+		{"length options", "message A 1 {\n\t0 X float32 varint\n}\n", "length options are not supported"},
+		{"time options", "message A 1 {\n\t0 X uint32 unit=s\n}\n", "time options are not supported"},
+		{"invalid tag", "message A 1 {\n\t0 X string length-prefix=12\n}\n", "line 2: field X"},
+		{"no fields", "message A 1 {\n}\n", "A has no fields"},
+		{"recursive", "message A 1 {\n\t0 X B\n}\nstruct B {\n\t0 Y *B\n}\n", "line 5: field Y: recursive struct B"},
+		{"nested error", "message A 1 {\n\t0 X B\n}\nstruct B {\n\t0 Y [2]nope\n}\n", "line 5: field Y: unknown type nope"},
+	} {
+		if _, err := ParseIDL(test.src); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", test.name, test.expected, err)
+		}
+	}
+
+	_, err := ParseIDL("message A 1 {\n\t0 X uint8\n}\nmessage B 1 {\n\t0 X uint8\n}\n")
+	if !errors.Is(err, ErrDuplicateTypeIndicator) {
+		t.Errorf("expected duplicate type indicator, got %v", err)
+	}
+}
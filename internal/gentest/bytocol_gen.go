@@ -0,0 +1,2063 @@
+// Code generated by bytocol-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maple-tech/bytocol"
+)
+
+// MarshalBytocol appends the encoded Blobs to the buffer, see [bytocol.Marshaler].
+func (m Blobs) MarshalBytocol(b []byte) ([]byte, error) {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	var err error
+	b = append(b, info.TypeIndicator)
+
+	// Default
+	if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Default))); err != nil {
+		return b, bytocolFieldError("Default", err)
+	}
+	b = append(b, m.Default...)
+
+	// Short
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Short))); err != nil {
+		return b, bytocolFieldError("Short", err)
+	}
+	b = append(b, m.Short...)
+
+	// Medium
+	if b, err = bytocolAppendLength(b, order, 16, uint64(len(m.Medium))); err != nil {
+		return b, bytocolFieldError("Medium", err)
+	}
+	b = append(b, m.Medium...)
+
+	// Long
+	if b, err = bytocolAppendLength(b, order, 32, uint64(len(m.Long))); err != nil {
+		return b, bytocolFieldError("Long", err)
+	}
+	b = append(b, m.Long...)
+
+	// Terminated
+	if strings.IndexByte(m.Terminated, 0) != -1 {
+		return b, bytocolFieldError("Terminated", bytocol.ErrNullInContent)
+	}
+	b = append(b, m.Terminated...)
+	b = append(b, 0)
+
+	// Varint
+	b = binary.AppendUvarint(b, uint64(len(m.Varint)))
+	b = append(b, m.Varint...)
+
+	// Bytes
+	if b, err = bytocolAppendLength(b, order, 16, uint64(len(m.Bytes))); err != nil {
+		return b, bytocolFieldError("Bytes", err)
+	}
+	b = append(b, m.Bytes...)
+
+	// Raw
+	b = binary.AppendUvarint(b, uint64(len(m.Raw)))
+	b = append(b, m.Raw...)
+
+	// RawEnd
+	if bytes.IndexByte(m.RawEnd, 0) != -1 {
+		return b, bytocolFieldError("RawEnd", bytocol.ErrNullInContent)
+	}
+	b = append(b, m.RawEnd...)
+	b = append(b, 0)
+	return b, nil
+}
+
+// SizeBytocol returns the encoded size of Blobs, see [bytocol.Marshaler].
+func (m Blobs) SizeBytocol() int {
+	n := 1
+	n += 8 + len(m.Default)
+	n += 1 + len(m.Short)
+	n += 2 + len(m.Medium)
+	n += 4 + len(m.Long)
+	n += len(m.Terminated) + 1
+	n += bytocolUvarintSize(uint64(len(m.Varint))) + len(m.Varint)
+	n += 2 + len(m.Bytes)
+	n += bytocolUvarintSize(uint64(len(m.Raw))) + len(m.Raw)
+	n += len(m.RawEnd) + 1
+	return n
+}
+
+// UnmarshalBytocol decodes Blobs without its type indicator, see [bytocol.Unmarshaler].
+func (m *Blobs) UnmarshalBytocol(r io.Reader) error {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}
+	var start int64
+
+	// Default
+	start = d.n
+	if data1 := d.bytes(d.length(order, 64), 0); d.err == nil {
+		m.Default = string(data1)
+	}
+	if d.err != nil {
+		return d.error("Default", 0, start)
+	}
+
+	// Short
+	start = d.n
+	if data2 := d.bytes(d.length(order, 8), 0); d.err == nil {
+		m.Short = string(data2)
+	}
+	if d.err != nil {
+		return d.error("Short", 1, start)
+	}
+
+	// Medium
+	start = d.n
+	if data3 := d.bytes(d.length(order, 16), 0); d.err == nil {
+		m.Medium = string(data3)
+	}
+	if d.err != nil {
+		return d.error("Medium", 2, start)
+	}
+
+	// Long
+	start = d.n
+	if data4 := d.bytes(d.length(order, 32), 0); d.err == nil {
+		m.Long = string(data4)
+	}
+	if d.err != nil {
+		return d.error("Long", 3, start)
+	}
+
+	// Terminated
+	start = d.n
+	if data5 := d.terminated(0); d.err == nil {
+		m.Terminated = string(data5)
+	}
+	if d.err != nil {
+		return d.error("Terminated", 4, start)
+	}
+
+	// Varint
+	start = d.n
+	if data6 := d.bytes(d.uvarint(), 0); d.err == nil {
+		m.Varint = string(data6)
+	}
+	if d.err != nil {
+		return d.error("Varint", 5, start)
+	}
+
+	// Bytes
+	start = d.n
+	if data7 := d.bytes(d.length(order, 16), 0); d.err == nil {
+		m.Bytes = []byte(data7)
+	}
+	if d.err != nil {
+		return d.error("Bytes", 6, start)
+	}
+
+	// Raw
+	start = d.n
+	if data8 := d.bytes(d.uvarint(), 0); d.err == nil {
+		m.Raw = Raw(data8)
+	}
+	if d.err != nil {
+		return d.error("Raw", 7, start)
+	}
+
+	// RawEnd
+	start = d.n
+	if data9 := d.terminated(0); d.err == nil {
+		m.RawEnd = []byte(data9)
+	}
+	if d.err != nil {
+		return d.error("RawEnd", 8, start)
+	}
+	return nil
+}
+
+// MarshalBytocol appends the encoded Codecs to the buffer, see [bytocol.Marshaler].
+func (m Codecs) MarshalBytocol(b []byte) ([]byte, error) {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	var err error
+	b = append(b, info.TypeIndicator)
+
+	// Version
+	var data1 []byte
+	if data1, err = m.Version.MarshalBytocolField(); err != nil {
+		return b, bytocolFieldError("Version", err)
+	}
+	if size := new(Version).BytocolFieldSize(); len(data1) != size {
+		return b, bytocolFieldError("Version", fmt.Errorf("bytocol: codec for Version encoded %d bytes, expected %d", len(data1), size))
+	}
+	b = append(b, data1...)
+
+	// Words
+	var data2 []byte
+	if data2, err = m.Words.MarshalBytocolField(); err != nil {
+		return b, bytocolFieldError("Words", err)
+	}
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(data2))); err != nil {
+		return b, bytocolFieldError("Words", err)
+	}
+	b = append(b, data2...)
+
+	// Addr
+	var data3 []byte
+	if data3, err = m.Addr.MarshalBinary(); err != nil {
+		return b, bytocolFieldError("Addr", err)
+	}
+	b = binary.AppendUvarint(b, uint64(len(data3)))
+	b = append(b, data3...)
+
+	// Versions
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Versions))); err != nil {
+		return b, bytocolFieldError("Versions", err)
+	}
+	for i4 := range m.Versions {
+		var data5 []byte
+		if data5, err = m.Versions[i4].MarshalBytocolField(); err != nil {
+			return b, bytocolFieldError("Versions", err)
+		}
+		if size := new(Version).BytocolFieldSize(); len(data5) != size {
+			return b, bytocolFieldError("Versions", fmt.Errorf("bytocol: codec for Version encoded %d bytes, expected %d", len(data5), size))
+		}
+		b = append(b, data5...)
+	}
+
+	// Peers
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Peers))); err != nil {
+		return b, bytocolFieldError("Peers", err)
+	}
+	type pair6 struct {
+		k string
+		v netip.Addr
+	}
+	pairs7 := make([]pair6, 0, len(m.Peers))
+	for k8, x9 := range m.Peers {
+		pairs7 = append(pairs7, pair6{k8, x9})
+	}
+	slices.SortFunc(pairs7, func(x, y pair6) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	for _, p10 := range pairs7 {
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(p10.k))); err != nil {
+			return b, bytocolFieldError("Peers", err)
+		}
+		b = append(b, p10.k...)
+		var data11 []byte
+		if data11, err = p10.v.MarshalBinary(); err != nil {
+			return b, bytocolFieldError("Peers", err)
+		}
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(data11))); err != nil {
+			return b, bytocolFieldError("Peers", err)
+		}
+		b = append(b, data11...)
+	}
+
+	// Aliases
+	if m.Aliases == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		var data12 []byte
+		if data12, err = (*m.Aliases).MarshalBytocolField(); err != nil {
+			return b, bytocolFieldError("Aliases", err)
+		}
+		if b, err = bytocolAppendLength(b, order, 16, uint64(len(data12))); err != nil {
+			return b, bytocolFieldError("Aliases", err)
+		}
+		b = append(b, data12...)
+	}
+	return b, nil
+}
+
+// SizeBytocol returns the encoded size of Codecs, see [bytocol.Marshaler].
+func (m Codecs) SizeBytocol() int {
+	n := 1
+	n += new(Version).BytocolFieldSize()
+	if data1, err := m.Words.MarshalBytocolField(); err == nil {
+		n += 1 + len(data1)
+	}
+	if data2, err := m.Addr.MarshalBinary(); err == nil {
+		n += bytocolUvarintSize(uint64(len(data2))) + len(data2)
+	}
+	n += 1
+	n += len(m.Versions) * new(Version).BytocolFieldSize()
+	n += 1
+	for k3, x4 := range m.Peers {
+		n += 8 + len(k3)
+		if data5, err := x4.MarshalBinary(); err == nil {
+			n += 8 + len(data5)
+		}
+	}
+	n++
+	if m.Aliases != nil {
+		if data6, err := (*m.Aliases).MarshalBytocolField(); err == nil {
+			n += 2 + len(data6)
+		}
+	}
+	return n
+}
+
+// UnmarshalBytocol decodes Codecs without its type indicator, see [bytocol.Unmarshaler].
+func (m *Codecs) UnmarshalBytocol(r io.Reader) error {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}
+	var start int64
+
+	// Version
+	start = d.n
+	if data1 := d.fixed(new(Version).BytocolFieldSize()); d.err == nil {
+		var x2 Version
+		if err := x2.UnmarshalBytocolField(data1); err != nil {
+			d.fail(err)
+		} else {
+			m.Version = x2
+		}
+	}
+	if d.err != nil {
+		return d.error("Version", 0, start)
+	}
+
+	// Words
+	start = d.n
+	if data3 := d.bytes(d.length(order, 8), 0); d.err == nil {
+		var x4 Words
+		if err := x4.UnmarshalBytocolField(data3); err != nil {
+			d.fail(err)
+		} else {
+			m.Words = x4
+		}
+	}
+	if d.err != nil {
+		return d.error("Words", 1, start)
+	}
+
+	// Addr
+	start = d.n
+	if data5 := d.bytes(d.uvarint(), 0); d.err == nil {
+		var x6 netip.Addr
+		if err := x6.UnmarshalBinary(data5); err != nil {
+			d.fail(err)
+		} else {
+			m.Addr = x6
+		}
+	}
+	if d.err != nil {
+		return d.error("Addr", 2, start)
+	}
+
+	// Versions
+	start = d.n
+	d.enter()
+	if count7 := d.count(d.length(order, 8), 0); d.err == nil {
+		s8 := make([]Version, 0, min(count7, 32768))
+		for i9 := 0; i9 < count7 && d.err == nil; i9++ {
+			s8 = append(s8, *new(Version))
+			if data10 := d.fixed(new(Version).BytocolFieldSize()); d.err == nil {
+				var x11 Version
+				if err := x11.UnmarshalBytocolField(data10); err != nil {
+					d.fail(err)
+				} else {
+					s8[i9] = x11
+				}
+			}
+		}
+		if d.err == nil {
+			m.Versions = s8
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Versions", 3, start)
+	}
+
+	// Peers
+	start = d.n
+	d.enter()
+	if count12 := d.count(d.length(order, 8), 0); d.err == nil {
+		m13 := make(map[string]netip.Addr, min(count12, 2730))
+		for i14 := 0; i14 < count12 && d.err == nil; i14++ {
+			var k15 string
+			if data17 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				k15 = string(data17)
+			}
+			var x16 netip.Addr
+			if data18 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				var x19 netip.Addr
+				if err := x19.UnmarshalBinary(data18); err != nil {
+					d.fail(err)
+				} else {
+					x16 = x19
+				}
+			}
+			m13[k15] = x16
+		}
+		if d.err == nil {
+			m.Peers = m13
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Peers", 4, start)
+	}
+
+	// Aliases
+	start = d.n
+	d.enter()
+	switch present20 := d.read(1)[0]; {
+	case d.err != nil:
+	case present20 == 0:
+		m.Aliases = nil
+	case present20 == 1:
+		x21 := new(Words)
+		if data22 := d.bytes(d.length(order, 16), 0); d.err == nil {
+			var x23 Words
+			if err := x23.UnmarshalBytocolField(data22); err != nil {
+				d.fail(err)
+			} else {
+				(*x21) = x23
+			}
+		}
+		if d.err == nil {
+			m.Aliases = x21
+		}
+	default:
+		d.fail(fmt.Errorf("%w: %d", bytocol.ErrInvalidPresence, present20))
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Aliases", 5, start)
+	}
+	return nil
+}
+
+// MarshalBytocol appends the encoded Composite to the buffer, see [bytocol.Marshaler].
+func (m Composite) MarshalBytocol(b []byte) ([]byte, error) {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	var err error
+	b = append(b, info.TypeIndicator)
+
+	// header
+	b = bytocolAppend32(b, order, uint32(m.header.Sequence))
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.header.Source))); err != nil {
+		return b, bytocolFieldError("header", err)
+	}
+	b = append(b, m.header.Source...)
+
+	// Position
+	b = bytocolAppend32(b, order, math.Float32bits(float32(m.Position.X)))
+	b = bytocolAppend32(b, order, math.Float32bits(float32(m.Position.Y)))
+	b = bytocolAppend64(b, order, math.Float64bits(float64(m.Position.Z)))
+
+	// Path
+	if b, err = bytocolAppendLength(b, order, 16, uint64(len(m.Path))); err != nil {
+		return b, bytocolFieldError("Path", err)
+	}
+	for i1 := range m.Path {
+		b = bytocolAppend32(b, order, math.Float32bits(float32(m.Path[i1].X)))
+		b = bytocolAppend32(b, order, math.Float32bits(float32(m.Path[i1].Y)))
+		b = bytocolAppend64(b, order, math.Float64bits(float64(m.Path[i1].Z)))
+	}
+
+	// Tags
+	b = binary.AppendUvarint(b, uint64(len(m.Tags)))
+	for i2 := range m.Tags {
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Tags[i2]))); err != nil {
+			return b, bytocolFieldError("Tags", err)
+		}
+		b = append(b, m.Tags[i2]...)
+	}
+
+	// Matrix
+	for i3 := range m.Matrix {
+		for i4 := range m.Matrix[i3] {
+			b = bytocolAppend16(b, order, uint16(m.Matrix[i3][i4]))
+		}
+	}
+
+	// Scores
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Scores))); err != nil {
+		return b, bytocolFieldError("Scores", err)
+	}
+	type pair5 struct {
+		k string
+		v uint32
+	}
+	pairs6 := make([]pair5, 0, len(m.Scores))
+	for k7, x8 := range m.Scores {
+		pairs6 = append(pairs6, pair5{k7, x8})
+	}
+	slices.SortFunc(pairs6, func(x, y pair5) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	for _, p9 := range pairs6 {
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(p9.k))); err != nil {
+			return b, bytocolFieldError("Scores", err)
+		}
+		b = append(b, p9.k...)
+		b = bytocolAppend32(b, order, uint32(p9.v))
+	}
+
+	// Flags
+	if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Flags))); err != nil {
+		return b, bytocolFieldError("Flags", err)
+	}
+	type pair10 struct {
+		k bool
+		v string
+	}
+	pairs11 := make([]pair10, 0, len(m.Flags))
+	for k12, x13 := range m.Flags {
+		pairs11 = append(pairs11, pair10{k12, x13})
+	}
+	slices.SortFunc(pairs11, func(x, y pair10) int {
+		return cmp.Compare(bytocolBool(bool(x.k)), bytocolBool(bool(y.k)))
+	})
+	for _, p14 := range pairs11 {
+		if p14.k {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(p14.v))); err != nil {
+			return b, bytocolFieldError("Flags", err)
+		}
+		b = append(b, p14.v...)
+	}
+
+	// Lookup
+	b = binary.AppendUvarint(b, uint64(len(m.Lookup)))
+	type pair15 struct {
+		k int8
+		v []Level
+	}
+	pairs16 := make([]pair15, 0, len(m.Lookup))
+	for k17, x18 := range m.Lookup {
+		pairs16 = append(pairs16, pair15{k17, x18})
+	}
+	slices.SortFunc(pairs16, func(x, y pair15) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	for _, p19 := range pairs16 {
+		b = append(b, byte(p19.k))
+		data20 := make([]byte, len(p19.v))
+		for i21, c22 := range p19.v {
+			data20[i21] = byte(c22)
+		}
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(data20))); err != nil {
+			return b, bytocolFieldError("Lookup", err)
+		}
+		b = append(b, data20...)
+	}
+
+	// Optional
+	if m.Optional == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		b = bytocolAppend32(b, order, uint32((*m.Optional)))
+	}
+
+	// Note
+	if m.Note == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		if b, err = bytocolAppendLength(b, order, 8, uint64(len((*m.Note)))); err != nil {
+			return b, bytocolFieldError("Note", err)
+		}
+		b = append(b, (*m.Note)...)
+	}
+
+	// Origin
+	if m.Origin == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		b = bytocolAppend32(b, order, math.Float32bits(float32((*m.Origin).X)))
+		b = bytocolAppend32(b, order, math.Float32bits(float32((*m.Origin).Y)))
+		b = bytocolAppend64(b, order, math.Float64bits(float64((*m.Origin).Z)))
+	}
+
+	// Waypoints
+	if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Waypoints))); err != nil {
+		return b, bytocolFieldError("Waypoints", err)
+	}
+	type pair23 struct {
+		k uint16
+		v *string
+	}
+	pairs24 := make([]pair23, 0, len(m.Waypoints))
+	for k25, x26 := range m.Waypoints {
+		pairs24 = append(pairs24, pair23{k25, x26})
+	}
+	slices.SortFunc(pairs24, func(x, y pair23) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	for _, p27 := range pairs24 {
+		b = bytocolAppend16(b, order, uint16(p27.k))
+		if p27.v == nil {
+			b = append(b, 0)
+		} else {
+			b = append(b, 1)
+			if b, err = bytocolAppendLength(b, order, 64, uint64(len((*p27.v)))); err != nil {
+				return b, bytocolFieldError("Waypoints", err)
+			}
+			b = append(b, (*p27.v)...)
+		}
+	}
+
+	// Weights
+	if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Weights))); err != nil {
+		return b, bytocolFieldError("Weights", err)
+	}
+	type pair28 struct {
+		k float64
+		v uint8
+	}
+	pairs29 := make([]pair28, 0, len(m.Weights))
+	for k30, x31 := range m.Weights {
+		pairs29 = append(pairs29, pair28{k30, x31})
+	}
+	slices.SortFunc(pairs29, func(x, y pair28) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	start33 := len(b)
+	var ends34 []int
+	for _, p32 := range pairs29 {
+		b = bytocolAppend64(b, order, math.Float64bits(float64(p32.k)))
+		b = append(b, byte(p32.v))
+		if p32.k != p32.k {
+			ends34 = append(ends34, len(b))
+		}
+	}
+	bytocolSortPairs(b, start33, ends34)
+	return b, nil
+}
+
+// SizeBytocol returns the encoded size of Composite, see [bytocol.Marshaler].
+func (m Composite) SizeBytocol() int {
+	n := 1
+	n += 4
+	n += 1 + len(m.header.Source)
+	n += 16
+	n += 2
+	n += len(m.Path) * 16
+	n += bytocolUvarintSize(uint64(len(m.Tags)))
+	for i1 := range m.Tags {
+		n += 8 + len(m.Tags[i1])
+	}
+	n += 12
+	n += 1
+	for k2 := range m.Scores {
+		n += 8 + len(k2)
+		n += 4
+	}
+	n += 8
+	for _, x3 := range m.Flags {
+		n += 1
+		n += 8 + len(x3)
+	}
+	n += bytocolUvarintSize(uint64(len(m.Lookup)))
+	for _, x4 := range m.Lookup {
+		n += 1
+		n += 8 + len(x4)
+	}
+	n++
+	if m.Optional != nil {
+		n += 4
+	}
+	n++
+	if m.Note != nil {
+		n += 1 + len((*m.Note))
+	}
+	n++
+	if m.Origin != nil {
+		n += 16
+	}
+	n += 8
+	for _, x5 := range m.Waypoints {
+		n += 2
+		n++
+		if x5 != nil {
+			n += 8 + len((*x5))
+		}
+	}
+	n += 8
+	n += len(m.Weights) * (8 + 1)
+	return n
+}
+
+// UnmarshalBytocol decodes Composite without its type indicator, see [bytocol.Unmarshaler].
+func (m *Composite) UnmarshalBytocol(r io.Reader) error {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}
+	var start int64
+
+	// header
+	start = d.n
+	d.enter()
+	m.header.Sequence = order.Uint32(d.read(4))
+	if data1 := d.bytes(d.length(order, 8), 0); d.err == nil {
+		m.header.Source = string(data1)
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("header", 0, start)
+	}
+
+	// Position
+	start = d.n
+	d.enter()
+	m.Position.X = math.Float32frombits(order.Uint32(d.read(4)))
+	m.Position.Y = math.Float32frombits(order.Uint32(d.read(4)))
+	m.Position.Z = math.Float64frombits(order.Uint64(d.read(8)))
+	d.leave()
+	if d.err != nil {
+		return d.error("Position", 1, start)
+	}
+
+	// Path
+	start = d.n
+	d.enter()
+	if count2 := d.count(d.length(order, 16), 0); d.err == nil {
+		s3 := make([]Position, 0, min(count2, 4096))
+		for i4 := 0; i4 < count2 && d.err == nil; i4++ {
+			s3 = append(s3, *new(Position))
+			d.enter()
+			s3[i4].X = math.Float32frombits(order.Uint32(d.read(4)))
+			s3[i4].Y = math.Float32frombits(order.Uint32(d.read(4)))
+			s3[i4].Z = math.Float64frombits(order.Uint64(d.read(8)))
+			d.leave()
+		}
+		if d.err == nil {
+			m.Path = s3
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Path", 2, start)
+	}
+
+	// Tags
+	start = d.n
+	d.enter()
+	if count5 := d.count(d.uvarint(), 0); d.err == nil {
+		s6 := make([]string, 0, min(count5, 4096))
+		for i7 := 0; i7 < count5 && d.err == nil; i7++ {
+			s6 = append(s6, *new(string))
+			if data8 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				s6[i7] = string(data8)
+			}
+		}
+		if d.err == nil {
+			m.Tags = s6
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Tags", 3, start)
+	}
+
+	// Matrix
+	start = d.n
+	d.enter()
+	for i9 := 0; i9 < len(m.Matrix) && d.err == nil; i9++ {
+		d.enter()
+		for i10 := 0; i10 < len(m.Matrix[i9]) && d.err == nil; i10++ {
+			m.Matrix[i9][i10] = int16(order.Uint16(d.read(2)))
+		}
+		d.leave()
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Matrix", 4, start)
+	}
+
+	// Scores
+	start = d.n
+	d.enter()
+	if count11 := d.count(d.length(order, 8), 0); d.err == nil {
+		m12 := make(map[string]uint32, min(count11, 16384))
+		for i13 := 0; i13 < count11 && d.err == nil; i13++ {
+			var k14 string
+			if data16 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				k14 = string(data16)
+			}
+			var x15 uint32
+			x15 = order.Uint32(d.read(4))
+			m12[k14] = x15
+		}
+		if d.err == nil {
+			m.Scores = m12
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Scores", 5, start)
+	}
+
+	// Flags
+	start = d.n
+	d.enter()
+	if count17 := d.count(d.length(order, 64), 0); d.err == nil {
+		m18 := make(map[bool]string, min(count17, 4096))
+		for i19 := 0; i19 < count17 && d.err == nil; i19++ {
+			var k20 bool
+			k20 = d.read(1)[0] == 1
+			var x21 string
+			if data22 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				x21 = string(data22)
+			}
+			m18[k20] = x21
+		}
+		if d.err == nil {
+			m.Flags = m18
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Flags", 6, start)
+	}
+
+	// Lookup
+	start = d.n
+	d.enter()
+	if count23 := d.count(d.uvarint(), 0); d.err == nil {
+		m24 := make(map[int8][]Level, min(count23, 2730))
+		for i25 := 0; i25 < count23 && d.err == nil; i25++ {
+			var k26 int8
+			k26 = int8(d.read(1)[0])
+			var x27 []Level
+			if data28 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				s29 := make([]Level, len(data28))
+				for i30, c31 := range data28 {
+					s29[i30] = Level(c31)
+				}
+				x27 = s29
+			}
+			m24[k26] = x27
+		}
+		if d.err == nil {
+			m.Lookup = m24
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Lookup", 7, start)
+	}
+
+	// Optional
+	start = d.n
+	d.enter()
+	switch present32 := d.read(1)[0]; {
+	case d.err != nil:
+	case present32 == 0:
+		m.Optional = nil
+	case present32 == 1:
+		x33 := new(uint32)
+		(*x33) = order.Uint32(d.read(4))
+		if d.err == nil {
+			m.Optional = x33
+		}
+	default:
+		d.fail(fmt.Errorf("%w: %d", bytocol.ErrInvalidPresence, present32))
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Optional", 8, start)
+	}
+
+	// Note
+	start = d.n
+	d.enter()
+	switch present34 := d.read(1)[0]; {
+	case d.err != nil:
+	case present34 == 0:
+		m.Note = nil
+	case present34 == 1:
+		x35 := new(string)
+		if data36 := d.bytes(d.length(order, 8), 0); d.err == nil {
+			(*x35) = string(data36)
+		}
+		if d.err == nil {
+			m.Note = x35
+		}
+	default:
+		d.fail(fmt.Errorf("%w: %d", bytocol.ErrInvalidPresence, present34))
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Note", 9, start)
+	}
+
+	// Origin
+	start = d.n
+	d.enter()
+	switch present37 := d.read(1)[0]; {
+	case d.err != nil:
+	case present37 == 0:
+		m.Origin = nil
+	case present37 == 1:
+		x38 := new(Position)
+		d.enter()
+		(*x38).X = math.Float32frombits(order.Uint32(d.read(4)))
+		(*x38).Y = math.Float32frombits(order.Uint32(d.read(4)))
+		(*x38).Z = math.Float64frombits(order.Uint64(d.read(8)))
+		d.leave()
+		if d.err == nil {
+			m.Origin = x38
+		}
+	default:
+		d.fail(fmt.Errorf("%w: %d", bytocol.ErrInvalidPresence, present37))
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Origin", 10, start)
+	}
+
+	// Waypoints
+	start = d.n
+	d.enter()
+	if count39 := d.count(d.length(order, 64), 0); d.err == nil {
+		m40 := make(map[uint16]*string, min(count39, 8192))
+		for i41 := 0; i41 < count39 && d.err == nil; i41++ {
+			var k42 uint16
+			k42 = order.Uint16(d.read(2))
+			var x43 *string
+			d.enter()
+			switch present44 := d.read(1)[0]; {
+			case d.err != nil:
+			case present44 == 0:
+				x43 = nil
+			case present44 == 1:
+				x45 := new(string)
+				if data46 := d.bytes(d.length(order, 64), 0); d.err == nil {
+					(*x45) = string(data46)
+				}
+				if d.err == nil {
+					x43 = x45
+				}
+			default:
+				d.fail(fmt.Errorf("%w: %d", bytocol.ErrInvalidPresence, present44))
+			}
+			d.leave()
+			m40[k42] = x43
+		}
+		if d.err == nil {
+			m.Waypoints = m40
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Waypoints", 11, start)
+	}
+
+	// Weights
+	start = d.n
+	d.enter()
+	if count47 := d.count(d.length(order, 64), 0); d.err == nil {
+		m48 := make(map[float64]uint8, min(count47, 65536))
+		for i49 := 0; i49 < count47 && d.err == nil; i49++ {
+			var k50 float64
+			k50 = math.Float64frombits(order.Uint64(d.read(8)))
+			var x51 uint8
+			x51 = d.read(1)[0]
+			m48[k50] = x51
+		}
+		if d.err == nil {
+			m.Weights = m48
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Weights", 12, start)
+	}
+	return nil
+}
+
+// MarshalBytocol appends the encoded Limited to the buffer, see [bytocol.Marshaler].
+func (m Limited) MarshalBytocol(b []byte) ([]byte, error) {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	var err error
+	b = append(b, info.TypeIndicator)
+
+	// Name
+	if b, err = bytocolAppendLength(b, order, 16, uint64(len(m.Name))); err != nil {
+		return b, bytocolFieldError("Name", err)
+	}
+	b = append(b, m.Name...)
+
+	// Label
+	if strings.IndexByte(m.Label, 0) != -1 {
+		return b, bytocolFieldError("Label", bytocol.ErrNullInContent)
+	}
+	b = append(b, m.Label...)
+	b = append(b, 0)
+
+	// Items
+	b = binary.AppendUvarint(b, uint64(len(m.Items)))
+	for i1 := range m.Items {
+		b = bytocolAppend16(b, order, uint16(m.Items[i1]))
+	}
+
+	// Counts
+	if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Counts))); err != nil {
+		return b, bytocolFieldError("Counts", err)
+	}
+	type pair2 struct {
+		k string
+		v uint8
+	}
+	pairs3 := make([]pair2, 0, len(m.Counts))
+	for k4, x5 := range m.Counts {
+		pairs3 = append(pairs3, pair2{k4, x5})
+	}
+	slices.SortFunc(pairs3, func(x, y pair2) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	for _, p6 := range pairs3 {
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(p6.k))); err != nil {
+			return b, bytocolFieldError("Counts", err)
+		}
+		b = append(b, p6.k...)
+		b = append(b, byte(p6.v))
+	}
+
+	// Path
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Path))); err != nil {
+		return b, bytocolFieldError("Path", err)
+	}
+	for i7 := range m.Path {
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(m.Path[i7]))); err != nil {
+			return b, bytocolFieldError("Path", err)
+		}
+		for i8 := range m.Path[i7] {
+			b = bytocolAppend32(b, order, math.Float32bits(float32(m.Path[i7][i8].X)))
+			b = bytocolAppend32(b, order, math.Float32bits(float32(m.Path[i7][i8].Y)))
+			b = bytocolAppend64(b, order, math.Float64bits(float64(m.Path[i7][i8].Z)))
+		}
+	}
+
+	// Data
+	if b, err = bytocolAppendLength(b, order, 16, uint64(len(m.Data))); err != nil {
+		return b, bytocolFieldError("Data", err)
+	}
+	b = append(b, m.Data...)
+
+	// Empty
+	if len(m.Empty) > 0 {
+		return b, bytocolFieldError("Empty", errors.New("bytocol: slice of zero size elements [][0]uint32 must be empty"))
+	}
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Empty))); err != nil {
+		return b, bytocolFieldError("Empty", err)
+	}
+	for i9 := range m.Empty {
+		for i10 := range m.Empty[i9] {
+			b = bytocolAppend32(b, order, uint32(m.Empty[i9][i10]))
+		}
+	}
+	return b, nil
+}
+
+// SizeBytocol returns the encoded size of Limited, see [bytocol.Marshaler].
+func (m Limited) SizeBytocol() int {
+	n := 1
+	n += 2 + len(m.Name)
+	n += len(m.Label) + 1
+	n += bytocolUvarintSize(uint64(len(m.Items)))
+	n += len(m.Items) * 2
+	n += 8
+	for k1 := range m.Counts {
+		n += 8 + len(k1)
+		n += 1
+	}
+	n += 1
+	for i2 := range m.Path {
+		n += 8
+		n += len(m.Path[i2]) * 16
+	}
+	n += 2 + len(m.Data)
+	n += 1
+	n += len(m.Empty) * 0
+	return n
+}
+
+// UnmarshalBytocol decodes Limited without its type indicator, see [bytocol.Unmarshaler].
+func (m *Limited) UnmarshalBytocol(r io.Reader) error {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}
+	var start int64
+
+	// Name
+	start = d.n
+	if data1 := d.bytes(d.length(order, 16), 8); d.err == nil {
+		m.Name = string(data1)
+	}
+	if d.err != nil {
+		return d.error("Name", 0, start)
+	}
+
+	// Label
+	start = d.n
+	if data2 := d.terminated(4); d.err == nil {
+		m.Label = string(data2)
+	}
+	if d.err != nil {
+		return d.error("Label", 1, start)
+	}
+
+	// Items
+	start = d.n
+	d.enter()
+	if count3 := d.count(d.uvarint(), 3); d.err == nil {
+		s4 := make([]uint16, 0, min(count3, 32768))
+		for i5 := 0; i5 < count3 && d.err == nil; i5++ {
+			s4 = append(s4, *new(uint16))
+			s4[i5] = order.Uint16(d.read(2))
+		}
+		if d.err == nil {
+			m.Items = s4
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Items", 2, start)
+	}
+
+	// Counts
+	start = d.n
+	d.enter()
+	if count6 := d.count(d.length(order, 64), 2); d.err == nil {
+		m7 := make(map[string]uint8, min(count6, 65536))
+		for i8 := 0; i8 < count6 && d.err == nil; i8++ {
+			var k9 string
+			if data11 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				k9 = string(data11)
+			}
+			var x10 uint8
+			x10 = d.read(1)[0]
+			m7[k9] = x10
+		}
+		if d.err == nil {
+			m.Counts = m7
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Counts", 3, start)
+	}
+
+	// Path
+	start = d.n
+	d.enter()
+	if count12 := d.count(d.length(order, 8), 0); d.err == nil {
+		s13 := make([][]Position, 0, min(count12, 2730))
+		for i14 := 0; i14 < count12 && d.err == nil; i14++ {
+			s13 = append(s13, *new([]Position))
+			d.enter()
+			if count15 := d.count(d.length(order, 64), 0); d.err == nil {
+				s16 := make([]Position, 0, min(count15, 4096))
+				for i17 := 0; i17 < count15 && d.err == nil; i17++ {
+					s16 = append(s16, *new(Position))
+					d.enter()
+					s16[i17].X = math.Float32frombits(order.Uint32(d.read(4)))
+					s16[i17].Y = math.Float32frombits(order.Uint32(d.read(4)))
+					s16[i17].Z = math.Float64frombits(order.Uint64(d.read(8)))
+					d.leave()
+				}
+				if d.err == nil {
+					s13[i14] = s16
+				}
+			}
+			d.leave()
+		}
+		if d.err == nil {
+			m.Path = s13
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Path", 4, start)
+	}
+
+	// Data
+	start = d.n
+	if data18 := d.bytes(d.length(order, 16), 0); d.err == nil {
+		m.Data = []byte(data18)
+	}
+	if d.err != nil {
+		return d.error("Data", 5, start)
+	}
+
+	// Empty
+	start = d.n
+	d.enter()
+	if count19 := d.count(d.length(order, 8), 0); count19 > 0 {
+		d.fail(fmt.Errorf("%w: count %d of zero size elements exceeds 0", bytocol.ErrLimitExceeded, count19))
+	} else if d.err == nil {
+		m.Empty = make([][0]uint32, 0)
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Empty", 6, start)
+	}
+	return nil
+}
+
+// MarshalBytocol appends the encoded Numbers to the buffer, see [bytocol.Marshaler].
+func (m Numbers) MarshalBytocol(b []byte) ([]byte, error) {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	b = append(b, info.TypeIndicator)
+
+	// Bool
+	if m.Bool {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+
+	// Uint8
+	b = append(b, byte(m.Uint8))
+
+	// Uint16
+	b = bytocolAppend16(b, order, uint16(m.Uint16))
+
+	// Uint32
+	b = bytocolAppend32(b, order, uint32(m.Uint32))
+
+	// Uint64
+	b = bytocolAppend64(b, order, uint64(m.Uint64))
+
+	// Uint
+	b = bytocolAppend64(b, order, uint64(m.Uint))
+
+	// Int8
+	b = append(b, byte(m.Int8))
+
+	// Int16
+	b = bytocolAppend16(b, order, uint16(m.Int16))
+
+	// Int32
+	b = bytocolAppend32(b, order, uint32(m.Int32))
+
+	// Int64
+	b = bytocolAppend64(b, order, uint64(m.Int64))
+
+	// Int
+	b = bytocolAppend64(b, order, uint64(m.Int))
+
+	// Float32
+	b = bytocolAppend32(b, order, math.Float32bits(float32(m.Float32)))
+
+	// Float64
+	b = bytocolAppend64(b, order, math.Float64bits(float64(m.Float64)))
+
+	// Level
+	b = append(b, byte(m.Level))
+
+	// Little
+	b = bytocolAppend32(b, binary.LittleEndian, uint32(m.Little))
+
+	// Counter
+	b = binary.AppendUvarint(b, uint64(m.Counter))
+
+	// Delta
+	b = binary.AppendVarint(b, int64(m.Delta))
+
+	// Small
+	b = binary.AppendUvarint(b, uint64(m.Small))
+	return b, nil
+}
+
+// SizeBytocol returns the encoded size of Numbers, see [bytocol.Marshaler].
+func (m Numbers) SizeBytocol() int {
+	n := 1
+	n += 1
+	n += 1
+	n += 2
+	n += 4
+	n += 8
+	n += 8
+	n += 1
+	n += 2
+	n += 4
+	n += 8
+	n += 8
+	n += 4
+	n += 8
+	n += 1
+	n += 4
+	n += bytocolUvarintSize(uint64(m.Counter))
+	n += bytocolVarintSize(int64(m.Delta))
+	n += bytocolUvarintSize(uint64(m.Small))
+	return n
+}
+
+// UnmarshalBytocol decodes Numbers without its type indicator, see [bytocol.Unmarshaler].
+func (m *Numbers) UnmarshalBytocol(r io.Reader) error {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}
+	var start int64
+
+	// Bool
+	start = d.n
+	m.Bool = d.read(1)[0] == 1
+	if d.err != nil {
+		return d.error("Bool", 0, start)
+	}
+
+	// Uint8
+	start = d.n
+	m.Uint8 = d.read(1)[0]
+	if d.err != nil {
+		return d.error("Uint8", 1, start)
+	}
+
+	// Uint16
+	start = d.n
+	m.Uint16 = order.Uint16(d.read(2))
+	if d.err != nil {
+		return d.error("Uint16", 2, start)
+	}
+
+	// Uint32
+	start = d.n
+	m.Uint32 = order.Uint32(d.read(4))
+	if d.err != nil {
+		return d.error("Uint32", 3, start)
+	}
+
+	// Uint64
+	start = d.n
+	m.Uint64 = order.Uint64(d.read(8))
+	if d.err != nil {
+		return d.error("Uint64", 4, start)
+	}
+
+	// Uint
+	start = d.n
+	m.Uint = uint(order.Uint64(d.read(8)))
+	if d.err != nil {
+		return d.error("Uint", 5, start)
+	}
+
+	// Int8
+	start = d.n
+	m.Int8 = int8(d.read(1)[0])
+	if d.err != nil {
+		return d.error("Int8", 6, start)
+	}
+
+	// Int16
+	start = d.n
+	m.Int16 = int16(order.Uint16(d.read(2)))
+	if d.err != nil {
+		return d.error("Int16", 7, start)
+	}
+
+	// Int32
+	start = d.n
+	m.Int32 = int32(order.Uint32(d.read(4)))
+	if d.err != nil {
+		return d.error("Int32", 8, start)
+	}
+
+	// Int64
+	start = d.n
+	m.Int64 = int64(order.Uint64(d.read(8)))
+	if d.err != nil {
+		return d.error("Int64", 9, start)
+	}
+
+	// Int
+	start = d.n
+	m.Int = int(int64(order.Uint64(d.read(8))))
+	if d.err != nil {
+		return d.error("Int", 10, start)
+	}
+
+	// Float32
+	start = d.n
+	m.Float32 = math.Float32frombits(order.Uint32(d.read(4)))
+	if d.err != nil {
+		return d.error("Float32", 11, start)
+	}
+
+	// Float64
+	start = d.n
+	m.Float64 = math.Float64frombits(order.Uint64(d.read(8)))
+	if d.err != nil {
+		return d.error("Float64", 12, start)
+	}
+
+	// Level
+	start = d.n
+	m.Level = Level(d.read(1)[0])
+	if d.err != nil {
+		return d.error("Level", 13, start)
+	}
+
+	// Little
+	start = d.n
+	m.Little = binary.LittleEndian.Uint32(d.read(4))
+	if d.err != nil {
+		return d.error("Little", 14, start)
+	}
+
+	// Counter
+	start = d.n
+	m.Counter = uint64(d.uvarint())
+	if d.err != nil {
+		return d.error("Counter", 15, start)
+	}
+
+	// Delta
+	start = d.n
+	if x1 := d.varint(); x1 < -1<<15 || x1 > 1<<15-1 {
+		d.fail(fmt.Errorf("%w: %d does not fit in int16", bytocol.ErrVarintOverflow, x1))
+	} else {
+		m.Delta = int16(x1)
+	}
+	if d.err != nil {
+		return d.error("Delta", 16, start)
+	}
+
+	// Small
+	start = d.n
+	if x2 := d.uvarint(); x2 > 1<<8-1 {
+		d.fail(fmt.Errorf("%w: %d does not fit in uint8", bytocol.ErrVarintOverflow, x2))
+	} else {
+		m.Small = uint8(x2)
+	}
+	if d.err != nil {
+		return d.error("Small", 17, start)
+	}
+	return nil
+}
+
+// MarshalBytocol appends the encoded Times to the buffer, see [bytocol.Marshaler].
+func (m Times) MarshalBytocol(b []byte) ([]byte, error) {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	var err error
+	b = append(b, info.TypeIndicator)
+
+	// Created
+	var units1 int64
+	if units1, err = bytocolUnixTime(m.Created, time.Nanosecond); err != nil {
+		return b, bytocolFieldError("Created", err)
+	}
+	b = bytocolAppend64(b, order, uint64(units1))
+
+	// Seconds
+	var units2 int64
+	if units2, err = bytocolUnixTime(m.Seconds, time.Second); err != nil {
+		return b, bytocolFieldError("Seconds", err)
+	}
+	b = bytocolAppend64(b, binary.LittleEndian, uint64(units2))
+
+	// Zoned
+	var units3 int64
+	if units3, err = bytocolUnixTime(m.Zoned, time.Millisecond); err != nil {
+		return b, bytocolFieldError("Zoned", err)
+	}
+	b = bytocolAppend64(b, order, uint64(units3))
+	_, offset4 := m.Zoned.Zone()
+	b = bytocolAppend32(b, order, uint32(int32(offset4)))
+
+	// Compact
+	var units5 int64
+	if units5, err = bytocolUnixTime(m.Compact, time.Microsecond); err != nil {
+		return b, bytocolFieldError("Compact", err)
+	}
+	b = binary.AppendVarint(b, units5)
+	_, offset6 := m.Compact.Zone()
+	b = binary.AppendVarint(b, int64(offset6))
+
+	// Timeout
+	b = bytocolAppend64(b, order, uint64(int64(m.Timeout/time.Millisecond)))
+
+	// Interval
+	b = binary.AppendVarint(b, int64(m.Interval))
+
+	// Expires
+	if m.Expires == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		var units7 int64
+		if units7, err = bytocolUnixTime((*m.Expires), time.Second); err != nil {
+			return b, bytocolFieldError("Expires", err)
+		}
+		b = bytocolAppend64(b, order, uint64(units7))
+	}
+
+	// History
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.History))); err != nil {
+		return b, bytocolFieldError("History", err)
+	}
+	for i8 := range m.History {
+		var units9 int64
+		if units9, err = bytocolUnixTime(m.History[i8], time.Nanosecond); err != nil {
+			return b, bytocolFieldError("History", err)
+		}
+		b = bytocolAppend64(b, order, uint64(units9))
+	}
+
+	// Spans
+	if b, err = bytocolAppendLength(b, order, 8, uint64(len(m.Spans))); err != nil {
+		return b, bytocolFieldError("Spans", err)
+	}
+	type pair10 struct {
+		k string
+		v time.Duration
+	}
+	pairs11 := make([]pair10, 0, len(m.Spans))
+	for k12, x13 := range m.Spans {
+		pairs11 = append(pairs11, pair10{k12, x13})
+	}
+	slices.SortFunc(pairs11, func(x, y pair10) int {
+		return cmp.Compare(x.k, y.k)
+	})
+	for _, p14 := range pairs11 {
+		if b, err = bytocolAppendLength(b, order, 64, uint64(len(p14.k))); err != nil {
+			return b, bytocolFieldError("Spans", err)
+		}
+		b = append(b, p14.k...)
+		b = bytocolAppend64(b, order, uint64(int64(p14.v)))
+	}
+	return b, nil
+}
+
+// SizeBytocol returns the encoded size of Times, see [bytocol.Marshaler].
+func (m Times) SizeBytocol() int {
+	n := 1
+	n += 8
+	n += 8
+	n += 12
+	if units1, err := bytocolUnixTime(m.Compact, time.Microsecond); err == nil {
+		n += bytocolVarintSize(units1)
+	}
+	_, offset2 := m.Compact.Zone()
+	n += bytocolVarintSize(int64(offset2))
+	n += 8
+	n += bytocolVarintSize(int64(m.Interval))
+	n++
+	if m.Expires != nil {
+		n += 8
+	}
+	n += 1
+	n += len(m.History) * 8
+	n += 1
+	for k3 := range m.Spans {
+		n += 8 + len(k3)
+		n += 8
+	}
+	return n
+}
+
+// UnmarshalBytocol decodes Times without its type indicator, see [bytocol.Unmarshaler].
+func (m *Times) UnmarshalBytocol(r io.Reader) error {
+	info := m.BytocolMessage()
+	order := bytocolByteOrder(info.ByteOrder)
+	d := &bytocolDecoder{r: r, message: info.DebugName, limits: bytocol.DecodeLimitsOf(r)}
+	var start int64
+
+	// Created
+	start = d.n
+	units1 := int64(order.Uint64(d.read(8)))
+	if x2 := bytocolTime(d, units1, time.Nanosecond, 0); d.err == nil {
+		m.Created = x2
+	}
+	if d.err != nil {
+		return d.error("Created", 0, start)
+	}
+
+	// Seconds
+	start = d.n
+	units3 := int64(binary.LittleEndian.Uint64(d.read(8)))
+	if x4 := bytocolTime(d, units3, time.Second, 0); d.err == nil {
+		m.Seconds = x4
+	}
+	if d.err != nil {
+		return d.error("Seconds", 1, start)
+	}
+
+	// Zoned
+	start = d.n
+	units5 := int64(order.Uint64(d.read(8)))
+	if x6 := bytocolTime(d, units5, time.Millisecond, int64(int32(order.Uint32(d.read(4))))); d.err == nil {
+		m.Zoned = x6
+	}
+	if d.err != nil {
+		return d.error("Zoned", 2, start)
+	}
+
+	// Compact
+	start = d.n
+	units7 := d.varint()
+	if x8 := bytocolTime(d, units7, time.Microsecond, d.varint()); d.err == nil {
+		m.Compact = x8
+	}
+	if d.err != nil {
+		return d.error("Compact", 3, start)
+	}
+
+	// Timeout
+	start = d.n
+	if x9 := bytocolDuration(d, int64(order.Uint64(d.read(8))), time.Millisecond); d.err == nil {
+		m.Timeout = x9
+	}
+	if d.err != nil {
+		return d.error("Timeout", 4, start)
+	}
+
+	// Interval
+	start = d.n
+	if x10 := bytocolDuration(d, d.varint(), time.Nanosecond); d.err == nil {
+		m.Interval = x10
+	}
+	if d.err != nil {
+		return d.error("Interval", 5, start)
+	}
+
+	// Expires
+	start = d.n
+	d.enter()
+	switch present11 := d.read(1)[0]; {
+	case d.err != nil:
+	case present11 == 0:
+		m.Expires = nil
+	case present11 == 1:
+		x12 := new(time.Time)
+		units13 := int64(order.Uint64(d.read(8)))
+		if x14 := bytocolTime(d, units13, time.Second, 0); d.err == nil {
+			(*x12) = x14
+		}
+		if d.err == nil {
+			m.Expires = x12
+		}
+	default:
+		d.fail(fmt.Errorf("%w: %d", bytocol.ErrInvalidPresence, present11))
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Expires", 6, start)
+	}
+
+	// History
+	start = d.n
+	d.enter()
+	if count15 := d.count(d.length(order, 8), 0); d.err == nil {
+		s16 := make([]time.Time, 0, min(count15, 2730))
+		for i17 := 0; i17 < count15 && d.err == nil; i17++ {
+			s16 = append(s16, *new(time.Time))
+			units18 := int64(order.Uint64(d.read(8)))
+			if x19 := bytocolTime(d, units18, time.Nanosecond, 0); d.err == nil {
+				s16[i17] = x19
+			}
+		}
+		if d.err == nil {
+			m.History = s16
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("History", 7, start)
+	}
+
+	// Spans
+	start = d.n
+	d.enter()
+	if count20 := d.count(d.length(order, 8), 0); d.err == nil {
+		m21 := make(map[string]time.Duration, min(count20, 8192))
+		for i22 := 0; i22 < count20 && d.err == nil; i22++ {
+			var k23 string
+			if data25 := d.bytes(d.length(order, 64), 0); d.err == nil {
+				k23 = string(data25)
+			}
+			var x24 time.Duration
+			if x26 := bytocolDuration(d, int64(order.Uint64(d.read(8))), time.Nanosecond); d.err == nil {
+				x24 = x26
+			}
+			m21[k23] = x24
+		}
+		if d.err == nil {
+			m.Spans = m21
+		}
+	}
+	d.leave()
+	if d.err != nil {
+		return d.error("Spans", 8, start)
+	}
+	return nil
+}
+
+// bytocolByteOrder returns the byte order of the message, big-endian unless
+// declared otherwise.
+func bytocolByteOrder(order binary.ByteOrder) binary.ByteOrder {
+	if order == nil {
+		return binary.BigEndian
+	}
+	return order
+}
+
+func bytocolBool(v bool) byte {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func bytocolAppend16(b []byte, order binary.ByteOrder, v uint16) []byte {
+	var buf [2]byte
+	order.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func bytocolAppend32(b []byte, order binary.ByteOrder, v uint32) []byte {
+	var buf [4]byte
+	order.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func bytocolAppend64(b []byte, order binary.ByteOrder, v uint64) []byte {
+	var buf [8]byte
+	order.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// bytocolAppendLength appends a length prefix of the given bit-size, failing if
+// the length does not fit.
+func bytocolAppendLength(b []byte, order binary.ByteOrder, bits byte, length uint64) ([]byte, error) {
+	if bits < 64 && length > (uint64(1)<<bits)-1 {
+		return b, fmt.Errorf("%w: length %d exceeds %d-bit length prefix", bytocol.ErrLengthOverflow, length, bits)
+	}
+
+	switch bits {
+	case 8:
+		return append(b, byte(length)), nil
+	case 16:
+		return bytocolAppend16(b, order, uint16(length)), nil
+	case 32:
+		return bytocolAppend32(b, order, uint32(length)), nil
+	}
+	return bytocolAppend64(b, order, length), nil
+}
+
+func bytocolUvarintSize(v uint64) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+	return size
+}
+
+func bytocolVarintSize(v int64) int {
+	return bytocolUvarintSize(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func bytocolFieldError(field string, err error) error {
+	return fmt.Errorf("bytocol: error writing field %s: %w", field, err)
+}
+
+// bytocolDecoder reads the fields of a message, keeping the first error and
+// the number of bytes read. It enforces the same limits as the runtime.
+type bytocolDecoder struct {
+	r       io.Reader
+	message string
+	n       int64
+	buf     [8]byte
+	err     error
+	limits  bytocol.DecodeLimits
+	depth   int
+}
+
+func (d *bytocolDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *bytocolDecoder) error(field string, order uint, start int64) error {
+	return &bytocol.DecodeError{Message: d.message, Field: field, Order: order, Offset: 1 + start, Err: d.err}
+}
+
+// readFull fills the buffer, reporting short reads like the runtime does.
+func (d *bytocolDecoder) readFull(buf []byte) {
+	n, err := io.ReadFull(d.r, buf)
+	d.n += int64(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if n > 0 {
+			err = fmt.Errorf("%w, read %d of %d bytes: %w", bytocol.ErrReadInvariance, n, len(buf), io.ErrUnexpectedEOF)
+		} else {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		d.fail(err)
+		clear(buf)
+	}
+}
+
+// read returns the next size bytes, or zeroes once failed.
+func (d *bytocolDecoder) read(size int) []byte {
+	buf := d.buf[:size]
+	if d.err != nil {
+		clear(buf)
+		return buf
+	}
+	d.readFull(buf)
+	return buf
+}
+
+// limit fails if the value is larger than the limit, zero not being limited.
+func (d *bytocolDecoder) limit(what string, value uint64, limit uint64) bool {
+	if limit != 0 && value > limit {
+		d.fail(fmt.Errorf("%w: %s %d exceeds %d", bytocol.ErrLimitExceeded, what, value, limit))
+	}
+	return d.err == nil
+}
+
+// fixed reads the size bytes of a fixed size codec, which the blob limits do
+// not apply to.
+func (d *bytocolDecoder) fixed(size int) []byte {
+	buf := make([]byte, size)
+	if d.err == nil {
+		d.readFull(buf)
+	}
+	return buf
+}
+
+// bytes reads the length bytes once checked against the limits, growing the
+// buffer as the data is received rather than allocating the length upfront.
+func (d *bytocolDecoder) bytes(length uint64, max uint64) []byte {
+	if d.err != nil || !d.limit("length", length, max) || !d.limit("blob size", length, d.limits.MaxBlobSize) {
+		return nil
+	} else if maxSize := d.limits.MaxMessageSize; maxSize != 0 {
+		remaining := maxSize - min(uint64(1+d.n), maxSize)
+		if length > remaining {
+			d.fail(fmt.Errorf("%w: length %d exceeds the %d bytes left of the message", bytocol.ErrLimitExceeded, length, remaining))
+			return nil
+		}
+	}
+	if length > math.MaxInt {
+		d.fail(fmt.Errorf("%w: length %d does not fit in an int", bytocol.ErrLengthOverflow, length))
+		return nil
+	}
+
+	size := int(length)
+	buf := make([]byte, 0, min(size, 1<<16))
+	for len(buf) < size && d.err == nil {
+		if len(buf) == cap(buf) {
+			buf = slices.Grow(buf, min(size-len(buf), len(buf)))
+		}
+
+		n, err := io.ReadFull(d.r, buf[len(buf):min(cap(buf), size)])
+		buf = buf[:len(buf)+n]
+		d.n += int64(n)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if len(buf) > 0 {
+				err = fmt.Errorf("%w, read %d of %d bytes: %w", bytocol.ErrReadInvariance, len(buf), size, io.ErrUnexpectedEOF)
+			} else {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		if err != nil {
+			d.fail(err)
+		}
+	}
+	return buf
+}
+
+// terminated reads until the NUL terminator, failing once the content exceeds
+// the limits.
+func (d *bytocolDecoder) terminated(max uint64) []byte {
+	limit := d.limits.MaxBlobSize
+	if max != 0 && (limit == 0 || max < limit) {
+		limit = max
+	}
+
+	content := make([]byte, 0)
+	for {
+		c := d.read(1)[0]
+		if d.err != nil || c == 0 || !d.limit("length", uint64(len(content)+1), limit) {
+			return content
+		}
+		content = append(content, c)
+	}
+}
+
+// count checks the count of slice or map elements against the limits, which
+// must fit in an int for the elements to be allocated.
+func (d *bytocolDecoder) count(count uint64, max uint64) int {
+	if d.err != nil || !d.limit("count", count, max) || !d.limit("count", count, d.limits.MaxSliceCount) {
+		return 0
+	} else if count > math.MaxInt {
+		d.fail(fmt.Errorf("%w: count %d does not fit in an int", bytocol.ErrLengthOverflow, count))
+		return 0
+	}
+	return int(count)
+}
+
+// enter nests one level deeper, failing past the maximum depth. Each call is
+// followed by leave.
+func (d *bytocolDecoder) enter() {
+	if maxDepth := d.limits.MaxDepth; maxDepth != 0 && d.depth >= maxDepth {
+		d.fail(fmt.Errorf("%w: nesting depth exceeds %d", bytocol.ErrLimitExceeded, maxDepth))
+	}
+	d.depth++
+}
+
+func (d *bytocolDecoder) leave() {
+	d.depth--
+}
+
+func (d *bytocolDecoder) length(order binary.ByteOrder, bits byte) uint64 {
+	switch bits {
+	case 8:
+		return uint64(d.read(1)[0])
+	case 16:
+		return uint64(order.Uint16(d.read(2)))
+	case 32:
+		return uint64(order.Uint32(d.read(4)))
+	}
+	return order.Uint64(d.read(8))
+}
+
+func (d *bytocolDecoder) uvarint() uint64 {
+	var value uint64
+	var shift uint
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		c := d.read(1)[0]
+		if d.err != nil {
+			return 0
+		}
+
+		if c < 0x80 {
+			if i == binary.MaxVarintLen64-1 && c > 1 {
+				break
+			}
+			return value | uint64(c)<<shift
+		}
+		value |= uint64(c&0x7f) << shift
+		shift += 7
+	}
+
+	d.fail(bytocol.ErrVarintOverflow)
+	return 0
+}
+
+func (d *bytocolDecoder) varint() int64 {
+	unsigned := d.uvarint()
+	value := int64(unsigned >> 1)
+	if unsigned&1 != 0 {
+		value = ^value
+	}
+	return value
+}
+
+// bytocolUnixTime returns the number of units since the Unix epoch, or
+// math.MinInt64 for the zero time which no other time may be encoded as.
+func bytocolUnixTime(t time.Time, unit time.Duration) (int64, error) {
+	if t.IsZero() {
+		return math.MinInt64, nil
+	}
+
+	var units int64
+	switch unit {
+	case time.Second:
+		units = t.Unix()
+	case time.Millisecond:
+		units = t.UnixMilli()
+	case time.Microsecond:
+		units = t.UnixMicro()
+	default:
+		if t.Before(time.Unix(0, math.MinInt64+1)) || t.After(time.Unix(0, math.MaxInt64)) {
+			return 0, fmt.Errorf("%w: %s in nanoseconds", bytocol.ErrTimeOverflow, t)
+		}
+		units = t.UnixNano()
+	}
+
+	if units == math.MinInt64 {
+		return 0, fmt.Errorf("%w: %s in %s", bytocol.ErrTimeOverflow, t, unit)
+	}
+	return units, nil
+}
+
+// bytocolTime returns the time of the number of units since the Unix epoch in
+// the zone of the offset, or UTC when the offset is 0. math.MinInt64 units
+// decode as the zero time.
+func bytocolTime(d *bytocolDecoder, units int64, unit time.Duration, offset int64) time.Time {
+	if offset < math.MinInt32 || offset > math.MaxInt32 {
+		d.fail(fmt.Errorf("%w: zone offset %d does not fit in int32", bytocol.ErrVarintOverflow, offset))
+	}
+
+	var t time.Time
+	switch {
+	case d.err != nil, units == math.MinInt64:
+		return t
+	case unit == time.Second:
+		t = time.Unix(units, 0)
+	case unit == time.Millisecond:
+		t = time.UnixMilli(units)
+	case unit == time.Microsecond:
+		t = time.UnixMicro(units)
+	default:
+		t = time.Unix(0, units)
+	}
+
+	if offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", int(offset)))
+}
+
+// bytocolDuration returns the duration of the number of units, failing if it
+// does not fit.
+func bytocolDuration(d *bytocolDecoder, units int64, unit time.Duration) time.Duration {
+	if d.err == nil && (units > math.MaxInt64/int64(unit) || units < math.MinInt64/int64(unit)) {
+		d.fail(fmt.Errorf("%w: %d units of %s as a duration", bytocol.ErrTimeOverflow, units, unit))
+	}
+	return time.Duration(units) * unit
+}
+
+// bytocolSortPairs orders the encoded pairs of b that start at start and end
+// at each of the ends by their bytes. NaN map keys are all equal to each other,
+// so their pairs are ordered by their encoding instead.
+func bytocolSortPairs(b []byte, start int, ends []int) {
+	if len(ends) < 2 {
+		return
+	}
+
+	pairs := make([][]byte, len(ends))
+	from := start
+	for i, end := range ends {
+		pairs[i] = bytes.Clone(b[from:end])
+		from = end
+	}
+	slices.SortFunc(pairs, bytes.Compare)
+	for _, pair := range pairs {
+		start += copy(b[start:], pair)
+	}
+}
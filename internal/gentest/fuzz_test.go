@@ -0,0 +1,77 @@
+package gentest
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/maple-tech/bytocol"
+)
+
+// fuzzPair is a message with generated methods and its reflection mirror.
+type fuzzPair struct {
+	generated func() bytocol.Unmarshaler
+	mirror    func() bytocol.Message
+}
+
+var fuzzPairs = []fuzzPair{
+	{func() bytocol.Unmarshaler { return new(Numbers) }, func() bytocol.Message { return new(reflectNumbers) }},
+	{func() bytocol.Unmarshaler { return new(Blobs) }, func() bytocol.Message { return new(reflectBlobs) }},
+	{func() bytocol.Unmarshaler { return new(Composite) }, func() bytocol.Message { return new(reflectComposite) }},
+	{func() bytocol.Unmarshaler { return new(Codecs) }, func() bytocol.Message { return new(reflectCodecs) }},
+	{func() bytocol.Unmarshaler { return new(Times) }, func() bytocol.Message { return new(reflectTimes) }},
+	{func() bytocol.Unmarshaler { return new(Limited) }, func() bytocol.Message { return new(reflectLimited) }},
+}
+
+// FuzzGeneratedUnmarshal checks the generated decoders accept and reject the
+// same data as the reflection based decoder, the first byte selecting the
+// message.
+func FuzzGeneratedUnmarshal(f *testing.F) {
+	for i, msg := range []bytocol.Marshaler{testNumbers, &testBlobs, testComposite, testCodecs, testTimes, testLimited} {
+		data, err := msg.MarshalBytocol(nil)
+		if err != nil {
+			f.Fatal(err)
+		}
+		data[0] = byte(i)
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 || int(data[0]) >= len(fuzzPairs) {
+			return
+		}
+		pair := fuzzPairs[data[0]]
+
+		// Both decode through the plan so that the limits of the message apply
+		generated, mirror := pair.generated(), pair.mirror()
+		plan, err := bytocol.PlanObject(generated.(bytocol.Message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mirrorPlan, err := bytocol.PlanObject(mirror)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = plan.Read(bytes.NewReader(data[1:]), generated.(bytocol.Message))
+		mirrorErr := mirrorPlan.Read(bytes.NewReader(data[1:]), mirror)
+		if (err == nil) != (mirrorErr == nil) {
+			t.Fatalf("generated error %v, runtime error %v", err, mirrorErr)
+		} else if err != nil {
+			return
+		}
+
+		// Encodings are compared rather than the values, as NaN differs from
+		// itself
+		encoded, err := generated.(bytocol.Marshaler).MarshalBytocol(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := bytocol.Marshal(reflect.ValueOf(mirror).Elem().Interface().(bytocol.Message))
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(encoded, expected) {
+			t.Errorf("generated decoding differs\n got: %+v\nwant: %+v", generated, mirror)
+		}
+	})
+}
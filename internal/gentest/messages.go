@@ -0,0 +1,179 @@
+// Package gentest holds message types covering every encoding supported by
+// bytocol-gen, along with their generated methods. Its tests check that the
+// generated code produces the same bytes as the reflection based encoder.
+package gentest
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/maple-tech/bytocol"
+)
+
+//go:generate go run ../../cmd/bytocol-gen
+
+// Level is a named integer type.
+type Level uint8
+
+// Raw is a named byte slice type.
+type Raw []byte
+
+// Position is a nested struct shared by the messages.
+type Position struct {
+	X float32 `bytocol:"0"`
+	Y float32 `bytocol:"1"`
+	Z float64 `bytocol:"2"`
+}
+
+// Version encodes itself with a fixed size.
+type Version struct {
+	Major, Minor uint8
+}
+
+func (v Version) MarshalBytocolField() ([]byte, error) {
+	return []byte{v.Major, v.Minor}, nil
+}
+
+func (v *Version) UnmarshalBytocolField(data []byte) error {
+	v.Major, v.Minor = data[0], data[1]
+	return nil
+}
+
+func (v Version) BytocolFieldSize() int {
+	return 2
+}
+
+// Words encodes itself as space separated text.
+type Words []string
+
+func (w Words) MarshalBytocolField() ([]byte, error) {
+	return []byte(strings.Join(w, " ")), nil
+}
+
+func (w *Words) UnmarshalBytocolField(data []byte) error {
+	*w = strings.Fields(string(data))
+	return nil
+}
+
+type header struct {
+	Sequence uint32 `bytocol:"0"`
+	Source   string `bytocol:"1,length-prefix=8"`
+}
+
+// Numbers covers fixed size numbers and varints.
+type Numbers struct {
+	Bool    bool    `bytocol:"0"`
+	Uint8   uint8   `bytocol:"1"`
+	Uint16  uint16  `bytocol:"2"`
+	Uint32  uint32  `bytocol:"3"`
+	Uint64  uint64  `bytocol:"4"`
+	Uint    uint    `bytocol:"5"`
+	Int8    int8    `bytocol:"6"`
+	Int16   int16   `bytocol:"7"`
+	Int32   int32   `bytocol:"8"`
+	Int64   int64   `bytocol:"9"`
+	Int     int     `bytocol:"10"`
+	Float32 float32 `bytocol:"11"`
+	Float64 float64 `bytocol:"12"`
+	Level   Level   `bytocol:"13"`
+	Little  uint32  `bytocol:"14,endian=little"`
+	Counter uint64  `bytocol:"15,varint"`
+	Delta   int16   `bytocol:"16,varint"`
+	Small   uint8   `bytocol:"17,varint"`
+}
+
+func (m Numbers) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{TypeIndicator: 1, DebugName: "numbers"}
+}
+
+// Blobs covers strings and byte slices with every length option.
+type Blobs struct {
+	Default    string `bytocol:"0"`
+	Short      string `bytocol:"1,length-prefix=8"`
+	Medium     string `bytocol:"2,length-prefix=16"`
+	Long       string `bytocol:"3,length-prefix=32"`
+	Terminated string `bytocol:"4,null-terminated"`
+	Varint     string `bytocol:"5,varint"`
+	Bytes      []byte `bytocol:"6,length-prefix=16"`
+	Raw        Raw    `bytocol:"7,varint"`
+	RawEnd     []byte `bytocol:"8,null-terminated"`
+}
+
+func (m *Blobs) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{TypeIndicator: 2, DebugName: "blobs"}
+}
+
+// Composite covers nested structs, slices, arrays, maps, and pointers in a
+// little-endian message.
+type Composite struct {
+	header    `bytocol:"0"`
+	Position  Position           `bytocol:"1"`
+	Path      []Position         `bytocol:"2,length-prefix=16"`
+	Tags      []string           `bytocol:"3,varint"`
+	Matrix    [2][3]int16        `bytocol:"4"`
+	Scores    map[string]uint32  `bytocol:"5,length-prefix=8"`
+	Flags     map[bool]string    `bytocol:"6"`
+	Lookup    map[int8][]Level   `bytocol:"7,varint"`
+	Optional  *uint32            `bytocol:"8"`
+	Note      *string            `bytocol:"9,length-prefix=8"`
+	Origin    *Position          `bytocol:"10"`
+	Waypoints map[uint16]*string `bytocol:"11"`
+	Weights   map[float64]uint8  `bytocol:"12"`
+}
+
+func (m Composite) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{TypeIndicator: 3, DebugName: "composite", ByteOrder: binary.LittleEndian}
+}
+
+// Codecs covers types encoding themselves, along with the binary marshaling
+// fallback of [netip.Addr].
+type Codecs struct {
+	Version  Version               `bytocol:"0"`
+	Words    Words                 `bytocol:"1,length-prefix=8"`
+	Addr     netip.Addr            `bytocol:"2,varint"`
+	Versions []Version             `bytocol:"3,length-prefix=8"`
+	Peers    map[string]netip.Addr `bytocol:"4,length-prefix=8"`
+	Aliases  *Words                `bytocol:"5,length-prefix=16"`
+}
+
+func (m Codecs) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{TypeIndicator: 4, DebugName: "codecs"}
+}
+
+// Times covers times and durations with every unit and option.
+type Times struct {
+	Created  time.Time                `bytocol:"0"`
+	Seconds  time.Time                `bytocol:"1,unit=s,endian=little"`
+	Zoned    time.Time                `bytocol:"2,unit=ms,tz"`
+	Compact  time.Time                `bytocol:"3,unit=us,varint,tz"`
+	Timeout  time.Duration            `bytocol:"4,unit=ms"`
+	Interval time.Duration            `bytocol:"5,varint"`
+	Expires  *time.Time               `bytocol:"6,unit=s"`
+	History  []time.Time              `bytocol:"7,length-prefix=8"`
+	Spans    map[string]time.Duration `bytocol:"8,length-prefix=8"`
+}
+
+func (m Times) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{TypeIndicator: 5, DebugName: "times"}
+}
+
+// Limited covers the max option along with the decode limits of the message.
+type Limited struct {
+	Name   string           `bytocol:"0,length-prefix=16,max=8"`
+	Label  string           `bytocol:"1,null-terminated,max=4"`
+	Items  []uint16         `bytocol:"2,varint,max=3"`
+	Counts map[string]uint8 `bytocol:"3,max=2"`
+	Path   [][]Position     `bytocol:"4,length-prefix=8"`
+	Data   []byte           `bytocol:"5,length-prefix=16"`
+	Empty  [][0]uint32      `bytocol:"6,length-prefix=8"`
+}
+
+func (m Limited) BytocolMessage() bytocol.MessageInfo {
+	return bytocol.MessageInfo{
+		TypeIndicator: 6,
+		DebugName:     "limited",
+		Limits:        bytocol.DecodeLimits{MaxBlobSize: 16, MaxDepth: 2},
+	}
+}
@@ -0,0 +1,360 @@
+package gentest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maple-tech/bytocol"
+)
+
+// The reflect types mirror the messages without their generated methods, so
+// that they are encoded by the reflection based planner.
+
+type reflectNumbers Numbers
+
+func (m reflectNumbers) BytocolMessage() bytocol.MessageInfo {
+	return Numbers{}.BytocolMessage()
+}
+
+type reflectBlobs Blobs
+
+func (m reflectBlobs) BytocolMessage() bytocol.MessageInfo {
+	return (&Blobs{}).BytocolMessage()
+}
+
+type reflectComposite Composite
+
+func (m reflectComposite) BytocolMessage() bytocol.MessageInfo {
+	return Composite{}.BytocolMessage()
+}
+
+type reflectCodecs Codecs
+
+func (m reflectCodecs) BytocolMessage() bytocol.MessageInfo {
+	return Codecs{}.BytocolMessage()
+}
+
+type reflectTimes Times
+
+func (m reflectTimes) BytocolMessage() bytocol.MessageInfo {
+	return Times{}.BytocolMessage()
+}
+
+type reflectLimited Limited
+
+func (m reflectLimited) BytocolMessage() bytocol.MessageInfo {
+	return Limited{}.BytocolMessage()
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+var testNumbers = Numbers{
+	Bool: true, Uint8: 200, Uint16: 60000, Uint32: 4000000000, Uint64: math.MaxUint64, Uint: 42,
+	Int8: -100, Int16: -30000, Int32: -2000000000, Int64: math.MinInt64, Int: -42,
+	Float32: math.Pi, Float64: math.E, Level: 7, Little: 0x01020304,
+	Counter: 300, Delta: -1234, Small: 255,
+}
+
+var testBlobs = Blobs{
+	Default: "default", Short: "short", Medium: "medium", Long: "long",
+	Terminated: "terminated", Varint: "varint",
+	Bytes: []byte{1, 2, 3}, Raw: Raw("raw"), RawEnd: []byte("end"),
+}
+
+var testComposite = Composite{
+	header:   header{Sequence: 9, Source: "sensor"},
+	Position: Position{1, 2, 3},
+	Path:     []Position{{4, 5, 6}, {7, 8, 9}},
+	Tags:     []string{"a", "bc", ""},
+	Matrix:   [2][3]int16{{1, -2, 3}, {-4, 5, -6}},
+	Scores:   map[string]uint32{"zed": 1, "alpha": 2, "mid": 3},
+	Flags:    map[bool]string{true: "yes", false: "no"},
+	Lookup:   map[int8][]Level{-1: {1, 2}, 5: {}, 3: {9}},
+	Optional: ptr(uint32(77)),
+	Note:     nil,
+	Origin:   &Position{-1, -2, -3},
+	Waypoints: map[uint16]*string{
+		3: ptr("three"),
+		1: nil,
+	},
+	Weights: map[float64]uint8{2.5: 1, -1: 2, math.Inf(1): 3},
+}
+
+var testCodecs = Codecs{
+	Version:  Version{1, 2},
+	Words:    Words{"hello", "world"},
+	Addr:     netip.MustParseAddr("192.168.0.1"),
+	Versions: []Version{{3, 4}, {5, 6}},
+	Peers: map[string]netip.Addr{
+		"b": netip.MustParseAddr("::1"),
+		"a": netip.MustParseAddr("10.0.0.1"),
+	},
+	Aliases: &Words{"x", "y", "z"},
+}
+
+var testZone = time.FixedZone("", -5*60*60)
+
+var testTimes = Times{
+	Created:  time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC),
+	Seconds:  time.Date(1960, 1, 2, 3, 4, 5, 0, time.UTC),
+	Zoned:    time.Date(2024, 5, 6, 2, 8, 9, 500000000, testZone),
+	Compact:  time.UnixMicro(123456).In(testZone),
+	Timeout:  1500 * time.Millisecond,
+	Interval: -time.Minute,
+	Expires:  ptr(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+	History:  []time.Time{{}, time.Unix(0, 0).UTC(), time.Unix(1, 0).UTC()},
+	Spans:    map[string]time.Duration{"short": time.Nanosecond, "long": time.Hour},
+}
+
+// checkGenerated compares the generated methods of the message against the
+// reflection based encoding of its mirror type.
+func checkGenerated[T bytocol.Marshaler, R bytocol.Message](t *testing.T, msg T, mirror R, decoded bytocol.Unmarshaler, mirrorDecoded bytocol.Message) {
+	t.Helper()
+
+	expected, err := bytocol.Marshal(mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated, err := msg.MarshalBytocol(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(generated, expected) {
+		t.Fatalf("generated bytes differ\n got: %v\nwant: %v", generated, expected)
+	} else if msg.SizeBytocol() != len(generated) {
+		t.Errorf("generated size %d, encoded %d bytes", msg.SizeBytocol(), len(generated))
+	}
+
+	// Both decoders must agree on the same bytes
+	plan, err := bytocol.PlanObject(mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = plan.Unmarshal(expected[1:], mirrorDecoded); err != nil {
+		t.Fatal(err)
+	}
+	if err = decoded.UnmarshalBytocol(bytes.NewReader(generated[1:])); err != nil {
+		t.Fatal(err)
+	}
+
+	converted := reflect.ValueOf(decoded).Elem().Convert(reflect.TypeOf(mirrorDecoded).Elem()).Interface()
+	if !reflect.DeepEqual(converted, reflect.ValueOf(mirrorDecoded).Elem().Interface()) {
+		t.Errorf("generated decoding differs\n got: %+v\nwant: %+v", converted, mirrorDecoded)
+	}
+}
+
+func TestGeneratedNumbers(t *testing.T) {
+	checkGenerated(t, testNumbers, reflectNumbers(testNumbers), new(Numbers), new(reflectNumbers))
+	checkGenerated(t, Numbers{}, reflectNumbers{}, new(Numbers), new(reflectNumbers))
+}
+
+func TestGeneratedBlobs(t *testing.T) {
+	checkGenerated(t, &testBlobs, reflectBlobs(testBlobs), new(Blobs), new(reflectBlobs))
+	checkGenerated(t, &Blobs{}, reflectBlobs{}, new(Blobs), new(reflectBlobs))
+
+	decoded := new(Blobs)
+	data, _ := testBlobs.MarshalBytocol(nil)
+	if err := decoded.UnmarshalBytocol(bytes.NewReader(data[1:])); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(*decoded, testBlobs) {
+		t.Errorf("unexpected round-trip %+v", decoded)
+	}
+}
+
+func TestGeneratedComposite(t *testing.T) {
+	checkGenerated(t, testComposite, reflectComposite(testComposite), new(Composite), new(reflectComposite))
+	checkGenerated(t, Composite{}, reflectComposite{}, new(Composite), new(reflectComposite))
+}
+
+func TestGeneratedNaNKeys(t *testing.T) {
+	msg := Composite{Weights: map[float64]uint8{1.5: 9}}
+	for i := 0; i < 8; i++ {
+		msg.Weights[math.NaN()] = uint8(i)
+	}
+
+	// NaN keys are ordered by their encoding, the same on every call
+	expected, err := bytocol.Marshal(reflectComposite(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		generated, err := msg.MarshalBytocol(nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(generated, expected) {
+			t.Fatalf("generated bytes differ\n got: %v\nwant: %v", generated, expected)
+		}
+	}
+}
+
+func TestGeneratedCodecs(t *testing.T) {
+	checkGenerated(t, testCodecs, reflectCodecs(testCodecs), new(Codecs), new(reflectCodecs))
+	checkGenerated(t, Codecs{}, reflectCodecs{}, new(Codecs), new(reflectCodecs))
+}
+
+func TestGeneratedTimes(t *testing.T) {
+	checkGenerated(t, testTimes, reflectTimes(testTimes), new(Times), new(reflectTimes))
+	checkGenerated(t, Times{}, reflectTimes{}, new(Times), new(reflectTimes))
+
+	invalid := testTimes
+	invalid.Created = time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := invalid.MarshalBytocol(nil); !errors.Is(err, bytocol.ErrTimeOverflow) {
+		t.Errorf("expected time overflow error, got %v", err)
+	}
+}
+
+var testLimited = Limited{
+	Name:   "name",
+	Label:  "tag",
+	Items:  []uint16{1, 300},
+	Counts: map[string]uint8{"a": 1},
+	Path:   [][]Position{{}, {}},
+	Data:   []byte("sixteen bytes ok"),
+}
+
+func TestGeneratedLimits(t *testing.T) {
+	checkGenerated(t, testLimited, reflectLimited(testLimited), new(Limited), new(reflectLimited))
+
+	plan, err := bytocol.PlanObject(Limited{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirrorPlan, err := bytocol.PlanObject(reflectLimited{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		modify func(*Limited)
+	}{
+		{"max length", "Name", func(m *Limited) { m.Name = "too long name" }},
+		{"max terminated", "Label", func(m *Limited) { m.Label = "label" }},
+		{"max count", "Items", func(m *Limited) { m.Items = []uint16{1, 2, 3, 4} }},
+		{"max pairs", "Counts", func(m *Limited) { m.Counts = map[string]uint8{"a": 1, "b": 2, "c": 3} }},
+		{"depth", "Path", func(m *Limited) { m.Path = [][]Position{{{1, 2, 3}}} }},
+		{"blob size", "Data", func(m *Limited) { m.Data = make([]byte, 17) }},
+	}
+
+	// The encoder does not enforce limits, both decoders must reject the data
+	// at the same field, the runtime also naming the elements
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msg := testLimited
+			test.modify(&msg)
+			data, err := msg.MarshalBytocol(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decErr *bytocol.DecodeError
+			err = plan.Unmarshal(data[1:], new(Limited))
+			if !errors.Is(err, bytocol.ErrLimitExceeded) || !errors.As(err, &decErr) || !strings.HasPrefix(decErr.Field, test.field) {
+				t.Errorf("expected generated limit error on %s, got %v", test.field, err)
+			}
+			err = mirrorPlan.Unmarshal(data[1:], new(reflectLimited))
+			if !errors.Is(err, bytocol.ErrLimitExceeded) || !errors.As(err, &decErr) || !strings.HasPrefix(decErr.Field, test.field) {
+				t.Errorf("expected runtime limit error on %s, got %v", test.field, err)
+			}
+		})
+	}
+}
+
+func TestGeneratedZeroSize(t *testing.T) {
+	if _, err := (Limited{Empty: make([][0]uint32, 1)}).MarshalBytocol(nil); err == nil {
+		t.Error("expected error encoding zero size elements")
+	}
+
+	plan, err := bytocol.PlanObject(Limited{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirrorPlan, err := bytocol.PlanObject(reflectLimited{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The count of zero size elements is the last byte
+	data, err := testLimited.MarshalBytocol(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] = 255
+
+	var decErr *bytocol.DecodeError
+	if err = plan.Unmarshal(data[1:], new(Limited)); !errors.Is(err, bytocol.ErrLimitExceeded) || !errors.As(err, &decErr) || decErr.Field != "Empty" {
+		t.Errorf("expected generated limit error on Empty, got %v", err)
+	}
+	if err = mirrorPlan.Unmarshal(data[1:], new(reflectLimited)); !errors.Is(err, bytocol.ErrLimitExceeded) || !errors.As(err, &decErr) || decErr.Field != "Empty" {
+		t.Errorf("expected runtime limit error on Empty, got %v", err)
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestGeneratedErrors(t *testing.T) {
+	invalid := testBlobs
+	invalid.Terminated = "nul\x00"
+	if _, err := invalid.MarshalBytocol(nil); !errors.Is(err, bytocol.ErrNullInContent) {
+		t.Errorf("expected NUL in content error, got %v", err)
+	}
+
+	invalid = testBlobs
+	invalid.Short = string(make([]byte, 256))
+	if _, err := invalid.MarshalBytocol(nil); !errors.Is(err, bytocol.ErrLengthOverflow) {
+		t.Errorf("expected length overflow error, got %v", err)
+	}
+
+	// Errors of the writer are returned by the generated encoder path
+	if err := bytocol.Write(testNumbers, failingWriter{}); !errors.Is(err, errWriteFailed) {
+		t.Errorf("expected write error, got %v", err)
+	}
+
+	// Truncated data reports the field it stopped at
+	data, _ := testNumbers.MarshalBytocol(nil)
+	var decErr *bytocol.DecodeError
+	err := new(Numbers).UnmarshalBytocol(bytes.NewReader(data[1:10]))
+	if !errors.As(err, &decErr) {
+		t.Fatalf("expected decode error, got %v", err)
+	} else if decErr.Field != "Uint64" || decErr.Order != 4 || decErr.Offset != 9 || decErr.Message != "numbers" {
+		t.Errorf("unexpected decode error %+v", decErr)
+	} else if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestGeneratedRuntime(t *testing.T) {
+	reg := bytocol.NewRegistry()
+	if _, err := bytocol.Register[Composite](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bytocol.Marshal(testComposite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := reg.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(msg, testComposite) {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
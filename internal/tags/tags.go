@@ -0,0 +1,132 @@
+// Package tags parses the bytocol struct tags. It is shared by the runtime
+// planner and the bytocol-gen code generator so both follow the same rules.
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag holds the options parsed from a `bytocol:"..."` struct tag.
+type Tag struct {
+	Order              uint
+	StringLengthPrefix bool
+	StringLengthSize   byte
+	NullTerminated     bool
+	Varint             bool
+	ByteOrder          binary.ByteOrder
+
+	// Unit is the precision of time and duration fields, zero when not set.
+	Unit time.Duration
+
+	// TimeZone indicates time fields include their zone offset.
+	TimeZone bool
+
+	// Max is the largest length of strings, byte slices, and codecs, or count
+	// of slices and maps, accepted when decoding. Zero when not set.
+	Max uint64
+}
+
+// Parse parses the contents of a bytocol struct tag, being the field order
+// optionally followed by comma separated options.
+func Parse(tag string) (Tag, error) {
+	var err error
+	var info Tag
+
+	// The order always comes first, optionally followed by options
+	orderPart := tag
+	firstComma := strings.IndexRune(tag, ',')
+	if firstComma != -1 {
+		orderPart = tag[:firstComma]
+	}
+
+	u64, err := strconv.ParseUint(strings.TrimSpace(orderPart), 10, 32)
+	if err != nil {
+		return info, err
+	}
+	info.Order = uint(u64)
+
+	if firstComma != -1 {
+		// Contains options, recursively parse the options
+		var optionKey string
+		var optionValue string
+		for _, rawOption := range strings.Split(tag[firstComma+1:], ",") {
+			// Check if it has value
+			equalInd := strings.IndexRune(rawOption, '=')
+			if equalInd == -1 {
+				// No value, just the option
+				optionKey = strings.TrimSpace(rawOption)
+				optionValue = ""
+			} else {
+				// Has value probably
+				optionKey = strings.TrimSpace(rawOption[:equalInd])
+				optionValue = strings.TrimSpace(rawOption[equalInd+1:])
+			}
+
+			switch optionKey {
+			case "null-terminated":
+				info.StringLengthPrefix = false
+				info.StringLengthSize = 0
+				info.NullTerminated = true
+			case "length-prefix":
+				info.StringLengthPrefix = true
+				info.NullTerminated = false
+				u64, err := strconv.ParseUint(optionValue, 10, 8)
+				if err != nil {
+					return info, fmt.Errorf("invalid length-prefix value: %s", err)
+				} else if u64 == 0 {
+					return info, errors.New("cannot have 0 length-prefix value")
+				} else if u64 != 8 && u64 != 16 && u64 != 32 && u64 != 64 {
+					return info, fmt.Errorf("length-prefix bit-size %d is invalid, must be 8|16|32|64", u64)
+				}
+				info.StringLengthSize = byte(u64)
+			case "varint":
+				info.Varint = true
+			case "endian":
+				switch optionValue {
+				case "little":
+					info.ByteOrder = binary.LittleEndian
+				case "big":
+					info.ByteOrder = binary.BigEndian
+				default:
+					return info, fmt.Errorf("endian value %q is invalid, must be little|big", optionValue)
+				}
+			case "unit":
+				switch optionValue {
+				case "s":
+					info.Unit = time.Second
+				case "ms":
+					info.Unit = time.Millisecond
+				case "us":
+					info.Unit = time.Microsecond
+				case "ns":
+					info.Unit = time.Nanosecond
+				default:
+					return info, fmt.Errorf("unit value %q is invalid, must be s|ms|us|ns", optionValue)
+				}
+			case "tz":
+				info.TimeZone = true
+			case "max":
+				u64, err := strconv.ParseUint(optionValue, 10, 64)
+				if err != nil {
+					return info, fmt.Errorf("invalid max value: %s", err)
+				} else if u64 == 0 {
+					return info, errors.New("cannot have 0 max value")
+				}
+				info.Max = u64
+			default:
+				return info, fmt.Errorf("invalid option %s in bytocol struct tag", optionKey)
+			}
+		}
+
+		if info.Varint && (info.StringLengthPrefix || info.NullTerminated) {
+			return info, errors.New("varint cannot be combined with length-prefix or null-terminated")
+		}
+	}
+
+	return info, err
+}
@@ -0,0 +1,179 @@
+package bytocol
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+)
+
+// DecodeLimits bounds what decoding a single message may allocate, so that
+// lengths and counts read off the wire cannot exhaust the memory of the
+// decoder. Every limit is checked before anything is allocated for the value,
+// failing with [ErrLimitExceeded]. Zero fields are not limited.
+//
+// Limits are declared per message type with [MessageInfo], and per connection
+// with [WithDecodeLimits]. When both apply, the lowest of each limit is used.
+type DecodeLimits struct {
+	// MaxBlobSize is the largest length in bytes of strings, byte slices, and
+	// variable size codecs.
+	MaxBlobSize uint64
+
+	// MaxMessageSize is the largest number of bytes of a message, from its
+	// type indicator to its last field. The checksum trailer is not counted.
+	MaxMessageSize uint64
+
+	// MaxSliceCount is the largest number of elements of slices, and of pairs
+	// of maps.
+	MaxSliceCount uint64
+
+	// MaxDepth is the largest number of structs, slices, arrays, maps, and
+	// optional values nested within each other. The fields of the message
+	// itself are not nested.
+	MaxDepth int
+}
+
+// tighten returns the lowest of each limit of both.
+func (l DecodeLimits) tighten(other DecodeLimits) DecodeLimits {
+	return DecodeLimits{
+		MaxBlobSize:    lowestLimit(l.MaxBlobSize, other.MaxBlobSize),
+		MaxMessageSize: lowestLimit(l.MaxMessageSize, other.MaxMessageSize),
+		MaxSliceCount:  lowestLimit(l.MaxSliceCount, other.MaxSliceCount),
+		MaxDepth:       lowestLimit(l.MaxDepth, other.MaxDepth),
+	}
+}
+
+// lowestLimit returns the lowest of both limits, zero not being limited.
+func lowestLimit[T uint64 | int](a, b T) T {
+	if a == 0 {
+		return b
+	} else if b == 0 {
+		return a
+	}
+	return min(a, b)
+}
+
+// checkLimit returns an [ErrLimitExceeded] error describing the value if it is
+// larger than the limit, zero not being limited.
+func checkLimit(what string, value uint64, limit uint64) error {
+	if limit != 0 && value > limit {
+		return fmt.Errorf("%w: %s %d exceeds %d", ErrLimitExceeded, what, value, limit)
+	}
+	return nil
+}
+
+// DecodeLimitsOf returns the limits applying to the reader passed to
+// [Unmarshaler.UnmarshalBytocol], or no limits for any other reader. Generated
+// decoders use it to enforce the same limits as [TypePlan.Read].
+func DecodeLimitsOf(r io.Reader) DecodeLimits {
+	if cr, ok := r.(*countingReader); ok {
+		return cr.limits
+	}
+	return DecodeLimits{}
+}
+
+// WithDecodeLimits bounds the messages received by the connection, on top of
+// the limits declared by each message type. The maximum frame size still
+// applies to the whole frame.
+func WithDecodeLimits(limits DecodeLimits) ConnOption {
+	return func(c *Conn) {
+		c.decodeLimits = limits
+	}
+}
+
+// preallocSize is the largest number of bytes, or elements, allocated before
+// they are read. Longer blobs and slices grow as their data is read, so that a
+// prefix larger than the data cannot allocate more memory than was received.
+const preallocSize = 1 << 16
+
+// checkBlob checks the length of a string, byte slice, or variable size codec of
+// the entry against its max option and the limits of the reader.
+func (pe planEntry) checkBlob(r io.Reader, length uint64) error {
+	cr, ok := r.(*countingReader)
+	if err := checkLimit("length", length, pe.Max); err != nil || !ok {
+		return err
+	} else if err = checkLimit("blob size", length, cr.limits.MaxBlobSize); err != nil {
+		return err
+	} else if length > cr.remaining() {
+		return fmt.Errorf("%w: length %d exceeds the %d bytes left of the message", ErrLimitExceeded, length, cr.remaining())
+	}
+	return nil
+}
+
+// readCount decodes the count prefix of slice or map elements for this entry,
+// checked against its max option and the limits of the reader. The count must
+// fit in an int for the elements to be allocated. Elements of zero size are
+// encoded as nothing, so only a count of zero is accepted for them rather than
+// reading them forever without consuming any data.
+func (pe planEntry) readCount(r io.Reader) (int, error) {
+	count, err := pe.readPrefix(r)
+	if err != nil {
+		return 0, err
+	} else if count > 0 && pe.countedSize() == 0 {
+		return 0, fmt.Errorf("%w: count %d of zero size elements exceeds 0", ErrLimitExceeded, count)
+	} else if err = checkLimit("count", count, pe.Max); err != nil {
+		return 0, err
+	} else if cr, ok := r.(*countingReader); ok {
+		if err = checkLimit("count", count, cr.limits.MaxSliceCount); err != nil {
+			return 0, err
+		}
+	}
+
+	if count > math.MaxInt {
+		return 0, fmt.Errorf("%w: count %d does not fit in an int", ErrLengthOverflow, count)
+	}
+	return int(count), nil
+}
+
+// enterNested increments the nesting depth of the reader, failing past its
+// maximum depth. Each successful call must be followed by [leaveNested].
+func enterNested(r io.Reader) error {
+	cr, ok := r.(*countingReader)
+	if !ok {
+		return nil
+	}
+
+	if maxDepth := cr.limits.MaxDepth; maxDepth != 0 && cr.depth >= maxDepth {
+		return fmt.Errorf("%w: nesting depth exceeds %d", ErrLimitExceeded, maxDepth)
+	}
+	cr.depth++
+	return nil
+}
+
+// leaveNested decrements the nesting depth of the reader.
+func leaveNested(r io.Reader) {
+	if cr, ok := r.(*countingReader); ok {
+		cr.depth--
+	}
+}
+
+// readGrowing reads the length bytes, allocating at most [preallocSize] bytes
+// ahead of the data received.
+func readGrowing(r io.Reader, length uint64) ([]byte, error) {
+	if length > math.MaxInt {
+		return nil, fmt.Errorf("%w: length %d does not fit in an int", ErrLengthOverflow, length)
+	}
+
+	size := int(length)
+	buf := make([]byte, 0, min(size, preallocSize))
+	for len(buf) < size {
+		if len(buf) == cap(buf) {
+			// Double the buffer, as the data received so far was complete
+			buf = slices.Grow(buf, min(size-len(buf), len(buf)))
+		}
+
+		n, err := io.ReadFull(r, buf[len(buf):min(cap(buf), size)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			return buf, shortReadError(len(buf), size, err)
+		}
+	}
+	return buf, nil
+}
+
+// preallocCount returns the number of elements of the type to allocate before
+// reading count of them, bounded by [preallocSize] bytes.
+func preallocCount(typeOf reflect.Type, count int) int {
+	return min(count, preallocSize/max(int(typeOf.Size()), 1))
+}
@@ -0,0 +1,259 @@
+package bytocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testMaxMessage struct {
+	Name   string          `bytocol:"0,length-prefix=16,max=4"`
+	Tags   []string        `bytocol:"1,length-prefix=8,max=2"`
+	Scores map[uint8]uint8 `bytocol:"2,max=1"`
+	Raw    []byte          `bytocol:"3,null-terminated,max=3"`
+}
+
+func (m testMaxMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 20, DebugName: "max"}
+}
+
+type testLimitsMessage struct {
+	Data   []byte       `bytocol:"0,varint"`
+	Items  []uint32     `bytocol:"1,varint"`
+	Nested [][]testItem `bytocol:"2,varint"`
+}
+
+type testItem struct {
+	ID uint8 `bytocol:"0"`
+}
+
+func (m testLimitsMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 21, DebugName: "limits", Limits: DecodeLimits{MaxBlobSize: 8, MaxDepth: 2}}
+}
+
+type testUnlimitedMessage struct {
+	Data  []byte           `bytocol:"0,varint"`
+	Items []uint32         `bytocol:"1,varint"`
+	Pairs map[uint8]string `bytocol:"2,varint"`
+}
+
+func (m testUnlimitedMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 22, DebugName: "unlimited"}
+}
+
+type testMaxNumberMessage struct {
+	Value uint32 `bytocol:"0,max=4"`
+}
+
+func (m testMaxNumberMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 23}
+}
+
+type testMaxVarintMessage struct {
+	Value uint32 `bytocol:"0,varint,max=4"`
+}
+
+func (m testMaxVarintMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 23}
+}
+
+type testMaxTimeMessage struct {
+	Value time.Time `bytocol:"0,max=4"`
+}
+
+func (m testMaxTimeMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 23}
+}
+
+type testZeroSizeMessage struct {
+	Items   [][0]uint32 `bytocol:"0"`
+	Version testVersion `bytocol:"1"`
+}
+
+func (m testZeroSizeMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 24, DebugName: "zero size"}
+}
+
+func TestDecodeMaxOption(t *testing.T) {
+	plan, err := PlanType[testMaxMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := testMaxMessage{Name: "abcd", Tags: []string{"a", "b"}, Scores: map[uint8]uint8{1: 2}, Raw: []byte("xyz")}
+	for _, test := range []struct {
+		name   string
+		field  string
+		modify func(*testMaxMessage)
+	}{
+		{"valid", "", func(*testMaxMessage) {}},
+		{"length", "Name", func(m *testMaxMessage) { m.Name = "abcde" }},
+		{"count", "Tags", func(m *testMaxMessage) { m.Tags = []string{"a", "b", "c"} }},
+		{"pairs", "Scores", func(m *testMaxMessage) { m.Scores = map[uint8]uint8{1: 2, 3: 4} }},
+		{"terminated", "Raw", func(m *testMaxMessage) { m.Raw = []byte("wxyz") }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			msg := valid
+			test.modify(&msg)
+
+			// Only decoding enforces the max option
+			data, err := Marshal(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded testMaxMessage
+			var decErr *DecodeError
+			err = plan.Unmarshal(data[1:], &decoded)
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			} else if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &decErr) || decErr.Field != test.field {
+				t.Errorf("expected limit error on %s, got %v", test.field, err)
+			}
+		})
+	}
+
+	if !strings.Contains(plan.String(), "Name string 2+ max=4") {
+		t.Errorf("expected max in plan string, got:\n%s", plan.String())
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	plan, err := PlanType[testLimitsMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		msg    testLimitsMessage
+		limits DecodeLimits
+		field  string
+	}{
+		{"within limits", testLimitsMessage{Data: make([]byte, 8), Nested: [][]testItem{{}}}, DecodeLimits{}, ""},
+		{"blob size", testLimitsMessage{Data: make([]byte, 9)}, DecodeLimits{}, "Data"},
+		{"depth", testLimitsMessage{Nested: [][]testItem{{{1}}}}, DecodeLimits{}, "Nested[0][0]"},
+		{"slice count", testLimitsMessage{Items: []uint32{1, 2, 3}}, DecodeLimits{MaxSliceCount: 2}, "Items"},
+		{"tighter blob size", testLimitsMessage{Data: make([]byte, 5)}, DecodeLimits{MaxBlobSize: 4}, "Data"},
+		{"looser blob size", testLimitsMessage{Data: make([]byte, 9)}, DecodeLimits{MaxBlobSize: 16}, "Data"},
+		{"message size", testLimitsMessage{Items: []uint32{1, 2, 3}}, DecodeLimits{MaxMessageSize: 11}, "Items[2]"},
+		{"remaining size", testLimitsMessage{Data: make([]byte, 6)}, DecodeLimits{MaxMessageSize: 6}, "Data"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := Marshal(test.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded testLimitsMessage
+			var decErr *DecodeError
+			err = plan.read(strings.NewReader(string(data[1:])), &decoded, test.limits)
+			if test.field == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			} else if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &decErr) || decErr.Field != test.field {
+				t.Errorf("expected limit error on %s, got %v", test.field, err)
+			}
+		})
+	}
+}
+
+func TestDecodeHugePrefix(t *testing.T) {
+	plan, err := PlanType[testUnlimitedMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without limits, prefixes larger than the data fail once the data runs
+	// out rather than allocating their length upfront
+	huge := binary.AppendUvarint(nil, 1<<62)
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"blob", append(huge, "short"...)},
+		{"count", append([]byte{0}, append(huge, 1, 2)...)},
+		{"pairs", append([]byte{0, 0}, append(huge, 1, 0)...)},
+	} {
+		var decoded testUnlimitedMessage
+		if err = plan.Unmarshal(test.data, &decoded); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: expected unexpected EOF, got %v", test.name, err)
+		}
+	}
+}
+
+func TestConnDecodeLimits(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testMaxMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := net.Pipe()
+	client := NewConn(a, WithRegistry(reg))
+	server := NewConn(b, WithRegistry(reg), WithDecodeLimits(DecodeLimits{MaxBlobSize: 2}))
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		if err := client.Send(testMaxMessage{Name: "abc"}); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if _, err := server.Receive(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected limit error, got %v", err)
+	}
+}
+
+func TestPlanMaxErrors(t *testing.T) {
+	if _, err := PlanType[testMaxNumberMessage](); err == nil || !strings.Contains(err.Error(), "length options") {
+		t.Errorf("expected length options error, got %v", err)
+	}
+
+	if _, err := PlanType[testMaxVarintMessage](); err == nil || !strings.Contains(err.Error(), "max is not supported") {
+		t.Errorf("expected max error, got %v", err)
+	}
+
+	if _, err := PlanType[testMaxTimeMessage](); err == nil || !strings.Contains(err.Error(), "length options") {
+		t.Errorf("expected length options error, got %v", err)
+	}
+}
+
+func TestDecodeZeroSizeCount(t *testing.T) {
+	plan, err := PlanType[testZeroSizeMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Elements encoded as nothing cannot be told apart from a huge count,
+	// which must fail rather than decode forever
+	data := []byte{24, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 2}
+	var decoded testZeroSizeMessage
+	var decErr *DecodeError
+	if err = plan.Unmarshal(data[1:], &decoded); !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &decErr) || decErr.Field != "Items" {
+		t.Errorf("expected limit error on Items, got %v", err)
+	} else if explained := plan.Explain(data); !strings.Contains(explained, "DATA OVERFLOW") {
+		t.Errorf("expected overflow explaining the count, got %s", explained)
+	}
+	if _, err = Marshal(testZeroSizeMessage{Items: make([][0]uint32, 1)}); err == nil {
+		t.Error("expected error encoding zero size elements")
+	}
+
+	// Fixed size codecs are not blobs, so the blob size does not apply
+	msg := testZeroSizeMessage{Items: [][0]uint32{}, Version: testVersion{1, 2}}
+	if data, err = Marshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err = plan.read(strings.NewReader(string(data[1:])), &decoded, DecodeLimits{MaxBlobSize: 1}); err != nil {
+		t.Error(err)
+	} else if decoded.Version != msg.Version {
+		t.Errorf("unexpected version %v", decoded.Version)
+	}
+}
@@ -0,0 +1,74 @@
+package bytocol
+
+import (
+	"cmp"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// isMapKeyKind returns true if the kind can be used as a map key for encoding.
+// Only kinds with a natural ordering are allowed so that the encoding is
+// deterministic.
+func isMapKeyKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+// compareMapKeys compares two map keys of the same kind by their natural
+// ordering. False is ordered before true.
+func compareMapKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Bool:
+		return cmp.Compare(boolToByte(a.Bool()), boolToByte(b.Bool()))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	}
+	return 0
+}
+
+// isNaNKey returns true if the map key is a floating point NaN, which is never
+// equal to any other key including itself.
+func isNaNKey(key reflect.Value) bool {
+	switch key.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return math.IsNaN(key.Float())
+	}
+	return false
+}
+
+// mapPair is a single key and value of a map value.
+type mapPair struct {
+	key   reflect.Value
+	value reflect.Value
+}
+
+// sortedMapPairs returns the pairs of the map value sorted by the natural
+// ordering of their keys, so that maps are always encoded in the same order.
+// The pairs are collected while ranging since NaN keys cannot be looked up.
+func sortedMapPairs(value reflect.Value) []mapPair {
+	pairs := make([]mapPair, 0, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		pairs = append(pairs, mapPair{iter.Key(), iter.Value()})
+	}
+
+	slices.SortFunc(pairs, func(a, b mapPair) int {
+		return compareMapKeys(a.key, b.key)
+	})
+	return pairs
+}
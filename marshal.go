@@ -11,20 +11,23 @@ import (
 // during encoding or writing to the pipe. Depending on the error will indicate
 // if the message might have been sent or not, but best to assume it did not.
 //
-// Just like [Encoder.Encode] the object must implement [bytocol.Message] interface,
-// as this uses that first and then writes it to the pipe.
+// The object must implement [bytocol.Message] interface, as this uses that
+// first and then writes it to the pipe.
 //
-// NOTE: This will run the reflection process on each object and will not
-// cache the encoding plan. Additionally, the byte data is allocated in-memory
-// and not streamed directly.
+// The encoding plan is built on the first use of each type and then cached, see
+// [CachedPlan].
 func Write(obj Message, w io.Writer) error {
+	if obj == nil {
+		return ErrNonMessageType
+	}
+
 	// Grab the message info for the error messages, the plan will
 	// write the actual type indicator
 	msgInfo := obj.BytocolMessage()
 
-	// Build an encoding plan containing values in order with their
+	// Find or build an encoding plan containing values in order with their
 	// values, types, and encoding options.
-	plan, err := PlanObject(obj)
+	plan, err := CachedPlan(obj)
 	if err != nil {
 		return fmt.Errorf("bytocol: cannot encode %s, %s", msgInfo.DebugName, err)
 	} else if !plan.IsValid() {
@@ -41,10 +44,23 @@ func Write(obj Message, w io.Writer) error {
 // contains the data up until the error was encountered.
 //
 // The object must implement [bytocol.Message] interface.
-//
-// NOTE: This will run the reflection processes on every object entered.
 func Marshal(obj Message) ([]byte, error) {
 	var buf bytes.Buffer
 	err := Write(obj, &buf)
 	return buf.Bytes(), err
 }
+
+// Read reads a single message from the [io.Reader] using the [DefaultRegistry].
+// The type indicator is peeked to find the registered plan and a freshly decoded
+// value is returned. The message type must have been registered beforehand with
+// [Register].
+func Read(r io.Reader) (Message, error) {
+	return DefaultRegistry.Read(r)
+}
+
+// Unmarshal decodes the byte data into a new message using the [DefaultRegistry].
+// The data must start with the type indicator. The message type must have been
+// registered beforehand with [Register].
+func Unmarshal(data []byte) (Message, error) {
+	return DefaultRegistry.Unmarshal(data)
+}
@@ -16,7 +16,7 @@ type testMessage struct {
 }
 
 func (m testMessage) BytocolMessage() MessageInfo {
-	return MessageInfo{1, "test"}
+	return MessageInfo{TypeIndicator: 1, DebugName: "test"}
 }
 
 var testMessageObj = testMessage{0, true, 1234, 4321, math.Pi, "Foo", []byte("Bar")}
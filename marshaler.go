@@ -0,0 +1,28 @@
+package bytocol
+
+import "io"
+
+// Marshaler is implemented by messages with an encoder generated by the
+// bytocol-gen command. When present it is preferred over the reflection based
+// encoding of [TypePlan.Write], and must produce identical bytes.
+type Marshaler interface {
+	Message
+
+	// MarshalBytocol appends the encoded message, starting with the type
+	// indicator, to the buffer and returns the extended buffer.
+	MarshalBytocol(buf []byte) ([]byte, error)
+
+	// SizeBytocol returns the number of bytes MarshalBytocol will append.
+	SizeBytocol() int
+}
+
+// Unmarshaler is implemented by pointers to messages with a decoder generated
+// by the bytocol-gen command. When present it is preferred over the reflection
+// based decoding of [TypePlan.Read].
+type Unmarshaler interface {
+	Message
+
+	// UnmarshalBytocol decodes the message from the reader, which must already
+	// have the type indicator removed.
+	UnmarshalBytocol(r io.Reader) error
+}
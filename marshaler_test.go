@@ -0,0 +1,55 @@
+package bytocol
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type testGeneratedMessage struct {
+	Value uint8 `bytocol:"0"`
+}
+
+func (m testGeneratedMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 10, DebugName: "generated"}
+}
+
+// The generated methods encode the value doubled, so that their use is visible.
+
+func (m testGeneratedMessage) MarshalBytocol(buf []byte) ([]byte, error) {
+	return append(buf, 10, m.Value*2), nil
+}
+
+func (m testGeneratedMessage) SizeBytocol() int {
+	return 2
+}
+
+func (m *testGeneratedMessage) UnmarshalBytocol(r io.Reader) error {
+	var buf [1]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return err
+	}
+	m.Value = buf[0] / 2
+	return nil
+}
+
+func TestPlanPrefersGenerated(t *testing.T) {
+	plan, err := PlanType[testGeneratedMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := plan.Marshal(testGeneratedMessage{Value: 21})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(data, []byte{10, 42}) {
+		t.Errorf("expected generated encoding, got %v", data)
+	}
+
+	var decoded testGeneratedMessage
+	if err = plan.Unmarshal(data[1:], &decoded); err != nil {
+		t.Fatal(err)
+	} else if decoded.Value != 21 {
+		t.Errorf("expected generated decoding, got %d", decoded.Value)
+	}
+}
@@ -1,5 +1,7 @@
 package bytocol
 
+import "encoding/binary"
+
 // MessageInfo is a description object describing how to encode a message. This
 // is used by the [bytocol.Message] interface to indicate options for a specific
 // message type.
@@ -13,4 +15,17 @@ type MessageInfo struct {
 	// DebugName is a helper string to name this message when debugging and for
 	// stringification purposes.
 	DebugName string
+
+	// ByteOrder is the default byte order for all numbers and length prefixes
+	// of the message, including nested structs. Individual fields can override
+	// it with the `endian` tag option. When nil [binary.BigEndian] is used.
+	ByteOrder binary.ByteOrder
+
+	// Checksum appends a trailer computed over the encoded message, verified
+	// when decoding. By default there is none.
+	Checksum Checksum
+
+	// Limits bound the resources spent decoding the message. By default only
+	// the max options of the fields limit it.
+	Limits DecodeLimits
 }
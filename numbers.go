@@ -15,6 +15,16 @@ type number interface {
 		float32 | float64
 }
 
+// isIntegerKind returns true for all the signed and unsigned integer kinds.
+func isIntegerKind(kind reflect.Kind) bool {
+	return isSignedKind(kind) || (kind >= reflect.Uint && kind <= reflect.Uint64)
+}
+
+// isSignedKind returns true for the signed integer kinds.
+func isSignedKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
 func boolToByte(b bool) byte {
 	if b {
 		return 1
@@ -22,7 +32,7 @@ func boolToByte(b bool) byte {
 	return 0
 }
 
-func numberToBytes[T number](num T) []byte {
+func numberToBytes[T number](num T, order binary.ByteOrder) []byte {
 	var data []byte
 
 	switch any(num).(type) {
@@ -30,31 +40,31 @@ func numberToBytes[T number](num T) []byte {
 		data = []byte{byte(num)}
 	case uint16, int16:
 		data = make([]byte, 2)
-		binary.BigEndian.PutUint16(data, uint16(num))
+		order.PutUint16(data, uint16(num))
 	case uint32, int32:
 		data = make([]byte, 4)
-		binary.BigEndian.PutUint32(data, uint32(num))
+		order.PutUint32(data, uint32(num))
 	case uint64, int64, uint, int:
 		data = make([]byte, 8)
-		binary.BigEndian.PutUint64(data, uint64(num))
+		order.PutUint64(data, uint64(num))
 	case float32:
 		data = make([]byte, 4)
-		binary.BigEndian.PutUint32(data, math.Float32bits(float32(num)))
+		order.PutUint32(data, math.Float32bits(float32(num)))
 	case float64:
 		data = make([]byte, 8)
-		binary.BigEndian.PutUint64(data, math.Float64bits(float64(num)))
+		order.PutUint64(data, math.Float64bits(float64(num)))
 	}
 
 	return data
 }
 
-func writeNumber[T number](num T, w io.Writer) error {
-	data := numberToBytes(num)
+func writeNumber[T number](num T, order binary.ByteOrder, w io.Writer) error {
+	data := numberToBytes(num, order)
 	_, err := w.Write(data)
 	return err
 }
 
-func bytesToNumber[T number](data []byte) (T, error) {
+func bytesToNumber[T number](data []byte, order binary.ByteOrder) (T, error) {
 	var value T
 
 	switch any(value).(type) {
@@ -67,35 +77,35 @@ func bytesToNumber[T number](data []byte) (T, error) {
 		if len(data) != 2 {
 			return value, fmt.Errorf("cannot convert %v bytes to uint16", data)
 		}
-		value = T(binary.BigEndian.Uint16(data))
+		value = T(order.Uint16(data))
 	case uint32, int32:
 		if len(data) != 4 {
 			return value, fmt.Errorf("cannot convert %v bytes to uint32", data)
 		}
-		value = T(binary.BigEndian.Uint32(data))
+		value = T(order.Uint32(data))
 	case uint64, int64:
 		if len(data) != 8 {
 			return value, fmt.Errorf("cannot convert %v bytes to uint64", data)
 		}
-		value = T(binary.BigEndian.Uint64(data))
+		value = T(order.Uint64(data))
 	case float32:
 		if len(data) != 4 {
 			return value, fmt.Errorf("cannot convert %v bytes to float32", data)
 		}
-		ui := binary.BigEndian.Uint32(data)
+		ui := order.Uint32(data)
 		value = T(math.Float32frombits(ui))
 	case float64:
 		if len(data) != 8 {
 			return value, fmt.Errorf("cannot convert %v bytes to float64", data)
 		}
-		ui := binary.BigEndian.Uint64(data)
+		ui := order.Uint64(data)
 		value = T(math.Float64frombits(ui))
 	}
 
 	return value, nil
 }
 
-func readNumber[T number](r io.Reader) (T, error) {
+func readNumber[T number](r io.Reader, order binary.ByteOrder) (T, error) {
 	var value T
 
 	length := 1
@@ -111,19 +121,14 @@ func readNumber[T number](r io.Reader) (T, error) {
 	}
 
 	buf := make([]byte, length)
-	n, err := r.Read(buf)
-
-	if n == length {
-		value, e := bytesToNumber[T](buf)
-		if e != nil {
-			return value, e
-		}
+	if err := readFull(r, buf); err != nil {
+		return value, err
 	}
-	return value, err
+	return bytesToNumber[T](buf, order)
 }
 
-func setNumberFromBytes[T number](data []byte, target reflect.Value) error {
-	num, err := bytesToNumber[T](data)
+func setNumberFromBytes[T number](data []byte, order binary.ByteOrder, target reflect.Value) error {
+	num, err := bytesToNumber[T](data, order)
 	if err != nil {
 		return err
 	}
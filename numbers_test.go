@@ -2,49 +2,69 @@ package bytocol
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math"
 	"reflect"
 	"testing"
 )
 
 func TestNumberToBytes(t *testing.T) {
-	if test := numberToBytes(byte(0xF0)); !bytes.Equal(test, []byte{0xF0}) {
+	if test := numberToBytes(byte(0xF0), binary.BigEndian); !bytes.Equal(test, []byte{0xF0}) {
 		t.Errorf("incorrect byte: %v", test)
 	}
-	if test := numberToBytes(uint16(0xF0A0)); !bytes.Equal(test, []byte{0xF0, 0xA0}) {
+	if test := numberToBytes(uint16(0xF0A0), binary.BigEndian); !bytes.Equal(test, []byte{0xF0, 0xA0}) {
 		t.Errorf("incorrect uint16: %v", test)
 	}
-	if test := numberToBytes(uint32(0xF0A05020)); !bytes.Equal(test, []byte{0xF0, 0xA0, 0x50, 0x20}) {
+	if test := numberToBytes(uint32(0xF0A05020), binary.BigEndian); !bytes.Equal(test, []byte{0xF0, 0xA0, 0x50, 0x20}) {
 		t.Errorf("incorrect uint32: %v", test)
 	}
-	if test := numberToBytes(uint64(0xF0A05020EEDDCCBB)); !bytes.Equal(test, []byte{0xF0, 0xA0, 0x50, 0x20, 0xEE, 0xDD, 0xCC, 0xBB}) {
+	if test := numberToBytes(uint64(0xF0A05020EEDDCCBB), binary.BigEndian); !bytes.Equal(test, []byte{0xF0, 0xA0, 0x50, 0x20, 0xEE, 0xDD, 0xCC, 0xBB}) {
 		t.Errorf("incorrect uint64: %v", test)
 	}
 
-	if test := numberToBytes(int8(-64)); !bytes.Equal(test, []byte{192}) {
+	if test := numberToBytes(int8(-64), binary.BigEndian); !bytes.Equal(test, []byte{192}) {
 		t.Errorf("incorrect int8: %v", test)
 	}
-	if test := numberToBytes(int16(-1000)); !bytes.Equal(test, []byte{252, 24}) {
+	if test := numberToBytes(int16(-1000), binary.BigEndian); !bytes.Equal(test, []byte{252, 24}) {
 		t.Errorf("incorrect int8: %v", test)
 	}
-	if test := numberToBytes(int32(-5000)); !bytes.Equal(test, []byte{255, 255, 236, 120}) {
+	if test := numberToBytes(int32(-5000), binary.BigEndian); !bytes.Equal(test, []byte{255, 255, 236, 120}) {
 		t.Errorf("incorrect int8: %v", test)
 	}
-	if test := numberToBytes(int64(-1_000_000)); !bytes.Equal(test, []byte{255, 255, 255, 255, 255, 240, 189, 192}) {
+	if test := numberToBytes(int64(-1_000_000), binary.BigEndian); !bytes.Equal(test, []byte{255, 255, 255, 255, 255, 240, 189, 192}) {
 		t.Errorf("incorrect int8: %v", test)
 	}
 
-	if test := numberToBytes(float32(math.E)); !bytes.Equal(test, []byte{64, 45, 248, 84}) {
+	if test := numberToBytes(float32(math.E), binary.BigEndian); !bytes.Equal(test, []byte{64, 45, 248, 84}) {
 		t.Errorf("incorrect float32: %v", test)
 	}
-	if test := numberToBytes(float64(-math.Pi * 2)); !bytes.Equal(test, []byte{192, 25, 33, 251, 84, 68, 45, 24}) {
+	if test := numberToBytes(float64(-math.Pi * 2), binary.BigEndian); !bytes.Equal(test, []byte{192, 25, 33, 251, 84, 68, 45, 24}) {
 		t.Errorf("incorrect float64: %v", test)
 	}
 }
 
+func TestNumberToBytesLittleEndian(t *testing.T) {
+	if test := numberToBytes(uint16(0xF0A0), binary.LittleEndian); !bytes.Equal(test, []byte{0xA0, 0xF0}) {
+		t.Errorf("incorrect uint16: %v", test)
+	}
+	if test := numberToBytes(int32(-5000), binary.LittleEndian); !bytes.Equal(test, []byte{120, 236, 255, 255}) {
+		t.Errorf("incorrect int32: %v", test)
+	}
+	if test := numberToBytes(float32(math.E), binary.LittleEndian); !bytes.Equal(test, []byte{84, 248, 45, 64}) {
+		t.Errorf("incorrect float32: %v", test)
+	}
+
+	value, err := bytesToNumber[uint64]([]byte{1, 0, 0, 0, 0, 0, 0, 0}, binary.LittleEndian)
+	if err != nil {
+		t.Error(err)
+	} else if value != 1 {
+		t.Errorf("incorrect uint64: %d", value)
+	}
+}
+
 func testNumber[T number](t *testing.T, num T) {
-	data := numberToBytes(num)
-	value, err := bytesToNumber[T](data)
+	data := numberToBytes(num, binary.BigEndian)
+	value, err := bytesToNumber[T](data, binary.BigEndian)
 	if err != nil {
 		t.Error(err)
 	} else if value != num {
@@ -68,11 +88,11 @@ func TestNumbers(t *testing.T) {
 }
 
 func TestSetNumberFromBytes(t *testing.T) {
-	data := numberToBytes(float32(math.Pi))
+	data := numberToBytes(float32(math.Pi), binary.BigEndian)
 
 	var target float32
 	valueOf := reflect.ValueOf(&target)
-	if err := setNumberFromBytes[float32](data, valueOf); err != nil {
+	if err := setNumberFromBytes[float32](data, binary.BigEndian, valueOf); err != nil {
 		t.Error(err)
 	} else if target != float32(math.Pi) {
 		t.Error("value to not equate")
@@ -0,0 +1,1235 @@
+package bytocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type planEntry struct {
+	FieldIndex int
+	Field      reflect.StructField
+	Type       reflect.Type
+	Order      uint
+	Size       uint
+	VarLength  bool
+	LengthBits byte
+
+	// NullTerminated indicates strings and byte slices are encoded with a
+	// trailing NUL byte instead of a length prefix.
+	NullTerminated bool
+
+	// ByteOrder is the byte order used for numbers and fixed length prefixes,
+	// defaulting to the message byte order unless overridden by the tag.
+	ByteOrder binary.ByteOrder
+
+	// Varint indicates integers are encoded as variable-length LEB128 (zigzag
+	// for signed), or for strings, slices, and maps that their length prefix is.
+	Varint bool
+
+	// Nested is the plan for struct fields, which are encoded inline in the
+	// order of their own fields.
+	Nested *TypePlan
+
+	// Elem is the plan for each element of slices and arrays, and the values of
+	// maps. Slices and maps are prefixed with the element count using the
+	// LengthBits, arrays have a fixed length and are not prefixed.
+	Elem *planEntry
+
+	// Key is the plan for the keys of maps.
+	Key *planEntry
+
+	// Optional indicates pointer fields, which are encoded with a presence byte
+	// followed by the Elem value when non-nil.
+	Optional bool
+
+	// Codec encodes types the planner does not know natively, either registered
+	// with [RegisterCodec] or implementing [FieldMarshaler] or
+	// [encoding.BinaryMarshaler]. Variable size codecs are length-prefixed.
+	Codec Codec
+
+	// RegisteredCodec indicates the Codec was registered with [RegisterCodec].
+	RegisteredCodec bool
+
+	// Unit is the precision of [time.Time] and [time.Duration] entries, which
+	// are encoded as signed integers of the unit. It is 0 for other types.
+	Unit time.Duration
+
+	// TimeZone indicates times are followed by their zone offset in seconds.
+	TimeZone bool
+
+	// Max is the largest length of strings, byte slices, and variable size
+	// codecs, or count of slices and maps, accepted when decoding. It is 0 when
+	// not limited by the max option.
+	Max uint64
+}
+
+// newElemEntry creates the plan entry for elements of a slice or array, or the
+// keys and values of a map. The elements use the default encoding options for
+// their type.
+func newElemEntry(typeOf reflect.Type, order binary.ByteOrder, stack []reflect.Type) (*planEntry, error) {
+	elem := &planEntry{
+		FieldIndex: -1,
+		Type:       typeOf,
+		LengthBits: 64,
+		ByteOrder:  order,
+	}
+
+	if err := elem.plan(fieldTag{}, stack); err != nil {
+		return nil, err
+	}
+	return elem, nil
+}
+
+// label returns the short description of the entry. Field entries include the
+// order and field name, while element entries only describe the type.
+func (pe planEntry) label() string {
+	var str strings.Builder
+
+	if pe.FieldIndex >= 0 {
+		str.WriteString(strconv.FormatUint(uint64(pe.Order), 10))
+		str.WriteByte(' ')
+		str.WriteString(pe.Field.Name)
+		str.WriteByte(' ')
+	}
+	str.WriteString(pe.Type.String())
+	str.WriteByte(' ')
+	str.WriteString(strconv.FormatUint(uint64(pe.Size), 10))
+	if pe.VarLength {
+		str.WriteByte('+')
+	}
+	if pe.Unit != 0 {
+		str.WriteByte(' ')
+		str.WriteString(unitName(pe.Unit))
+	}
+	if pe.TimeZone {
+		str.WriteString(" tz")
+	}
+	if pe.Max != 0 {
+		str.WriteString(" max=")
+		str.WriteString(strconv.FormatUint(pe.Max, 10))
+	}
+	if pe.ByteOrder == binary.LittleEndian && pe.usesByteOrder() {
+		str.WriteString(" le")
+	}
+
+	return str.String()
+}
+
+// usesByteOrder returns true if the encoding of this entry itself depends on
+// the byte order, being a multi-byte number or having a fixed length prefix
+// larger than a byte.
+func (pe planEntry) usesByteOrder() bool {
+	if pe.Unit != 0 {
+		return !pe.Varint
+	} else if pe.Varint || pe.NullTerminated {
+		return false
+	} else if pe.Codec != nil {
+		return pe.Codec.Size() == 0 && pe.LengthBits > 8
+	}
+
+	switch pe.Type.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return pe.LengthBits > 8
+	case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint,
+		reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func (pe planEntry) String() string {
+	var str strings.Builder
+
+	str.WriteString(pe.label())
+
+	if pe.Key != nil {
+		str.WriteString(" [ ")
+		str.WriteString(pe.Key.String())
+		str.WriteString(" => ")
+		str.WriteString(pe.Elem.String())
+		str.WriteString(" ]")
+	} else if pe.Optional {
+		str.WriteString(" ? ")
+		str.WriteString(pe.Elem.String())
+	} else if pe.Elem != nil {
+		str.WriteString(" [ ")
+		str.WriteString(pe.Elem.String())
+		str.WriteString(" ]")
+	}
+
+	if pe.Nested != nil {
+		str.WriteString(" {")
+		for i, entry := range pe.Nested.entries {
+			if i > 0 {
+				str.WriteString(" |")
+			}
+			str.WriteByte(' ')
+			str.WriteString(entry.String())
+		}
+		str.WriteString(" }")
+	}
+
+	return str.String()
+}
+
+// plan sets the encoding size and options for the entry based on the entry
+// type and the parsed field tag. The stack holds the struct types currently
+// being planned to catch recursive types.
+func (pe *planEntry) plan(tag fieldTag, stack []reflect.Type) error {
+	var err error
+
+	// The tag overrides the inherited byte order for this entry and everything
+	// contained within it
+	if tag.ByteOrder != nil {
+		pe.ByteOrder = tag.ByteOrder
+	}
+
+	// Codecs take precedence over the built-in encodings, although pointers
+	// remain optional values of the codec type
+	if kind := pe.Type.Kind(); kind != reflect.Pointer && kind != reflect.Interface {
+		codec, ok := registeredCodec(pe.Type)
+		pe.RegisteredCodec = ok
+		if !ok {
+			if codec, ok, err = newFieldMarshalerCodec(pe.Type); err != nil {
+				return err
+			}
+		}
+		if ok {
+			return pe.planCodec(codec, tag)
+		}
+	}
+
+	// Times and durations take precedence over the binary marshaling of times
+	if pe.Type == timeType || pe.Type == durationType {
+		return pe.planTime(tag)
+	}
+
+	// Integers can be encoded with a variable-length instead of fixed size
+	if tag.Varint && isIntegerKind(pe.Type.Kind()) {
+		if tag.Max != 0 {
+			return fmt.Errorf("bytocol: max is not supported on field %s of type %s", pe.Field.Name, pe.Type.String())
+		}
+		pe.Varint = true
+		pe.Size = 1
+		pe.VarLength = true
+		return nil
+	}
+
+	switch pe.Type.Kind() {
+	case reflect.Bool, reflect.Uint8, reflect.Int8:
+		pe.Size = 1
+	case reflect.Uint16, reflect.Int16:
+		pe.Size = 2
+	case reflect.Uint32, reflect.Int32, reflect.Float32:
+		pe.Size = 4
+	case reflect.Uint64, reflect.Uint, reflect.Int64, reflect.Int, reflect.Float64:
+		pe.Size = 8
+	case reflect.String:
+		pe.planBlob(tag)
+	case reflect.Slice:
+		if pe.Type.Elem().Kind() == reflect.Uint8 {
+			pe.planBlob(tag)
+			break
+		}
+
+		if tag.NullTerminated {
+			err = fmt.Errorf("bytocol: null-terminated is not supported on slice type %s", pe.Type.String())
+			break
+		}
+
+		// Count prefixed, the minimum size is the prefix only
+		pe.planPrefix(tag)
+		pe.Elem, err = newElemEntry(pe.Type.Elem(), pe.ByteOrder, stack)
+	case reflect.Map:
+		if !isMapKeyKind(pe.Type.Key().Kind()) {
+			err = fmt.Errorf("bytocol: unsupported map key type %s", pe.Type.Key().String())
+			break
+		} else if tag.NullTerminated {
+			err = fmt.Errorf("bytocol: null-terminated is not supported on map type %s", pe.Type.String())
+			break
+		}
+
+		// Count prefixed, the minimum size is the prefix only
+		pe.planPrefix(tag)
+		if pe.Key, err = newElemEntry(pe.Type.Key(), pe.ByteOrder, stack); err == nil {
+			pe.Elem, err = newElemEntry(pe.Type.Elem(), pe.ByteOrder, stack)
+		}
+	case reflect.Array:
+		pe.Elem, err = newElemEntry(pe.Type.Elem(), pe.ByteOrder, stack)
+		if err == nil {
+			pe.Size = uint(pe.Type.Len()) * pe.Elem.Size
+			pe.VarLength = pe.Elem.VarLength
+		}
+	case reflect.Pointer:
+		// Presence byte followed by the value, the tag options apply to the
+		// value being pointed to
+		pe.Optional = true
+		pe.Elem = &planEntry{
+			FieldIndex: -1,
+			Type:       pe.Type.Elem(),
+			LengthBits: 64,
+			ByteOrder:  pe.ByteOrder,
+		}
+		err = pe.Elem.plan(tag, stack)
+		pe.Size = 1
+		pe.VarLength = true
+	case reflect.Struct:
+		// Structs without tags may still encode themselves
+		if !hasTaggedFields(pe.Type) {
+			if codec, ok := newBinaryMarshalerCodec(pe.Type); ok {
+				return pe.planCodec(codec, tag)
+			}
+		}
+
+		if slices.Contains(stack, pe.Type) {
+			err = fmt.Errorf("bytocol: recursive type %s is not supported", pe.Type.String())
+			break
+		}
+
+		pe.Nested = &TypePlan{byteOrder: pe.ByteOrder}
+		err = pe.Nested.planType(pe.Type, stack)
+		if err == nil && !pe.Nested.IsValid() {
+			err = fmt.Errorf("bytocol: nested struct %s has no exported fields", pe.Type.String())
+		}
+		pe.Size = pe.Nested.size
+		pe.VarLength = pe.Nested.varLength
+	default:
+		if codec, ok := newBinaryMarshalerCodec(pe.Type); ok && pe.Type.Kind() != reflect.Interface {
+			return pe.planCodec(codec, tag)
+		}
+		err = fmt.Errorf("bytocol: unsupported encode type %s", pe.Type.String())
+	}
+
+	switch pe.Type.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Pointer:
+		// Length options were applied above
+	default:
+		if err == nil && (tag.StringLengthPrefix || tag.NullTerminated || tag.Varint || tag.Max != 0) {
+			err = fmt.Errorf("bytocol: length options are not supported on field %s of type %s", pe.Field.Name, pe.Type.String())
+		}
+	}
+
+	// Pointers pass the time options on to the value being pointed to
+	if err == nil && !pe.Optional && (tag.Unit != 0 || tag.TimeZone) {
+		err = fmt.Errorf("bytocol: time options are not supported on field %s of type %s", pe.Field.Name, pe.Type.String())
+	}
+
+	return err
+}
+
+// planTime sets the encoding options for times and durations, a 64-bit or
+// variable-length integer of the unit. Zoned times are followed by a 32-bit or
+// variable-length offset.
+func (pe *planEntry) planTime(tag fieldTag) error {
+	if tag.StringLengthPrefix || tag.NullTerminated || tag.Max != 0 {
+		return fmt.Errorf("bytocol: length options are not supported on field %s of type %s", pe.Field.Name, pe.Type.String())
+	} else if tag.TimeZone && pe.Type != timeType {
+		return fmt.Errorf("bytocol: tz is not supported on field %s of type %s", pe.Field.Name, pe.Type.String())
+	}
+
+	pe.Unit = time.Nanosecond
+	if tag.Unit != 0 {
+		pe.Unit = tag.Unit
+	}
+	pe.TimeZone = tag.TimeZone
+
+	size := uint(8)
+	if tag.Varint {
+		pe.Varint = true
+		pe.VarLength = true
+		size = 1
+	}
+
+	pe.Size = size
+	if pe.TimeZone {
+		pe.Size += min(size, 4)
+	}
+	return nil
+}
+
+// planCodec sets the encoding options for types using a [Codec]. Fixed size
+// codecs do not accept length options, while variable size ones are prefixed
+// like byte slices.
+func (pe *planEntry) planCodec(codec Codec, tag fieldTag) error {
+	pe.Codec = codec
+
+	if size := codec.Size(); size > 0 {
+		if tag.StringLengthPrefix || tag.NullTerminated || tag.Varint || tag.Max != 0 {
+			return fmt.Errorf("bytocol: length options are not supported on fixed size codec type %s", pe.Type.String())
+		}
+		pe.Size = uint(size)
+		return nil
+	}
+
+	if tag.NullTerminated {
+		return fmt.Errorf("bytocol: null-terminated is not supported on codec type %s", pe.Type.String())
+	}
+	pe.planPrefix(tag)
+	return nil
+}
+
+// usesRegisteredCodec returns true if this entry, or anything contained within
+// it, uses a codec registered with [RegisterCodec].
+func (pe planEntry) usesRegisteredCodec() bool {
+	switch {
+	case pe.RegisteredCodec:
+		return true
+	case pe.Key != nil && pe.Key.usesRegisteredCodec():
+		return true
+	case pe.Elem != nil && pe.Elem.usesRegisteredCodec():
+		return true
+	case pe.Nested != nil:
+		return pe.Nested.registeredCodecs
+	}
+	return false
+}
+
+// isVarintNumber returns true for integers encoded as variable-length.
+func (pe planEntry) isVarintNumber() bool {
+	return pe.Varint && pe.Codec == nil && pe.Unit == 0 && isIntegerKind(pe.Type.Kind())
+}
+
+// planBlob sets the encoding options for strings and byte slices from the
+// field tag. Unless null-terminated, blobs default to a 64-bit length prefix.
+func (pe *planEntry) planBlob(tag fieldTag) {
+	pe.VarLength = true
+	pe.Max = tag.Max
+	if tag.NullTerminated {
+		pe.NullTerminated = true
+		pe.LengthBits = 0
+
+		// Minimum size is the terminator itself
+		pe.Size = 1
+		return
+	}
+
+	pe.planPrefix(tag)
+}
+
+// planPrefix sets the length or count prefix options from the field tag. The
+// prefix defaults to 64-bit unless the length-prefix or varint options are set.
+func (pe *planEntry) planPrefix(tag fieldTag) {
+	pe.VarLength = true
+	pe.Max = tag.Max
+	if tag.Varint {
+		pe.Varint = true
+		pe.LengthBits = 0
+
+		// Minimum size is a single byte varint
+		pe.Size = 1
+		return
+	}
+
+	if tag.StringLengthPrefix {
+		pe.LengthBits = tag.StringLengthSize
+	}
+	pe.Size = uint(pe.LengthBits / 8)
+}
+
+// countedSize returns the minimum size of each slice element, or map key and
+// value pair, counted by the prefix of this entry.
+func (pe planEntry) countedSize() uint {
+	if pe.Key != nil {
+		return pe.Key.Size + pe.Elem.Size
+	}
+	return pe.Elem.Size
+}
+
+// writePrefix encodes the length or count prefix for this entry.
+func (pe planEntry) writePrefix(length uint64, w io.Writer) error {
+	if pe.Varint {
+		return writeUvarint(length, w)
+	}
+	return writeLength(length, pe.LengthBits, pe.ByteOrder, w)
+}
+
+// readPrefix decodes the length or count prefix for this entry.
+func (pe planEntry) readPrefix(r io.Reader) (uint64, error) {
+	if pe.Varint {
+		return readUvarint(r)
+	}
+	return readLength(r, pe.LengthBits, pe.ByteOrder)
+}
+
+// explainPrefix decodes the length or count prefix for this entry from the
+// data at the offset. It returns the length, the size of the prefix, and false
+// if the prefix could not be decoded.
+func (pe planEntry) explainPrefix(data []byte, offset int) (uint64, int, bool) {
+	if offset > len(data) {
+		return 0, 0, false
+	}
+
+	if pe.Varint {
+		length, n := binary.Uvarint(data[offset:])
+		return length, n, n > 0
+	}
+
+	lenSize := int(pe.LengthBits / 8)
+	if (offset + lenSize) > len(data) {
+		return 0, 0, false
+	}
+	return bytesToLength(data[offset:offset+lenSize], pe.ByteOrder), lenSize, true
+}
+
+// writeBlob encodes the string or byte slice data with either the NUL
+// terminator or the length prefix.
+func (pe planEntry) writeBlob(data []byte, w io.Writer) error {
+	if pe.NullTerminated {
+		return writeTerminatedBlob(data, w)
+	} else if !pe.Varint {
+		return writeBlob(data, pe.LengthBits, pe.ByteOrder, w)
+	}
+
+	if err := writeUvarint(uint64(len(data)), w); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// writeValue encodes the value for this entry onto the [io.Writer].
+func (pe planEntry) writeValue(value reflect.Value, w io.Writer) error {
+	var err error
+
+	if pe.Codec != nil {
+		return pe.writeCodec(value, w)
+	} else if pe.Unit != 0 {
+		return pe.writeTime(value, w)
+	} else if pe.isVarintNumber() {
+		if isSignedKind(pe.Type.Kind()) {
+			return writeVarint(value.Int(), w)
+		}
+		return writeUvarint(value.Uint(), w)
+	}
+
+	switch pe.Type.Kind() {
+	case reflect.Bool:
+		err = writeNumber(boolToByte(value.Bool()), pe.ByteOrder, w)
+
+	case reflect.Uint8:
+		err = writeNumber(uint8(value.Uint()), pe.ByteOrder, w)
+	case reflect.Uint16:
+		err = writeNumber(uint16(value.Uint()), pe.ByteOrder, w)
+	case reflect.Uint32:
+		err = writeNumber(uint32(value.Uint()), pe.ByteOrder, w)
+	case reflect.Uint64, reflect.Uint:
+		err = writeNumber(value.Uint(), pe.ByteOrder, w)
+
+	case reflect.Int8:
+		err = writeNumber(int8(value.Int()), pe.ByteOrder, w)
+	case reflect.Int16:
+		err = writeNumber(int16(value.Int()), pe.ByteOrder, w)
+	case reflect.Int32:
+		err = writeNumber(int32(value.Int()), pe.ByteOrder, w)
+	case reflect.Int64, reflect.Int:
+		err = writeNumber(value.Int(), pe.ByteOrder, w)
+
+	case reflect.Float32:
+		err = writeNumber(float32(value.Float()), pe.ByteOrder, w)
+	case reflect.Float64:
+		err = writeNumber(value.Float(), pe.ByteOrder, w)
+
+	case reflect.String:
+		err = pe.writeBlob([]byte(value.String()), w)
+	case reflect.Slice:
+		if pe.Elem == nil {
+			// Byte slice, use the blob method
+			err = pe.writeBlob(value.Bytes(), w)
+		} else if pe.Elem.Size == 0 && value.Len() > 0 {
+			// Their count could not be told apart from a malformed one
+			err = fmt.Errorf("bytocol: slice of zero size elements %s must be empty", pe.Type.String())
+		} else if err = pe.writePrefix(uint64(value.Len()), w); err == nil {
+			err = pe.writeElems(value, w)
+		}
+	case reflect.Map:
+		if err = pe.writePrefix(uint64(value.Len()), w); err == nil {
+			err = pe.writePairs(value, w)
+		}
+	case reflect.Pointer:
+		if value.IsNil() {
+			err = writeNumber(byte(0), pe.ByteOrder, w)
+		} else if err = writeNumber(byte(1), pe.ByteOrder, w); err == nil {
+			err = pe.Elem.writeValue(value.Elem(), w)
+		}
+	case reflect.Array:
+		err = pe.writeElems(value, w)
+	case reflect.Struct:
+		err = pe.Nested.writeFields(value, w)
+	default:
+		err = fmt.Errorf("bytocol: unsupported encode type %s", pe.Type.String())
+	}
+
+	return err
+}
+
+// writeCodec encodes the value using the codec, prefixing the data with its
+// length unless the codec has a fixed size.
+func (pe planEntry) writeCodec(value reflect.Value, w io.Writer) error {
+	if !value.CanInterface() {
+		return fmt.Errorf("bytocol: cannot encode unexported value of codec type %s", pe.Type.String())
+	}
+
+	data, err := pe.Codec.Marshal(value.Interface())
+	if err != nil {
+		return err
+	}
+
+	if size := pe.Codec.Size(); size > 0 {
+		if len(data) != size {
+			return fmt.Errorf("bytocol: codec for %s encoded %d bytes, expected %d", pe.Type.String(), len(data), size)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	return pe.writeBlob(data, w)
+}
+
+// writeTime encodes the time or duration as a signed integer of the unit,
+// followed by the zone offset of zoned times.
+func (pe planEntry) writeTime(value reflect.Value, w io.Writer) error {
+	var units int64
+	var offset int
+	var err error
+
+	if pe.Type == durationType {
+		units = value.Int() / int64(pe.Unit)
+	} else if !value.CanInterface() {
+		return fmt.Errorf("bytocol: cannot encode unexported value of type %s", pe.Type.String())
+	} else {
+		t := value.Interface().(time.Time)
+		if units, err = timeToUnix(t, pe.Unit); err != nil {
+			return err
+		}
+		_, offset = t.Zone()
+	}
+
+	if pe.Varint {
+		err = writeVarint(units, w)
+	} else {
+		err = writeNumber(units, pe.ByteOrder, w)
+	}
+	if err != nil || !pe.TimeZone {
+		return err
+	}
+
+	if pe.Varint {
+		return writeVarint(int64(offset), w)
+	}
+	return writeNumber(int32(offset), pe.ByteOrder, w)
+}
+
+// writeElems encodes every element of the slice or array value in order.
+func (pe planEntry) writeElems(value reflect.Value, w io.Writer) error {
+	for i := 0; i < value.Len(); i++ {
+		if err := pe.Elem.writeValue(value.Index(i), w); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// writePairs encodes every key and value pair of the map value, ordered by the
+// keys so that the output is deterministic. NaN keys are all equal to each
+// other, so those pairs are ordered by their encoding instead.
+func (pe planEntry) writePairs(value reflect.Value, w io.Writer) error {
+	pairs := sortedMapPairs(value)
+
+	// NaN keys are sorted first
+	nans := 0
+	for nans < len(pairs) && isNaNKey(pairs[nans].key) {
+		nans++
+	}
+	if nans > 1 {
+		encoded := make([][]byte, nans)
+		for i, pair := range pairs[:nans] {
+			var buf bytes.Buffer
+			if err := pe.Key.writeValue(pair.key, &buf); err != nil {
+				return fmt.Errorf("key %v: %w", pair.key, err)
+			}
+			if err := pe.Elem.writeValue(pair.value, &buf); err != nil {
+				return fmt.Errorf("key %v: %w", pair.key, err)
+			}
+			encoded[i] = buf.Bytes()
+		}
+		slices.SortFunc(encoded, bytes.Compare)
+		for _, data := range encoded {
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		pairs = pairs[nans:]
+	}
+
+	for _, pair := range pairs {
+		if err := pe.Key.writeValue(pair.key, w); err != nil {
+			return fmt.Errorf("key %v: %w", pair.key, err)
+		}
+		if err := pe.Elem.writeValue(pair.value, w); err != nil {
+			return fmt.Errorf("key %v: %w", pair.key, err)
+		}
+	}
+	return nil
+}
+
+// readValue decodes the value for this entry from the [io.Reader] and sets it
+// on the target value, which must be settable.
+func (pe planEntry) readValue(r io.Reader, field reflect.Value) error {
+	var err error
+	excerpt := make([]byte, 8)
+
+	if pe.Codec != nil {
+		return pe.readCodec(r, field)
+	} else if pe.Unit != 0 {
+		return pe.readTime(r, field)
+	} else if pe.isVarintNumber() {
+		return pe.readVarintValue(r, field)
+	}
+
+	// Structs, and values containing elements, are nested one level deeper
+	if pe.Nested != nil || pe.Elem != nil {
+		if err = enterNested(r); err != nil {
+			return err
+		}
+		defer leaveNested(r)
+	}
+
+	switch pe.Type.Kind() {
+	case reflect.Bool:
+		// Read 1 byte
+		if err = readFull(r, excerpt[:1]); err == nil {
+			field.SetBool(excerpt[0] == 1)
+		}
+
+	case reflect.Uint8:
+		// Read 1 byte
+		if err = readFull(r, excerpt[:1]); err == nil {
+			err = setNumberFromBytes[uint8](excerpt[:1], pe.ByteOrder, field)
+		}
+	case reflect.Uint16:
+		// Read 2 bytes
+		if err = readFull(r, excerpt[:2]); err == nil {
+			err = setNumberFromBytes[uint16](excerpt[:2], pe.ByteOrder, field)
+		}
+	case reflect.Uint32:
+		// Read 4 bytes
+		if err = readFull(r, excerpt[:4]); err == nil {
+			err = setNumberFromBytes[uint32](excerpt[:4], pe.ByteOrder, field)
+		}
+	case reflect.Uint64, reflect.Uint:
+		// Read 8 bytes
+		if err = readFull(r, excerpt[:8]); err == nil {
+			err = setNumberFromBytes[uint64](excerpt[:8], pe.ByteOrder, field)
+		}
+
+	case reflect.Int8:
+		// Read 1 byte
+		if err = readFull(r, excerpt[:1]); err == nil {
+			err = setNumberFromBytes[int8](excerpt[:1], pe.ByteOrder, field)
+		}
+	case reflect.Int16:
+		// Read 2 bytes
+		if err = readFull(r, excerpt[:2]); err == nil {
+			err = setNumberFromBytes[int16](excerpt[:2], pe.ByteOrder, field)
+		}
+	case reflect.Int32:
+		// Read 4 bytes
+		if err = readFull(r, excerpt[:4]); err == nil {
+			err = setNumberFromBytes[int32](excerpt[:4], pe.ByteOrder, field)
+		}
+	case reflect.Int64, reflect.Int:
+		// Read 8 bytes
+		if err = readFull(r, excerpt[:8]); err == nil {
+			err = setNumberFromBytes[int64](excerpt[:8], pe.ByteOrder, field)
+		}
+
+	case reflect.Float32:
+		// Read 4 bytes
+		if err = readFull(r, excerpt[:4]); err == nil {
+			err = setNumberFromBytes[float32](excerpt[:4], pe.ByteOrder, field)
+		}
+	case reflect.Float64:
+		// Read 8 bytes
+		if err = readFull(r, excerpt[:8]); err == nil {
+			err = setNumberFromBytes[float64](excerpt[:8], pe.ByteOrder, field)
+		}
+
+	case reflect.String:
+		// Depending on field size, read N bytes
+		var blob []byte
+		if blob, err = pe.readBytes(r); err == nil {
+			field.SetString(string(blob))
+		}
+	case reflect.Slice:
+		if pe.Elem == nil {
+			// Byte slice, use the blob method
+			var blob []byte
+			if blob, err = pe.readBytes(r); err == nil {
+				field.SetBytes(blob)
+			}
+			break
+		}
+
+		// Count prefixed elements
+		var count int
+		if count, err = pe.readCount(r); err == nil {
+			var slice reflect.Value
+			if slice, err = pe.readSlice(r, count); err == nil {
+				field.Set(slice)
+			}
+		}
+	case reflect.Map:
+		var count int
+		if count, err = pe.readCount(r); err == nil {
+			mapValue := reflect.MakeMapWithSize(pe.Type, preallocCount(pe.Type.Elem(), count))
+			if err = pe.readPairs(r, mapValue, uint64(count)); err == nil {
+				field.Set(mapValue)
+			}
+		}
+	case reflect.Pointer:
+		// Read the presence byte, allocate only when present
+		if err = readFull(r, excerpt[:1]); err != nil {
+			break
+		}
+
+		switch excerpt[0] {
+		case 0:
+			field.SetZero()
+		case 1:
+			ptr := reflect.New(pe.Elem.Type)
+			if err = pe.Elem.readValue(r, ptr.Elem()); err == nil {
+				field.Set(ptr)
+			}
+		default:
+			err = fmt.Errorf("%w: %d", ErrInvalidPresence, excerpt[0])
+		}
+	case reflect.Array:
+		err = pe.readElems(r, field)
+	case reflect.Struct:
+		err = pe.Nested.readFields(r, field)
+	default:
+		err = fmt.Errorf("bytocol: unsupported encode type %s", pe.Type.String())
+	}
+
+	return err
+}
+
+// readCodec reads the data of the codec, either its fixed size or the length
+// prefixed bytes, and decodes it into the target value.
+func (pe planEntry) readCodec(r io.Reader, field reflect.Value) error {
+	var data []byte
+	var err error
+	if size := pe.Codec.Size(); size > 0 {
+		data = make([]byte, size)
+		err = readFull(r, data)
+	} else {
+		data, err = pe.readBytes(r)
+	}
+	if err != nil {
+		return err
+	}
+
+	target := reflect.New(pe.Type)
+	if err = pe.Codec.Unmarshal(data, target.Interface()); err != nil {
+		return err
+	}
+	field.Set(target.Elem())
+	return nil
+}
+
+// readTime decodes the time or duration from the signed integer of the unit,
+// followed by the zone offset of zoned times.
+func (pe planEntry) readTime(r io.Reader, field reflect.Value) error {
+	units, err := pe.readTimeInt(r, readNumber[int64])
+	if err != nil {
+		return err
+	}
+
+	if pe.Type == durationType {
+		duration, err := unitsToDuration(units, pe.Unit)
+		if err == nil {
+			field.SetInt(int64(duration))
+		}
+		return err
+	}
+
+	var offset int64
+	if pe.TimeZone {
+		offset, err = pe.readTimeInt(r, func(r io.Reader, order binary.ByteOrder) (int64, error) {
+			offset, err := readNumber[int32](r, order)
+			return int64(offset), err
+		})
+		if err != nil {
+			return err
+		} else if offset < math.MinInt32 || offset > math.MaxInt32 {
+			return fmt.Errorf("%w: zone offset %d does not fit in int32", ErrVarintOverflow, offset)
+		}
+	}
+
+	field.Set(reflect.ValueOf(unixToTime(units, pe.Unit, int(offset))))
+	return nil
+}
+
+// readTimeInt reads a signed integer of a time entry, either variable-length
+// or fixed size using the read function.
+func (pe planEntry) readTimeInt(r io.Reader, read func(io.Reader, binary.ByteOrder) (int64, error)) (int64, error) {
+	if pe.Varint {
+		return readVarint(r)
+	}
+	return read(r, pe.ByteOrder)
+}
+
+// readElems decodes every element of the array value in order.
+func (pe planEntry) readElems(r io.Reader, value reflect.Value) error {
+	for i := 0; i < value.Len(); i++ {
+		offset := readOffset(r)
+		if err := pe.Elem.readValue(r, value.Index(i)); err != nil {
+			return prefixDecodeError(fmt.Sprintf("[%d]", i), offset, err)
+		}
+	}
+	return nil
+}
+
+// readSlice decodes the count elements into a new slice. The slice grows as the
+// elements are read rather than being allocated for the count upfront.
+func (pe planEntry) readSlice(r io.Reader, count int) (reflect.Value, error) {
+	slice := reflect.New(pe.Type).Elem()
+	slice.Set(reflect.MakeSlice(pe.Type, 0, preallocCount(pe.Type.Elem(), count)))
+
+	for i := 0; i < count; i++ {
+		if i == slice.Cap() {
+			slice.Grow(max(min(count-i, i), 1))
+		}
+		slice.SetLen(i + 1)
+
+		offset := readOffset(r)
+		if err := pe.Elem.readValue(r, slice.Index(i)); err != nil {
+			return slice, prefixDecodeError(fmt.Sprintf("[%d]", i), offset, err)
+		}
+	}
+	return slice, nil
+}
+
+// readVarintValue decodes a variable-length integer and sets it on the target
+// value. If the decoded value does not fit in the target type an
+// [ErrVarintOverflow] error is returned.
+func (pe planEntry) readVarintValue(r io.Reader, field reflect.Value) error {
+	if isSignedKind(pe.Type.Kind()) {
+		value, err := readVarint(r)
+		if err != nil {
+			return err
+		} else if field.OverflowInt(value) {
+			return fmt.Errorf("%w: %d does not fit in %s", ErrVarintOverflow, value, pe.Type.String())
+		}
+		field.SetInt(value)
+		return nil
+	}
+
+	value, err := readUvarint(r)
+	if err != nil {
+		return err
+	} else if field.OverflowUint(value) {
+		return fmt.Errorf("%w: %d does not fit in %s", ErrVarintOverflow, value, pe.Type.String())
+	}
+	field.SetUint(value)
+	return nil
+}
+
+// readPairs decodes the number of key and value pairs into the map value.
+func (pe planEntry) readPairs(r io.Reader, mapValue reflect.Value, count uint64) error {
+	for i := uint64(0); i < count; i++ {
+		offset := readOffset(r)
+		key := reflect.New(pe.Key.Type).Elem()
+		if err := pe.Key.readValue(r, key); err != nil {
+			return prefixDecodeError(fmt.Sprintf("[%d]", i), offset, err)
+		}
+
+		offset = readOffset(r)
+		value := reflect.New(pe.Elem.Type).Elem()
+		if err := pe.Elem.readValue(r, value); err != nil {
+			return prefixDecodeError(fmt.Sprintf("[%v]", key), offset, err)
+		}
+		mapValue.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// readBytes decodes the string or byte slice data, either NUL terminated or
+// length prefixed. The length is checked against the limits before anything is
+// allocated.
+func (pe planEntry) readBytes(r io.Reader) ([]byte, error) {
+	if pe.NullTerminated {
+		return readTerminatedBytes(r, lowestLimit(pe.Max, DecodeLimitsOf(r).MaxBlobSize))
+	}
+
+	// Read the unsigned integer length prefix
+	contentSize, err := pe.readPrefix(r)
+	if err != nil {
+		return nil, err
+	} else if err = pe.checkBlob(r, contentSize); err != nil {
+		return nil, err
+	}
+
+	// Read the remaining content based on content size
+	return readGrowing(r, contentSize)
+}
+
+// readTerminatedBytes reads byte-by-byte until the NUL terminator is found, the
+// terminator is consumed but not included in the result. Content longer than
+// the limit fails with [ErrLimitExceeded], zero not being limited.
+func readTerminatedBytes(r io.Reader, limit uint64) ([]byte, error) {
+	content := make([]byte, 0)
+	excerpt := make([]byte, 1)
+	for {
+		if err := readFull(r, excerpt); err != nil {
+			return content, err
+		}
+
+		if excerpt[0] == 0 {
+			return content, nil
+		} else if err := checkLimit("length", uint64(len(content)+1), limit); err != nil {
+			return content, err
+		}
+		content = append(content, excerpt[0])
+	}
+}
+
+// explain writes the human-readable breakdown of the bytes for this entry
+// starting at the offset, using the label to describe it. It returns the new
+// offset after this entry, and false if the data could not be explained any
+// further.
+func (pe planEntry) explain(data []byte, offset int, indent string, label string, str *strings.Builder) (int, bool) {
+	str.WriteString(indent)
+	str.WriteString(label)
+
+	// Nested structs list their own fields on the following lines
+	if pe.Nested != nil {
+		str.WriteString(" {\n")
+		offset, ok := pe.Nested.explainFields(data, offset, indent+"  ", str)
+		if ok {
+			str.WriteString(indent)
+			str.WriteString("}\n")
+		}
+		return offset, ok
+	}
+
+	str.WriteString(" = ")
+
+	// Optional values print their presence, followed by the value itself
+	if pe.Optional {
+		if offset >= len(data) {
+			str.WriteString("DATA OVERFLOW")
+			return offset, false
+		}
+
+		present := data[offset]
+		offset++
+
+		str.WriteString("Present=")
+		str.WriteString(strconv.FormatUint(uint64(present), 10))
+		str.WriteByte('\n')
+		if present == 0 {
+			return offset, true
+		}
+		return pe.Elem.explain(data, offset, indent+"  ", pe.Elem.label(), str)
+	}
+
+	// Slices, arrays and maps list their elements on the following lines
+	if pe.Elem != nil {
+		count := uint64(0)
+		if pe.Type.Kind() == reflect.Array {
+			count = uint64(pe.Type.Len())
+		} else {
+			var lenSize int
+			var ok bool
+			if count, lenSize, ok = pe.explainPrefix(data, offset); !ok {
+				str.WriteString("DATA OVERFLOW")
+				return offset, false
+			}
+			offset += lenSize
+		}
+
+		str.WriteString("Count=")
+		str.WriteString(strconv.FormatUint(count, 10))
+
+		// Protect against counts that cannot possibly fit in the data
+		elemSize := pe.countedSize()
+		if elemSize == 0 && count > 0 || elemSize > 0 && count > uint64(len(data)-offset)/uint64(elemSize) {
+			str.WriteString(", DATA OVERFLOW")
+			return offset, false
+		}
+		str.WriteByte('\n')
+
+		ok := true
+		elemLabel := pe.Elem.label()
+		for i := uint64(0); i < count && ok; i++ {
+			if pe.Key == nil {
+				offset, ok = pe.Elem.explain(data, offset, indent+"  ", fmt.Sprintf("[%d] %s", i, elemLabel), str)
+				continue
+			}
+
+			offset, ok = pe.Key.explain(data, offset, indent+"  ", fmt.Sprintf("[%d] key %s", i, pe.Key.label()), str)
+			if ok {
+				offset, ok = pe.Elem.explain(data, offset, indent+"  ", fmt.Sprintf("[%d] value %s", i, elemLabel), str)
+			}
+		}
+		return offset, ok
+	}
+
+	// Times and durations print their bytes followed by the readable value
+	if pe.Unit != 0 {
+		return pe.explainTime(data, offset, str)
+	}
+
+	byteLength := int(pe.Size)
+
+	// Check if this is a variable length entry
+	if pe.VarLength && pe.NullTerminated {
+		// Find the terminator, the content does not include it
+		terminator := bytes.IndexByte(data[min(offset, len(data)):], 0)
+		if terminator == -1 {
+			str.WriteString("MISSING TERMINATOR")
+			return offset, false
+		}
+
+		str.WriteString("Length=")
+		str.WriteString(strconv.Itoa(terminator))
+		str.WriteString(", ")
+
+		// Include the terminator in the printed bytes
+		byteLength = terminator + 1
+	} else if pe.isVarintNumber() {
+		// The varint is printed as bytes followed by the decoded value
+		_, n := binary.Uvarint(data[min(offset, len(data)):])
+		if n <= 0 {
+			str.WriteString("DATA OVERFLOW")
+			return offset, false
+		}
+		byteLength = n
+	} else if pe.VarLength {
+		// Decode the length prefix, protecting against overflow
+		length, lenSize, ok := pe.explainPrefix(data, offset)
+		if !ok {
+			str.WriteString("DATA OVERFLOW")
+			return offset, false
+		}
+		offset += lenSize
+
+		// Print the length first
+		str.WriteString("Length=")
+		str.WriteString(strconv.FormatUint(length, 10))
+		str.WriteString(", ")
+
+		// Lengths past the end of the data cannot be sliced
+		if length > uint64(len(data)-offset) {
+			str.WriteString("DATA OVERFLOW")
+			return offset, false
+		}
+		byteLength = int(length)
+	}
+
+	// Print out the bytes
+	maxOffset := (offset + byteLength)
+	for j := offset; j < maxOffset; j++ {
+		if j >= len(data) {
+			str.WriteString("EOD")
+			break
+		}
+
+		str.WriteString(fmt.Sprintf("%03d", data[j]))
+		if j < maxOffset-1 {
+			str.WriteByte(' ')
+		}
+	}
+
+	// If it was a varint print the decoded value
+	if pe.isVarintNumber() {
+		value, _ := binary.Uvarint(data[offset:])
+		str.WriteString(" (")
+		if isSignedKind(pe.Type.Kind()) {
+			str.WriteString(strconv.FormatInt(int64(value>>1)^-int64(value&1), 10))
+		} else {
+			str.WriteString(strconv.FormatUint(value, 10))
+		}
+		str.WriteByte(')')
+	}
+
+	// If it was a string print it now
+	if pe.Type.Kind() == reflect.String && pe.Codec == nil {
+		content := data[min(offset, len(data)):min(offset+byteLength, len(data))]
+		if pe.NullTerminated {
+			content = bytes.TrimSuffix(content, []byte{0})
+		}
+
+		str.WriteString(` "`)
+		str.WriteString(string(content))
+		str.WriteByte('"')
+	}
+
+	str.WriteByte('\n')
+
+	return offset + byteLength, true
+}
+
+// explainTime writes the bytes of the time or duration entry, followed by its
+// value as a duration or an RFC 3339 time.
+func (pe planEntry) explainTime(data []byte, offset int, str *strings.Builder) (int, bool) {
+	units, end, ok := pe.explainTimeInt(data, offset, 8)
+
+	var zone int64
+	if ok && pe.TimeZone {
+		zone, end, ok = pe.explainTimeInt(data, end, 4)
+	}
+	if !ok {
+		str.WriteString("DATA OVERFLOW")
+		return offset, false
+	}
+
+	for j := offset; j < end; j++ {
+		str.WriteString(fmt.Sprintf("%03d", data[j]))
+		if j < end-1 {
+			str.WriteByte(' ')
+		}
+	}
+
+	str.WriteString(" (")
+	if pe.Type == durationType {
+		str.WriteString((time.Duration(units) * pe.Unit).String())
+	} else {
+		str.WriteString(unixToTime(units, pe.Unit, int(zone)).Format(time.RFC3339Nano))
+	}
+	str.WriteString(")\n")
+
+	return end, true
+}
+
+// explainTimeInt decodes a signed integer of a time entry at the offset, either
+// variable-length or of the fixed size in bytes. It returns the offset after
+// the integer, and false if the data is too short.
+func (pe planEntry) explainTimeInt(data []byte, offset int, size int) (int64, int, bool) {
+	if offset > len(data) {
+		return 0, offset, false
+	}
+
+	if pe.Varint {
+		value, n := binary.Varint(data[offset:])
+		return value, offset + n, n > 0
+	}
+
+	if offset+size > len(data) {
+		return 0, offset, false
+	}
+	value := bytesToLength(data[offset:offset+size], pe.ByteOrder)
+	if size == 4 {
+		return int64(int32(value)), offset + size, true
+	}
+	return int64(value), offset + size, true
+}
@@ -3,6 +3,7 @@ package bytocol
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -11,63 +12,6 @@ import (
 	"strings"
 )
 
-type planEntry struct {
-	FieldIndex int
-	Field      reflect.StructField
-	Order      uint
-	Size       uint
-	VarLength  bool
-	LengthBits byte
-}
-
-func (pe planEntry) String() string {
-	var str strings.Builder
-
-	str.WriteString(strconv.FormatUint(uint64(pe.Order), 10))
-	str.WriteByte(' ')
-	str.WriteString(pe.Field.Name)
-	str.WriteByte(' ')
-	str.WriteString(pe.Field.Type.String())
-	str.WriteByte(' ')
-	str.WriteString(strconv.FormatUint(uint64(pe.Size), 10))
-	if pe.VarLength {
-		str.WriteByte('+')
-	}
-
-	return str.String()
-}
-
-func (pe planEntry) readBytes(r io.Reader) ([]byte, error) {
-	// Read the unsigned integer length prefix
-	lenSize := int(pe.LengthBits / 8)
-	lenBuf := make([]byte, lenSize)
-	_, err := r.Read(lenBuf)
-	if err != nil {
-		return nil, err
-	}
-
-	// Convert read bytes into proper integer size
-	var contentSize uint64
-	switch lenSize {
-	case 1:
-		contentSize = uint64(lenBuf[0])
-	case 2:
-		contentSize = uint64(binary.BigEndian.Uint16(lenBuf))
-	case 4:
-		contentSize = uint64(binary.BigEndian.Uint32(lenBuf))
-	case 8:
-		contentSize = binary.BigEndian.Uint64(lenBuf)
-	}
-
-	// Read the remaining content based on content size
-	contentBuffer := make([]byte, contentSize)
-	n, err := r.Read(contentBuffer)
-	if err == nil && n != len(contentBuffer) {
-		return contentBuffer, ErrReadInvariance
-	}
-	return contentBuffer, err
-}
-
 // TypePlan is a cached plan for how to encode/decode a given Message type.
 type TypePlan struct {
 	typeOf        reflect.Type
@@ -76,6 +20,13 @@ type TypePlan struct {
 	entries       []planEntry
 	size          uint
 	varLength     bool
+	byteOrder     binary.ByteOrder
+	checksum      Checksum
+	limits        DecodeLimits
+
+	// registeredCodecs indicates an entry uses a codec registered with
+	// [RegisterCodec], which generated encoders do not know about.
+	registeredCodecs bool
 }
 
 // IsValid returns true if this [TypePlan] is considered valid. It is valid if
@@ -110,6 +61,11 @@ func (ep TypePlan) String() string {
 		str.WriteString(strconv.FormatUint(uint64(ep.size), 10))
 	}
 
+	if ep.checksum != ChecksumNone {
+		str.WriteString(", checksum=")
+		str.WriteString(ep.checksum.String())
+	}
+
 	str.WriteString("):")
 	str.WriteByte('\n')
 
@@ -145,7 +101,25 @@ func (ep TypePlan) Name() string {
 	return ep.debugName
 }
 
-// Size returns the total byte size of a message encoded.
+// ByteOrder returns the default byte order used for the numbers and length
+// prefixes of the message. Individual fields may override it.
+func (ep TypePlan) ByteOrder() binary.ByteOrder {
+	return ep.byteOrder
+}
+
+// Checksum returns the checksum appended to the message as a trailer.
+func (ep TypePlan) Checksum() Checksum {
+	return ep.checksum
+}
+
+// Limits returns the limits declared by the message, which [TypePlan.Read]
+// enforces.
+func (ep TypePlan) Limits() DecodeLimits {
+	return ep.limits
+}
+
+// Size returns the total byte size of a message encoded, including the
+// checksum trailer.
 func (ep TypePlan) Size() uint {
 	return ep.size
 }
@@ -154,7 +128,8 @@ func (ep TypePlan) Size() uint {
 // against the debug string diagram of the plan. It returns a new human-readable
 // explanation as a string that can be printed.
 func (ep TypePlan) Explain(data []byte) string {
-	if len(data) < int(ep.size) {
+	// The type indicator is always needed, even when the fields have no size
+	if len(data) == 0 || len(data) < int(ep.size) {
 		return fmt.Sprintf("Invalid data size %d compared to minimum size %d", len(data), ep.size)
 	}
 
@@ -170,104 +145,92 @@ func (ep TypePlan) Explain(data []byte) string {
 
 	// Break down the bytes into their groups, start at 1 because
 	// the first is the type indicator
-	offset := 1
-	for _, entry := range ep.entries {
-		str.WriteString(entry.String())
-		str.WriteString(" = ")
-
-		byteLength := int(entry.Size)
-
-		// Check if this is a variable length entry
-		if entry.VarLength {
-			// Protect against overflow
-			if (offset + byteLength) >= len(data) {
-				str.WriteString("DATA OVERFLOW")
-				break
-			}
-
-			// The size indicates the length prefix, decode that now
-			var length uint64
-			if entry.Size == 1 {
-				length = uint64(data[offset])
-				offset++
-			} else if entry.Size == 2 {
-				raw, err := bytesToNumber[uint16](data[offset : offset+2])
-				if err != nil {
-					str.WriteString("ERROR PARSING LENGTH")
-				} else {
-					length = uint64(raw)
-				}
-				offset += 2
-			} else if entry.Size == 4 {
-				raw, err := bytesToNumber[uint32](data[offset : offset+4])
-				if err != nil {
-					str.WriteString("ERROR PARSING LENGTH")
-				} else {
-					length = uint64(raw)
-				}
-				offset += 4
-			} else if entry.Size == 8 {
-				raw, err := bytesToNumber[uint64](data[offset : offset+8])
-				if err != nil {
-					str.WriteString("ERROR PARSING LENGTH")
-				} else {
-					length = uint64(raw)
-				}
-				offset += 8
-			}
-
-			// Print the length first
-			str.WriteString("Length=")
-			str.WriteString(strconv.FormatUint(length, 10))
-			str.WriteString(", ")
+	offset, ok := ep.explainFields(data, 1, "", &str)
+	if ok && ep.checksum != ChecksumNone {
+		offset, ok = ep.explainChecksum(data, offset, &str)
+	}
 
-			byteLength = int(length)
+	switch {
+	case !ok:
+		// The entry failing to explain ends its line early
+		if !strings.HasSuffix(str.String(), "\n") {
+			str.WriteByte('\n')
 		}
+		str.WriteString(fmt.Sprintf("Data overflow at offset %d, total length %d bytes", offset, len(data)))
+	case offset < len(data):
+		str.WriteString(fmt.Sprintf("\n%d bytes remaining", len(data)-offset))
+	default:
+		str.WriteString(fmt.Sprintf("All bytes accounted for, total length %d bytes", offset))
+	}
 
-		// Print out the bytes
-		maxOffset := (offset + byteLength)
-		for j := offset; j < maxOffset; j++ {
-			if j >= len(data) {
-				str.WriteString("EOD")
-				break
-			}
+	return str.String()
+}
 
-			str.WriteString(fmt.Sprintf("%03d", data[j]))
-			if j < maxOffset-1 {
-				str.WriteByte(' ')
-			}
-		}
+// explainChecksum writes the checksum trailer at the offset and whether it
+// matches the bytes before it. It returns the new offset, and false if the
+// trailer is missing.
+func (ep TypePlan) explainChecksum(data []byte, offset int, str *strings.Builder) (int, bool) {
+	str.WriteString("Checksum ")
+	str.WriteString(ep.checksum.String())
+	str.WriteString(" = ")
+
+	end := offset + ep.checksum.Size()
+	if end > len(data) {
+		str.WriteString("DATA OVERFLOW")
+		return offset, false
+	}
 
-		// If it was a string print it now
-		if entry.Field.Type.Kind() == reflect.String {
-			str.WriteString(` "`)
-			str.WriteString(string(data[offset : offset+byteLength]))
-			str.WriteByte('"')
+	for j := offset; j < end; j++ {
+		str.WriteString(fmt.Sprintf("%03d", data[j]))
+		if j < end-1 {
+			str.WriteByte(' ')
 		}
-
-		offset += byteLength
-
-		str.WriteByte('\n')
 	}
 
-	if offset < len(data) {
-		str.WriteString(fmt.Sprintf("\n%d bytes remaining", len(data)-offset))
+	received := bytesToLength(data[offset:end], ep.byteOrder)
+	if computed := ep.checksum.compute(data[:offset]); computed == received {
+		str.WriteString(" (valid)\n")
 	} else {
-		str.WriteString(fmt.Sprintf("All bytes accounted for, total length %d bytes", offset))
+		str.WriteString(fmt.Sprintf(" (MISMATCH, computed %#x)\n", computed))
 	}
+	return end, true
+}
 
-	return str.String()
+// explainFields writes the explanation of every entry in the plan starting
+// at the offset. It returns the new offset, and false if the data could not be
+// explained any further.
+func (ep TypePlan) explainFields(data []byte, offset int, indent string, str *strings.Builder) (int, bool) {
+	ok := true
+	for _, entry := range ep.entries {
+		offset, ok = entry.explain(data, offset, indent, entry.label(), str)
+		if !ok {
+			break
+		}
+	}
+	return offset, ok
 }
 
 // fillTopLevel grabs the [MessageInfo] data from the [Message] interface
 // for the object provided. If the object does not implement the interface,
 // an [ErrNonMessageType] error is returned.
 func (ep *TypePlan) fillTopLevel(obj any) error {
+	// A nil pointer would panic on value receivers, so ask a fresh value
+	// instead. This is what zero values of pointer types look like.
+	if valueOf := reflect.ValueOf(obj); valueOf.Kind() == reflect.Pointer && valueOf.IsNil() {
+		obj = reflect.New(valueOf.Type().Elem()).Interface()
+	}
+
 	// Extract the top-level information
 	if asMessage, ok := obj.(Message); ok {
 		msgInfo := asMessage.BytocolMessage()
 		ep.typeIndicator = msgInfo.TypeIndicator
 		ep.debugName = msgInfo.DebugName
+		ep.byteOrder = msgInfo.ByteOrder
+		ep.checksum = msgInfo.Checksum
+		ep.limits = msgInfo.Limits
+		if ep.checksum.Size() == 0 && ep.checksum != ChecksumNone {
+			return fmt.Errorf("bytocol: unknown checksum %d for %s", ep.checksum, ep.debugName)
+		}
 	} else {
 		return ErrNonMessageType
 	}
@@ -277,9 +240,20 @@ func (ep *TypePlan) fillTopLevel(obj any) error {
 // planObject internally sets all the variables for this [TypePlan] based on
 // the object provided. The object can be a zero value.
 func (ep *TypePlan) planObject(obj any) error {
+	typeOf := reflect.TypeOf(obj)
+	if typeOf == nil {
+		return ErrNonStruct
+	}
+	return ep.planType(typeOf, nil)
+}
+
+// planType internally sets all the variables for this [TypePlan] based on
+// the type provided. Pointers are indirected to their element type. The stack
+// holds the parent struct types currently being planned.
+func (ep *TypePlan) planType(typeOf reflect.Type, stack []reflect.Type) error {
 	ep.entries = make([]planEntry, 0)
 
-	ep.typeOf = reflect.TypeOf(obj)
+	ep.typeOf = typeOf
 	if ep.typeOf.Kind() == reflect.Pointer {
 		// Indirect pointer to type
 		ep.typeOf = ep.typeOf.Elem()
@@ -290,6 +264,12 @@ func (ep *TypePlan) planObject(obj any) error {
 	}
 
 	ep.size = 0
+	stack = append(stack, ep.typeOf)
+
+	// Big-Endian unless the message declared otherwise
+	if ep.byteOrder == nil {
+		ep.byteOrder = binary.BigEndian
+	}
 
 	// Iterate over all the fields and save them to the plan entries
 	var entry planEntry
@@ -298,9 +278,14 @@ func (ep *TypePlan) planObject(obj any) error {
 			FieldIndex: i,
 			Field:      ep.typeOf.Field(i),
 			LengthBits: 64,
+			ByteOrder:  ep.byteOrder,
 		}
+		entry.Type = entry.Field.Type
 
-		if !entry.Field.IsExported() {
+		// Embedded structs of unexported types still promote their exported
+		// fields, so those are allowed
+		embeddedStruct := entry.Field.Anonymous && entry.Field.Type.Kind() == reflect.Struct
+		if !entry.Field.IsExported() && !embeddedStruct {
 			continue
 		}
 
@@ -318,37 +303,12 @@ func (ep *TypePlan) planObject(obj any) error {
 		entry.Order = tagInfo.Order
 
 		// Figure out the encoding size and type
-		switch entry.Field.Type.Kind() {
-		case reflect.Bool, reflect.Uint8:
-			entry.Size = 1
-		case reflect.Uint16, reflect.Int16:
-			entry.Size = 2
-		case reflect.Uint32, reflect.Int32, reflect.Float32:
-			entry.Size = 4
-		case reflect.Uint64, reflect.Uint, reflect.Int64, reflect.Int, reflect.Float64:
-			entry.Size = 8
-		case reflect.String:
-			entry.Size = uint(entry.LengthBits / 8)
-			entry.VarLength = true
-			ep.varLength = true
-		case reflect.Slice:
-			elem := entry.Field.Type.Elem()
-			if elem.Kind() == reflect.Uint8 {
-				entry.Size = uint(entry.LengthBits / 8)
-				entry.VarLength = true
-			} else {
-				// UNIMPLEMENTED
-				err = fmt.Errorf("bytocol: unsupported slice type %s", elem.String())
-			}
-			ep.varLength = true
-		default:
-			err = fmt.Errorf("bytocol: unsupported encode type %s", entry.Field.Type.String())
-		}
-
-		if err != nil {
+		if err = entry.plan(tagInfo, stack); err != nil {
 			return err
 		}
 		ep.size += entry.Size
+		ep.varLength = ep.varLength || entry.VarLength
+		ep.registeredCodecs = ep.registeredCodecs || entry.usesRegisteredCodec()
 
 		// Save the plan entry
 		ep.entries = append(ep.entries, entry)
@@ -370,21 +330,21 @@ func (ep *TypePlan) planObject(obj any) error {
 		}
 	}
 
+	// Only messages have a checksum, nested structs never do
+	ep.size += uint(ep.checksum.Size())
 	return nil
 }
 
 // Write executes an encoding plan using the given object as a value for the
 // plan and writes the bytes to the given [io.Writer]. The type of the object must
 // match the type for the plan.
+//
+// If the object implements [Marshaler] the generated encoder is used instead,
+// unless the plan uses a codec registered with [RegisterCodec]. The checksum
+// trailer is appended in both cases.
 func (ep TypePlan) Write(obj Message, w io.Writer) error {
 	var err error
 
-	// Write the type indicator first
-	_, err = w.Write([]byte{ep.typeIndicator})
-	if err != nil {
-		return err
-	}
-
 	// Figure out the object type
 	valueOf := reflect.ValueOf(obj)
 	if valueOf.Type().Kind() == reflect.Pointer {
@@ -396,68 +356,39 @@ func (ep TypePlan) Write(obj Message, w io.Writer) error {
 		return ErrNonMatchingType
 	}
 
-	for _, entry := range ep.entries {
-		fieldValue := valueOf.Field(entry.FieldIndex)
-
-		switch entry.Field.Type.Kind() {
-		case reflect.Bool:
-			err = writeNumber(boolToByte(fieldValue.Bool()), w)
-
-		case reflect.Uint8:
-			casted, _ := fieldValue.Interface().(uint8)
-			err = writeNumber(casted, w)
-		case reflect.Uint16:
-			casted, _ := fieldValue.Interface().(uint16)
-			err = writeNumber(casted, w)
-		case reflect.Uint32:
-			casted, _ := fieldValue.Interface().(uint32)
-			err = writeNumber(casted, w)
-		case reflect.Uint64:
-			casted, _ := fieldValue.Interface().(uint64)
-			err = writeNumber(casted, w)
-		case reflect.Uint:
-			casted, _ := fieldValue.Interface().(uint)
-			err = writeNumber(casted, w)
-
-		case reflect.Int8:
-			casted, _ := fieldValue.Interface().(int8)
-			err = writeNumber(casted, w)
-		case reflect.Int16:
-			casted, _ := fieldValue.Interface().(int16)
-			err = writeNumber(casted, w)
-		case reflect.Int32:
-			casted, _ := fieldValue.Interface().(int32)
-			err = writeNumber(casted, w)
-		case reflect.Int64:
-			casted, _ := fieldValue.Interface().(int64)
-			err = writeNumber(casted, w)
-		case reflect.Int:
-			casted, _ := fieldValue.Interface().(int)
-			err = writeNumber(casted, w)
-
-		case reflect.Float32:
-			casted, _ := fieldValue.Interface().(float32)
-			err = writeNumber(casted, w)
-		case reflect.Float64:
-			casted, _ := fieldValue.Interface().(float64)
-			err = writeNumber(casted, w)
-
-		case reflect.String:
-			err = writeBlob(fieldValue.String(), entry.LengthBits, w)
-		case reflect.Slice:
-			elem := entry.Field.Type.Elem()
-			if elem.Kind() == reflect.Uint8 {
-				// Byte slice, use the blob method
-				err = writeBlob(fieldValue.Bytes(), entry.LengthBits, w)
-			} else {
-				// UNIMPLEMENTED
-				err = fmt.Errorf("bytocol: unsupported slice type %s", elem.String())
-			}
-		default:
-			err = fmt.Errorf("bytocol: unsupported encode type %s", entry.Field.Type.String())
+	// The checksum covers everything written before the trailer
+	out := w
+	sum := ep.checksum.newState()
+	if sum != nil {
+		out = io.MultiWriter(w, sum)
+	}
+
+	// Prefer the generated encoder, which includes the type indicator
+	if generated, ok := obj.(Marshaler); ok && !ep.registeredCodecs {
+		var data []byte
+		if data, err = generated.MarshalBytocol(make([]byte, 0, generated.SizeBytocol()+ep.checksum.Size())); err != nil {
+			return err
 		}
+		_, err = out.Write(data)
+	} else if _, err = out.Write([]byte{ep.typeIndicator}); err == nil {
+		// Write the type indicator first
+		err = ep.writeFields(valueOf, out)
+	}
+
+	if err != nil || sum == nil {
+		return err
+	}
+	return writeChecksum(ep.checksum, sum, ep.byteOrder, w)
+}
 
+// writeFields encodes every entry of the plan from the struct value onto the
+// [io.Writer], without the type indicator.
+func (ep TypePlan) writeFields(valueOf reflect.Value, w io.Writer) error {
+	var err error
+	for _, entry := range ep.entries {
+		err = entry.writeValue(valueOf.Field(entry.FieldIndex), w)
 		if err != nil {
+			err = fmt.Errorf("bytocol: error writing field %s: %w", entry.Field.Name, err)
 			break
 		}
 	}
@@ -479,8 +410,32 @@ func (ep TypePlan) Marshal(obj Message) ([]byte, error) {
 // Because peeking will be required to deduce the type (and thus plan) to use, it
 // is required that the type indicator not be the first byte this will read from.
 // That is, remove the type-indicator from the read buffer first.
+//
+// Short reads are retried until each field is complete. If a field cannot be
+// decoded a [*DecodeError] is returned describing the field and offset.
+//
+// If the target implements [Unmarshaler] the generated decoder is used instead,
+// unless the plan uses a codec registered with [RegisterCodec].
+//
+// The checksum trailer is verified once every field is decoded, returning an
+// [ErrChecksumMismatch] error if it differs. Corrupted data may fail to decode
+// before reaching the trailer, which [Conn] avoids by verifying whole frames
+// first.
+//
+// Lengths and counts are checked against the max options of the fields and the
+// [DecodeLimits] of the message before anything is allocated for them, failing
+// with [ErrLimitExceeded].
 func (ep TypePlan) Read(r io.Reader, target Message) error {
+	return ep.read(r, target, DecodeLimits{})
+}
+
+// read decodes the message like [TypePlan.Read], enforcing the limits on top
+// of the ones of the message.
+func (ep TypePlan) read(r io.Reader, target Message, limits DecodeLimits) error {
 	// Ensure the target is correct
+	if target == nil {
+		return ErrNilTarget
+	}
 	valueOf := reflect.ValueOf(target)
 	if valueOf.Type().Kind() == reflect.Pointer {
 		if valueOf.IsNil() {
@@ -495,126 +450,58 @@ func (ep TypePlan) Read(r io.Reader, target Message) error {
 		return ErrNonMatchingType
 	}
 
-	excerpt := make([]byte, 8)
-
-	var err error
-	for _, entry := range ep.entries {
-		field := valueOf.Field(entry.FieldIndex)
-		if !field.CanSet() {
-			return fmt.Errorf("field %s cannot be set", field.Type().Name())
-		}
-
-		switch field.Type().Kind() {
-		case reflect.Bool:
-			// Read 1 byte
-			_, err = r.Read(excerpt[:1])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-
-			field.SetBool(excerpt[0] == 1)
+	// The checksum covers the type indicator already consumed
+	in := r
+	sum := ep.checksum.newState()
+	if sum != nil {
+		sum.Write([]byte{ep.typeIndicator})
+		in = io.TeeReader(r, sum)
+	}
 
-		case reflect.Uint8:
-			// Read 1 byte
-			_, err = r.Read(excerpt[:1])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[uint8](excerpt[:1], field)
+	// Offsets are counted from the type indicator already consumed
+	counting := &countingReader{r: in, offset: 1, limits: ep.limits.tighten(limits)}
 
-		case reflect.Uint16:
-			// Read 2 bytes
-			_, err = r.Read(excerpt[:2])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[uint16](excerpt[:2], field)
-		case reflect.Uint32:
-			// Read 4 bytes
-			_, err = r.Read(excerpt[:4])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[uint32](excerpt[:4], field)
+	// Prefer the generated decoder, it requires a pointer target
+	var err error
+	if generated, ok := target.(Unmarshaler); ok && !ep.registeredCodecs && reflect.TypeOf(target).Kind() == reflect.Pointer {
+		err = generated.UnmarshalBytocol(counting)
+	} else {
+		err = ep.readFields(counting, valueOf)
+	}
 
-		case reflect.Uint64, reflect.Uint:
-			// Read 8 bytes
-			_, err = r.Read(excerpt[:8])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[uint64](excerpt[:8], field)
+	if err == nil && sum != nil {
+		err = readChecksum(ep.checksum, sum, ep.byteOrder, r)
+	}
 
-		case reflect.Int8:
-			// Read 1 byte
-			_, err = r.Read(excerpt[:1])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[int8](excerpt[:1], field)
-		case reflect.Int16:
-			// Read 2 bytes
-			_, err = r.Read(excerpt[:2])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[int16](excerpt[:2], field)
-		case reflect.Int32:
-			// Read 4 bytes
-			_, err = r.Read(excerpt[:4])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[int32](excerpt[:4], field)
-		case reflect.Int64, reflect.Int:
-			// Read 8 bytes
-			_, err = r.Read(excerpt[:8])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[int64](excerpt[:8], field)
+	var decErr *DecodeError
+	if errors.As(err, &decErr) {
+		decErr.Message = ep.debugName
+	}
+	return err
+}
 
-		case reflect.Float32:
-			// Read 4 bytes
-			_, err = r.Read(excerpt[:4])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[float32](excerpt[:4], field)
-		case reflect.Float64:
-			// Read 8 bytes
-			_, err = r.Read(excerpt[:8])
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			err = setNumberFromBytes[float64](excerpt[:8], field)
+// readFields decodes every entry of the plan from the [io.Reader] into the
+// struct value, which must be settable. Errors are returned as a [*DecodeError]
+// describing the failing field.
+func (ep TypePlan) readFields(r io.Reader, valueOf reflect.Value) error {
+	for _, entry := range ep.entries {
+		offset := readOffset(r)
 
-		case reflect.String:
-			// Depending on field size, read N bytes
-			blob, err := entry.readBytes(r)
-			if err != nil {
-				return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-			}
-			field.SetString(string(blob))
-		case reflect.Slice:
-			elem := entry.Field.Type.Elem()
-			if elem.Kind() == reflect.Uint8 {
-				// Byte slice, use the blob method
-				blob, err := entry.readBytes(r)
-				if err != nil {
-					return fmt.Errorf("bytocol: error reading for field %s: %s", field.Type().Name(), err)
-				}
-				field.SetBytes(blob)
-			} else {
-				// UNIMPLEMENTED
-				err = fmt.Errorf("bytocol: unsupported slice type %s", elem.String())
+		field := valueOf.Field(entry.FieldIndex)
+		if entry.Nested == nil && !field.CanSet() {
+			return &DecodeError{
+				Field:  entry.Field.Name,
+				Order:  entry.Order,
+				Offset: offset,
+				Err:    errors.New("field cannot be set"),
 			}
-		default:
-			err = fmt.Errorf("bytocol: unsupported encode type %s", entry.Field.Type.String())
 		}
 
-		if err != nil {
-			break
+		if err := entry.readValue(r, field); err != nil {
+			// Nested fields and elements already describe their own path
+			decErr := prefixDecodeError(entry.Field.Name, offset, err)
+			decErr.Order = entry.Order
+			return decErr
 		}
 	}
 
@@ -631,8 +518,10 @@ func (ep TypePlan) Unmarshal(data []byte, target Message) error {
 
 // PlanType creates a new [TypePlan] based on the generic argument provided.
 // This will create a zero-value object of the type and run the reflection process
-// to build an encoding/decoding plan for it. It returns nil, and an error if
-// something fails in building a plan for the given type.
+// to build an encoding/decoding plan for it. Pointer types are planned from a
+// freshly allocated value, so value receivers are never called on nil. It
+// returns nil, and an error if something fails in building a plan for the given
+// type.
 func PlanType[T Message]() (*TypePlan, error) {
 	var zeroValue T
 	plan := new(TypePlan)
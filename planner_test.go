@@ -2,7 +2,14 @@ package bytocol
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestPlanEntries(t *testing.T) {
@@ -128,3 +135,723 @@ func TestPlanUnmarshal(t *testing.T) {
 		t.Log(plan.Explain(data))
 	}
 }
+
+type testBlobMessage struct {
+	Short      string `bytocol:"0,length-prefix=8"`
+	Medium     []byte `bytocol:"1,length-prefix=16"`
+	Long       string `bytocol:"2,length-prefix=32"`
+	Terminated string `bytocol:"3,null-terminated"`
+	Default    string `bytocol:"4"`
+}
+
+func (m testBlobMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 3, DebugName: "blobs"}
+}
+
+func TestPlanBlobOptions(t *testing.T) {
+	plan, err := PlanType[testBlobMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.Size() != (1 + 2 + 4 + 1 + 8) {
+		t.Errorf("unexpected min-size %d", plan.Size())
+	}
+
+	obj := testBlobMessage{"short", []byte("medium"), "long", "terminated", "default"}
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedLength := 1 + (1 + 5) + (2 + 6) + (4 + 4) + (10 + 1) + (8 + 7)
+	if len(data) != expectedLength {
+		t.Errorf("unexpected length %d: %v", len(data), data)
+	}
+
+	var result testBlobMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if result.Short != obj.Short || !bytes.Equal(result.Medium, obj.Medium) ||
+		result.Long != obj.Long || result.Terminated != obj.Terminated || result.Default != obj.Default {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if t.Failed() {
+		t.Log(plan.Explain(data))
+	}
+
+	// Catch overflowing the declared prefix
+	obj.Short = strings.Repeat("a", 256)
+	if _, err = plan.Marshal(obj); !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+
+	// Catch NUL bytes in null-terminated content
+	obj.Short = ""
+	obj.Terminated = "bad\x00string"
+	if _, err = plan.Marshal(obj); !errors.Is(err, ErrNullInContent) {
+		t.Errorf("expected NUL content error, got %v", err)
+	}
+
+	// Catch length options on non-blob fields
+	type BadOptions struct {
+		Number int `bytocol:"0,length-prefix=8"`
+	}
+	if err = new(TypePlan).planObject(BadOptions{}); err == nil {
+		t.Error("expected error for length-prefix on number")
+	}
+}
+
+type testPosition struct {
+	X float32 `bytocol:"0"`
+	Y float32 `bytocol:"1"`
+	Z float32 `bytocol:"2"`
+}
+
+type testHeader struct {
+	Sequence uint16 `bytocol:"0"`
+	Source   string `bytocol:"1,length-prefix=8"`
+}
+
+type testNestedMessage struct {
+	testHeader `bytocol:"0"`
+	Position   testPosition `bytocol:"2"`
+	Velocity   testPosition `bytocol:"1"`
+	Untagged   testPosition
+}
+
+func (m testNestedMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 4, DebugName: "nested"}
+}
+
+func TestPlanNested(t *testing.T) {
+	plan, err := PlanType[testNestedMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if len(plan.entries) != 3 {
+		t.Errorf("expected 3 plan entries, got %d", len(plan.entries))
+		return
+	} else if plan.Size() != (2+1)+12+12 {
+		t.Errorf("unexpected min-size %d", plan.Size())
+	}
+
+	obj := testNestedMessage{
+		testHeader: testHeader{7, "src"},
+		Position:   testPosition{1, 2, 3},
+		Velocity:   testPosition{-1, -2, -3},
+		Untagged:   testPosition{9, 9, 9},
+	}
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	} else if len(data) != 1+(2+1+3)+12+12 {
+		t.Errorf("unexpected length %d: %v", len(data), data)
+	}
+
+	// Velocity is ordered before position
+	if x, _ := bytesToNumber[float32](data[7:11], binary.BigEndian); x != -1 {
+		t.Errorf("expected velocity first, got %v", x)
+	}
+
+	var result testNestedMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if result.testHeader != obj.testHeader || result.Position != obj.Position || result.Velocity != obj.Velocity {
+		t.Errorf("unexpected result %+v", result)
+	} else if result.Untagged != (testPosition{}) {
+		t.Error("expected untagged struct to be skipped")
+	}
+
+	if t.Failed() {
+		t.Log(plan.String())
+		t.Log(plan.Explain(data))
+	}
+
+	// Catch nested structs without any encoded fields
+	type Empty struct {
+		Foo int
+	}
+	type BadNested struct {
+		Nested Empty `bytocol:"0"`
+	}
+	if err = new(TypePlan).planObject(BadNested{}); err == nil {
+		t.Error("expected error for nested struct without fields")
+	}
+}
+
+type testListMessage struct {
+	Numbers   []uint32       `bytocol:"0,length-prefix=8"`
+	Names     []string       `bytocol:"1,length-prefix=16"`
+	Vector    [4]float32     `bytocol:"2"`
+	Positions []testPosition `bytocol:"3"`
+	Grid      [2][2]int16    `bytocol:"4"`
+	Headers   [2]testHeader  `bytocol:"5"`
+	Nested    [][]byte       `bytocol:"6,length-prefix=8"`
+	Empty     []testPosition `bytocol:"7,length-prefix=8"`
+}
+
+func (m testListMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 5, DebugName: "lists"}
+}
+
+func TestPlanLists(t *testing.T) {
+	plan, err := PlanType[testListMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedSize := uint(1 + 2 + 16 + 8 + 8 + 2*(2+1) + 1 + 1)
+	if plan.Size() != expectedSize {
+		t.Errorf("unexpected min-size %d, expected %d", plan.Size(), expectedSize)
+	}
+
+	obj := testListMessage{
+		Numbers:   []uint32{1, 2, 3},
+		Names:     []string{"a", "bc"},
+		Vector:    [4]float32{1, 2, 3, 4},
+		Positions: []testPosition{{1, 2, 3}, {4, 5, 6}},
+		Grid:      [2][2]int16{{1, -1}, {-2, 2}},
+		Headers:   [2]testHeader{{1, "x"}, {2, "yz"}},
+		Nested:    [][]byte{{1}, {2, 3}},
+	}
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedLength := 1 + (1 + 3*4) + (2 + (8 + 1) + (8 + 2)) + 16 + (8 + 2*12) + 8 + (2 + 1 + 1 + 2 + 1 + 2) + (1 + (8 + 1) + (8 + 2)) + 1
+	if len(data) != expectedLength {
+		t.Errorf("unexpected length %d, expected %d", len(data), expectedLength)
+	}
+
+	var result testListMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(result.Numbers, obj.Numbers) || !reflect.DeepEqual(result.Names, obj.Names) ||
+		result.Vector != obj.Vector || !reflect.DeepEqual(result.Positions, obj.Positions) ||
+		result.Grid != obj.Grid || result.Headers != obj.Headers || !reflect.DeepEqual(result.Nested, obj.Nested) ||
+		len(result.Empty) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if t.Failed() {
+		t.Log(plan.String())
+		t.Log(plan.Explain(data))
+	}
+
+	// Catch overflowing the count prefix
+	obj.Numbers = make([]uint32, 256)
+	if _, err = plan.Marshal(obj); !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+
+	// Catch unsupported element types
+	type BadElem struct {
+		Funcs []func() `bytocol:"0"`
+	}
+	if err = new(TypePlan).planObject(BadElem{}); err == nil {
+		t.Error("expected error for unsupported element type")
+	}
+
+	// Catch length options on arrays
+	type BadArray struct {
+		Array [2]int `bytocol:"0,length-prefix=8"`
+	}
+	if err = new(TypePlan).planObject(BadArray{}); err == nil {
+		t.Error("expected error for length-prefix on array")
+	}
+
+	// Catch recursive types
+	type Node struct {
+		Children []Node `bytocol:"0"`
+	}
+	if err = new(TypePlan).planObject(Node{}); err == nil {
+		t.Error("expected error for recursive type")
+	}
+}
+
+type testCountMessage struct {
+	Numbers []uint32          `bytocol:"0,length-prefix=64"`
+	Lookup  map[uint8]float32 `bytocol:"1,length-prefix=64"`
+}
+
+func (m testCountMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 77, DebugName: "counts"}
+}
+
+func TestPlanCountOverflow(t *testing.T) {
+	plan, err := PlanType[testCountMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Counts that do not fit in an int cannot be allocated
+	huge := []byte{0x80, 0, 0, 0, 0, 0, 0, 1}
+	var decErr *DecodeError
+	var result testCountMessage
+	err = plan.Unmarshal(huge, &result)
+	if !errors.As(err, &decErr) || decErr.Field != "Numbers" || !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("expected overflowing slice count, got %v", err)
+	}
+
+	err = plan.Unmarshal(append(make([]byte, 8), huge...), &result)
+	if !errors.As(err, &decErr) || decErr.Field != "Lookup" || !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("expected overflowing map count, got %v", err)
+	}
+}
+
+type testMapMessage struct {
+	Counters map[string]uint16     `bytocol:"0,length-prefix=8"`
+	Lookup   map[int8]testPosition `bytocol:"1"`
+	Tags     map[uint32][]string   `bytocol:"2,length-prefix=16"`
+	Flags    map[bool]bool         `bytocol:"3,length-prefix=8"`
+}
+
+func (m testMapMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 6, DebugName: "maps"}
+}
+
+func TestPlanMaps(t *testing.T) {
+	plan, err := PlanType[testMapMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.Size() != (1 + 8 + 2 + 1) {
+		t.Errorf("unexpected min-size %d", plan.Size())
+	}
+
+	obj := testMapMessage{
+		Counters: map[string]uint16{"zeta": 1, "alpha": 2, "mid": 3},
+		Lookup:   map[int8]testPosition{-5: {1, 2, 3}, 5: {4, 5, 6}},
+		Tags:     map[uint32][]string{2: {"b"}, 1: {"a", "aa"}},
+		Flags:    map[bool]bool{true: false, false: true},
+	}
+
+	// Marshal repeatedly to ensure deterministic output
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 20; i++ {
+		again, err := plan.Marshal(obj)
+		if err != nil {
+			t.Error(err)
+			return
+		} else if !bytes.Equal(data, again) {
+			t.Error("expected deterministic map encoding")
+			break
+		}
+	}
+
+	// Keys are sorted, "alpha" comes first after the 8-bit count
+	if data[1] != 3 || string(data[10:15]) != "alpha" {
+		t.Errorf("expected sorted keys, got %v", data[:20])
+	}
+
+	var result testMapMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(result, obj) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if t.Failed() {
+		t.Log(plan.String())
+		t.Log(plan.Explain(data))
+	}
+
+	// Catch unsupported key types
+	type BadKey struct {
+		Map map[testPosition]int `bytocol:"0"`
+	}
+	if err = new(TypePlan).planObject(BadKey{}); err == nil {
+		t.Error("expected error for unsupported key type")
+	}
+}
+
+type testFloatKeyMessage struct {
+	Weights map[float64]uint16 `bytocol:"0,length-prefix=8"`
+}
+
+func (m testFloatKeyMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 78, DebugName: "float-keys"}
+}
+
+func TestPlanMapsNaNKey(t *testing.T) {
+	plan, err := PlanType[testFloatKeyMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	obj := testFloatKeyMessage{Weights: map[float64]uint16{math.NaN(): 7, 1.5: 9}}
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// NaN is ordered before every other key
+	if len(data) != 1+1+2*(8+2) || data[1] != 2 || data[11] != 7 || data[21] != 9 {
+		t.Errorf("unexpected encoding %v", data)
+	}
+
+	var result testFloatKeyMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+		return
+	} else if len(result.Weights) != 2 || result.Weights[1.5] != 9 {
+		t.Errorf("unexpected result %v", result.Weights)
+	}
+	for key, value := range result.Weights {
+		if math.IsNaN(key) && value != 7 {
+			t.Errorf("unexpected value %d for NaN key", value)
+		}
+	}
+}
+
+func TestPlanMapsNaNKeysOrder(t *testing.T) {
+	plan, err := PlanType[testFloatKeyMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	obj := testFloatKeyMessage{Weights: map[float64]uint16{1.5: 9}}
+	for i := 0; i < 8; i++ {
+		obj.Weights[math.NaN()] = uint16(i)
+	}
+
+	expected, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 50; i++ {
+		data, err := plan.Marshal(obj)
+		if err != nil {
+			t.Error(err)
+			return
+		} else if !bytes.Equal(data, expected) {
+			t.Errorf("encoding changed from %v to %v", expected, data)
+			return
+		}
+	}
+}
+
+type testEmptyMessage struct {
+	Nothing [0]uint8 `bytocol:"0"`
+}
+
+func (m testEmptyMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 79, DebugName: "empty"}
+}
+
+func TestPlanExplainMalformed(t *testing.T) {
+	// Plans without any size still need the type indicator
+	empty, err := PlanType[testEmptyMessage]()
+	if err != nil {
+		t.Fatal(err)
+	} else if explained := empty.Explain(nil); !strings.HasPrefix(explained, "Invalid data size 0") {
+		t.Errorf("unexpected explanation of no data:\n%s", explained)
+	}
+
+	plan, err := PlanType[testMapMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := plan.Marshal(testMapMessage{Counters: map[string]uint16{"alpha": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The count of the map promises more than the data holds
+	explained := plan.Explain(data[:len(data)-4])
+	if !strings.Contains(explained, "DATA OVERFLOW\nData overflow at offset ") || strings.Contains(explained, "All bytes accounted for") {
+		t.Errorf("unexpected explanation of truncated data:\n%s", explained)
+	}
+}
+
+type testOptionalMessage struct {
+	Limit    *uint32       `bytocol:"0"`
+	Name     *string       `bytocol:"1,length-prefix=8"`
+	Position *testPosition `bytocol:"2"`
+	Missing  *int64        `bytocol:"3"`
+}
+
+func (m testOptionalMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 7, DebugName: "optional"}
+}
+
+func TestPlanOptional(t *testing.T) {
+	plan, err := PlanType[testOptionalMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.Size() != 4 {
+		t.Errorf("unexpected min-size %d", plan.Size())
+	}
+
+	limit := uint32(10)
+	name := "name"
+	obj := testOptionalMessage{
+		Limit:    &limit,
+		Name:     &name,
+		Position: &testPosition{1, 2, 3},
+	}
+
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	} else if len(data) != 1+(1+4)+(1+1+4)+(1+12)+1 {
+		t.Errorf("unexpected length %d: %v", len(data), data)
+	}
+
+	var result testOptionalMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(result, obj) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if t.Failed() {
+		t.Log(plan.String())
+		t.Log(plan.Explain(data))
+	}
+
+	// Catch invalid presence bytes
+	field := reflect.ValueOf(&result).Elem().Field(0)
+	if err = plan.entries[0].readValue(bytes.NewReader([]byte{2}), field); !errors.Is(err, ErrInvalidPresence) {
+		t.Errorf("expected presence error, got %v", err)
+	}
+
+	// Catch recursive types through pointers
+	type Node struct {
+		Next *Node `bytocol:"0"`
+	}
+	if err = new(TypePlan).planObject(Node{}); err == nil {
+		t.Error("expected error for recursive type")
+	}
+}
+
+type testVarintMessage struct {
+	Counter uint64            `bytocol:"0,varint"`
+	Delta   int32             `bytocol:"1,varint"`
+	Name    string            `bytocol:"2,varint"`
+	Samples []int16           `bytocol:"3,varint"`
+	Labels  map[string]string `bytocol:"4,varint"`
+	Limit   *int              `bytocol:"5,varint"`
+}
+
+func (m testVarintMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 8, DebugName: "varint"}
+}
+
+func TestPlanVarint(t *testing.T) {
+	plan, err := PlanType[testVarintMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.Size() != 6 {
+		t.Errorf("unexpected min-size %d", plan.Size())
+	}
+
+	limit := -300
+	obj := testVarintMessage{
+		Counter: 300,
+		Delta:   -2,
+		Name:    "abc",
+		Samples: []int16{1, 2},
+		Labels:  map[string]string{"k": "v"},
+		Limit:   &limit,
+	}
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := []byte{
+		8,
+		172, 2, // Counter
+		3,                // Delta
+		3, 'a', 'b', 'c', // Name
+		2, 0, 1, 0, 2, // Samples
+		1, 0, 0, 0, 0, 0, 0, 0, 1, 'k', 0, 0, 0, 0, 0, 0, 0, 1, 'v', // Labels
+		1, 215, 4, // Limit
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("unexpected encoding %v", data)
+	}
+
+	var result testVarintMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(result, obj) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if t.Failed() {
+		t.Log(plan.String())
+		t.Log(plan.Explain(data))
+	}
+
+	// Catch values that overflow the field type
+	type Small struct {
+		Value uint8 `bytocol:"0,varint"`
+	}
+	small := new(TypePlan)
+	if err = small.planObject(Small{}); err != nil {
+		t.Error(err)
+		return
+	}
+	var smallResult Small
+	field := reflect.ValueOf(&smallResult).Elem().Field(0)
+	if err = small.entries[0].readValue(bytes.NewReader([]byte{172, 2}), field); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+
+	// Catch varint on unsupported types
+	type BadVarint struct {
+		Value float32 `bytocol:"0,varint"`
+	}
+	if err = new(TypePlan).planObject(BadVarint{}); err == nil {
+		t.Error("expected error for varint on float")
+	}
+}
+
+type testEndianMessage struct {
+	Big      uint16            `bytocol:"0,endian=big"`
+	Little   uint32            `bytocol:"1"`
+	Name     string            `bytocol:"2,length-prefix=16"`
+	Position testPosition      `bytocol:"3,endian=big"`
+	Values   []int16           `bytocol:"4,length-prefix=16"`
+	Header   testHeader        `bytocol:"5"`
+	Lookup   map[uint16]uint16 `bytocol:"6,length-prefix=16,endian=big"`
+}
+
+func (m testEndianMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 9, DebugName: "endian", ByteOrder: binary.LittleEndian}
+}
+
+func TestPlanEndian(t *testing.T) {
+	plan, err := PlanType[testEndianMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.ByteOrder() != binary.LittleEndian {
+		t.Error("expected little endian plan")
+	}
+
+	obj := testEndianMessage{
+		Big:      0x0102,
+		Little:   0x01020304,
+		Name:     "a",
+		Position: testPosition{1, 0, 0},
+		Values:   []int16{0x0102},
+		Header:   testHeader{0x0102, "b"},
+		Lookup:   map[uint16]uint16{0x0102: 0x0304},
+	}
+	data, err := plan.Marshal(obj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := []byte{
+		9,
+		1, 2, // Big
+		4, 3, 2, 1, // Little
+		1, 0, 'a', // Name
+		63, 128, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // Position
+		1, 0, 2, 1, // Values
+		2, 1, 1, 'b', // Header
+		0, 1, 1, 2, 3, 4, // Lookup
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("unexpected encoding %v", data)
+	}
+
+	var result testEndianMessage
+	if err = plan.Unmarshal(data[1:], &result); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(result, obj) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if t.Failed() {
+		t.Log(plan.String())
+		t.Log(plan.Explain(data))
+	}
+}
+
+func TestPlanReadShort(t *testing.T) {
+	plan, err := PlanObject(testMessageObj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data, err := plan.Marshal(testMessageObj)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Readers returning a single byte at a time must still fill every field
+	var result testMessage
+	if err = plan.Read(iotest.OneByteReader(bytes.NewReader(data[1:])), &result); err != nil {
+		t.Error(err)
+	} else if result.String != testMessageObj.String || !bytes.Equal(result.Bytes, testMessageObj.Bytes) {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// Truncated within the Int field, which starts at offset 4
+	err = plan.Unmarshal(data[1:6], &result)
+	var decErr *DecodeError
+	if !errors.As(err, &decErr) {
+		t.Errorf("expected decode error, got %v", err)
+	} else if decErr.Message != "test" || decErr.Field != "Int" || decErr.Order != 2 || decErr.Offset != 4 {
+		t.Errorf("unexpected decode error %+v", decErr)
+	} else if !errors.Is(err, io.ErrUnexpectedEOF) || !errors.Is(err, ErrReadInvariance) {
+		t.Errorf("expected unexpected EOF and read invariance, got %v", err)
+	}
+
+	// Truncated at the start of a field
+	err = plan.Unmarshal(data[1:4], &result)
+	if !errors.As(err, &decErr) || decErr.Field != "Int" {
+		t.Errorf("unexpected decode error %v", err)
+	} else if !errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrReadInvariance) {
+		t.Errorf("expected only unexpected EOF, got %v", err)
+	}
+
+	// Nested and element paths are described
+	lists, err := PlanType[testListMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, err = lists.Marshal(testListMessage{Positions: []testPosition{{1, 2, 3}, {4, 5, 6}}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Cut in the middle of the second position's Y
+	cut := 1 + 1 + 2 + 16 + 8 + 12 + 6
+	var listResult testListMessage
+	err = lists.Unmarshal(data[1:cut], &listResult)
+	if !errors.As(err, &decErr) {
+		t.Errorf("expected decode error, got %v", err)
+	} else if decErr.Field != "Positions[1].Y" || decErr.Order != 3 || decErr.Offset != int64(cut-2) {
+		t.Errorf("unexpected decode error %+v", decErr)
+	}
+}
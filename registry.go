@@ -0,0 +1,179 @@
+package bytocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// registryEntry pairs a cached [TypePlan] with the type that was registered.
+// The registered type may be a pointer to the planned struct, in which case
+// decoded values are returned as pointers as well.
+type registryEntry struct {
+	plan   *TypePlan
+	typeOf reflect.Type
+}
+
+// decode allocates a fresh value and reads the message body into it, enforcing
+// the limits. The result is a pointer if the registered type was a pointer,
+// otherwise the value.
+func (re registryEntry) decode(r io.Reader, limits DecodeLimits) (Message, error) {
+	ptr := reflect.New(re.plan.typeOf)
+	target, _ := ptr.Interface().(Message)
+	if err := re.plan.read(r, target, limits); err != nil {
+		return nil, err
+	}
+
+	if re.typeOf.Kind() == reflect.Pointer {
+		return target, nil
+	}
+	result, _ := ptr.Elem().Interface().(Message)
+	return result, nil
+}
+
+// Registry holds the encoding plans for every [Message] type of a protocol,
+// keyed by their type indicator. Each type is registered once using [Register]
+// which builds and caches the [TypePlan]. The registry can then decode incoming
+// messages without knowing their type ahead of time by peeking the type
+// indicator. It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[byte]registryEntry
+}
+
+// NewRegistry creates a new [Registry] with only the built-in [ErrorMessage]
+// registered.
+func NewRegistry() *Registry {
+	reg := &Registry{
+		types: make(map[byte]registryEntry),
+	}
+
+	if _, err := Register[ErrorMessage](reg); err != nil {
+		panic(fmt.Sprintf("bytocol: cannot register built-in error message: %s", err))
+	}
+	return reg
+}
+
+// DefaultRegistry is the [Registry] used by the package-level [Read] and
+// [Unmarshal] functions.
+var DefaultRegistry = NewRegistry()
+
+// Register plans the [Message] type provided as the generic argument and adds
+// it to the registry. The resulting [TypePlan] is returned, and also shared with
+// the plan cache used by [Write] and [Marshal]. It returns an error
+// if the plan could not be built, if the type indicator is already used by
+// another type, or if the type uses the reserved type indicator 0 without being
+// the built-in [ErrorMessage].
+func Register[T Message](reg *Registry) (*TypePlan, error) {
+	plan, err := CachePlan[T]()
+	if err != nil {
+		return nil, err
+	} else if !plan.IsValid() {
+		return nil, fmt.Errorf("bytocol: cannot register %s, no exported fields", plan.debugName)
+	}
+
+	typeOf := reflect.TypeFor[T]()
+	if plan.typeIndicator == 0 && plan.typeOf != reflect.TypeFor[ErrorMessage]() {
+		return nil, fmt.Errorf("bytocol: cannot register %s, %w", plan.debugName, ErrReservedTypeIndicator)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if existing, ok := reg.types[plan.typeIndicator]; ok {
+		return nil, fmt.Errorf("bytocol: cannot register %s, %w %d already used by %s", plan.debugName, ErrDuplicateTypeIndicator, plan.typeIndicator, existing.plan.debugName)
+	}
+
+	reg.types[plan.typeIndicator] = registryEntry{plan, typeOf}
+	return plan, nil
+}
+
+// Plan returns the [TypePlan] registered for the given type indicator, and
+// true if it was found.
+func (reg *Registry) Plan(typeIndicator byte) (*TypePlan, bool) {
+	entry, ok := reg.entry(typeIndicator)
+	return entry.plan, ok
+}
+
+// entry returns the [registryEntry] for the given type indicator, and true if
+// it was found.
+func (reg *Registry) entry(typeIndicator byte) (registryEntry, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	entry, ok := reg.types[typeIndicator]
+	return entry, ok
+}
+
+// Plans returns all the registered plans ordered by their type indicator.
+func (reg *Registry) Plans() []*TypePlan {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	plans := make([]*TypePlan, 0, len(reg.types))
+	for _, entry := range reg.types {
+		plans = append(plans, entry.plan)
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].typeIndicator < plans[j].typeIndicator
+	})
+	return plans
+}
+
+// Read reads a single message from the [io.Reader]. The first byte is expected
+// to be the type indicator which is used to find the registered plan. A freshly
+// decoded value of the registered type is returned. If the type indicator is not
+// registered an [ErrUnknownTypeIndicator] error is returned.
+//
+// If the message read is an [ErrorMessage] it is returned as the error with a
+// nil message, use [errors.As] to distinguish it from decoding errors.
+func (reg *Registry) Read(r io.Reader) (Message, error) {
+	return reg.readLimited(r, DecodeLimits{})
+}
+
+// readLimited reads a single message like [Registry.Read], enforcing the
+// limits on top of the ones of the message.
+func (reg *Registry) readLimited(r io.Reader, limits DecodeLimits) (Message, error) {
+	var indicator [1]byte
+	if _, err := io.ReadFull(r, indicator[:]); err != nil {
+		return nil, err
+	}
+
+	return reg.decode(indicator[0], r, limits)
+}
+
+// Unmarshal decodes a single message from the byte data, including the leading
+// type indicator. This internally wraps the data in a [io.Reader] buffer and
+// uses [Registry.Read].
+func (reg *Registry) Unmarshal(data []byte) (Message, error) {
+	return reg.Read(bytes.NewReader(data))
+}
+
+// decode reads the message body for the given type indicator, enforcing the
+// limits. The type indicator must already be consumed from the reader.
+func (reg *Registry) decode(typeIndicator byte, r io.Reader, limits DecodeLimits) (Message, error) {
+	reg.mu.RLock()
+	entry, ok := reg.types[typeIndicator]
+	reg.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("bytocol: %w %d", ErrUnknownTypeIndicator, typeIndicator)
+	}
+
+	msg, err := entry.decode(r, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	// Error messages are surfaced as Go errors
+	switch asError := msg.(type) {
+	case ErrorMessage:
+		return nil, asError
+	case *ErrorMessage:
+		return nil, *asError
+	}
+	return msg, nil
+}
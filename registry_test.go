@@ -0,0 +1,182 @@
+package bytocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type testPointerMessage struct {
+	Value uint32 `bytocol:"0"`
+}
+
+func (m *testPointerMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 2, DebugName: "pointer"}
+}
+
+type testValueReceiverMessage struct {
+	Value uint32 `bytocol:"0"`
+}
+
+func (m testValueReceiverMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 3, DebugName: "value-receiver"}
+}
+
+type testReservedMessage struct {
+	Value uint32 `bytocol:"0"`
+}
+
+func (m testReservedMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 0, DebugName: "reserved"}
+}
+
+type testDuplicateMessage struct {
+	Value uint32 `bytocol:"0"`
+}
+
+func (m testDuplicateMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 1, DebugName: "duplicate"}
+}
+
+func TestRegister(t *testing.T) {
+	reg := NewRegistry()
+
+	plan, err := Register[testMessage](reg)
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.TypeIndicator() != 1 {
+		t.Errorf("unexpected type indicator %d", plan.TypeIndicator())
+	}
+
+	if found, ok := reg.Plan(1); !ok || found != plan {
+		t.Error("expected registered plan to be found")
+	}
+
+	// Catch duplicates
+	_, err = Register[testDuplicateMessage](reg)
+	if !errors.Is(err, ErrDuplicateTypeIndicator) {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+
+	// Catch reserved
+	_, err = Register[testReservedMessage](reg)
+	if !errors.Is(err, ErrReservedTypeIndicator) {
+		t.Errorf("expected reserved error, got %v", err)
+	}
+
+	if _, err = Register[*testPointerMessage](reg); err != nil {
+		t.Error(err)
+	}
+
+	// Includes the built-in error message
+	if plans := reg.Plans(); len(plans) != 3 {
+		t.Errorf("expected 3 plans, got %d", len(plans))
+	}
+}
+
+func TestRegisterPointerValueReceiver(t *testing.T) {
+	// Only the pointer type is planned so the cache cannot hide the nil receiver
+	plan, err := PlanType[*testValueReceiverMessage]()
+	if err != nil {
+		t.Error(err)
+		return
+	} else if plan.TypeIndicator() != 3 {
+		t.Errorf("unexpected type indicator %d", plan.TypeIndicator())
+	}
+
+	reg := NewRegistry()
+	if _, err = Register[*testValueReceiverMessage](reg); err != nil {
+		t.Error(err)
+		return
+	}
+
+	data, err := Marshal(&testValueReceiverMessage{7})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	msg, err := reg.Unmarshal(data)
+	if err != nil {
+		t.Error(err)
+	} else if ptr, ok := msg.(*testValueReceiverMessage); !ok || ptr.Value != 7 {
+		t.Errorf("unexpected message %#v", msg)
+	}
+}
+
+func TestRegistryRead(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := Register[*testPointerMessage](reg); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := Write(testMessageObj, &buf); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := Write(&testPointerMessage{42}, &buf); err != nil {
+		t.Error(err)
+		return
+	}
+
+	msg, err := reg.Read(&buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	result, ok := msg.(testMessage)
+	if !ok {
+		t.Errorf("unexpected message type %T", msg)
+		return
+	} else if result.String != testMessageObj.String || !bytes.Equal(result.Bytes, testMessageObj.Bytes) {
+		t.Errorf("unexpected message contents %+v", result)
+	}
+
+	msg, err = reg.Read(&buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ptr, ok := msg.(*testPointerMessage); !ok {
+		t.Errorf("unexpected message type %T", msg)
+	} else if ptr.Value != 42 {
+		t.Errorf("unexpected value %d", ptr.Value)
+	}
+
+	// Catch unknown types
+	_, err = reg.Unmarshal([]byte{200, 0, 0})
+	if !errors.Is(err, ErrUnknownTypeIndicator) {
+		t.Errorf("expected unknown type error, got %v", err)
+	}
+}
+
+func TestRegistryReadError(t *testing.T) {
+	reg := NewRegistry()
+
+	original := errors.New("something went wrong")
+	data, err := Marshal(Error(original))
+	if err != nil {
+		t.Error(err)
+		return
+	} else if data[0] != 0 {
+		t.Errorf("expected type indicator 0, got %d", data[0])
+	}
+
+	msg, err := reg.Unmarshal(data)
+	if msg != nil {
+		t.Errorf("expected nil message, got %T", msg)
+	}
+
+	var errMsg ErrorMessage
+	if !errors.As(err, &errMsg) {
+		t.Errorf("expected error message, got %v", err)
+	} else if errMsg.Error() != original.Error() {
+		t.Errorf("unexpected error message %q", errMsg.Error())
+	}
+}
@@ -0,0 +1,374 @@
+package bytocol
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// Kind is the wire encoding of a type within a [Schema].
+type Kind string
+
+const (
+	KindBool     Kind = "bool"
+	KindUint8    Kind = "uint8"
+	KindUint16   Kind = "uint16"
+	KindUint32   Kind = "uint32"
+	KindUint64   Kind = "uint64"
+	KindInt8     Kind = "int8"
+	KindInt16    Kind = "int16"
+	KindInt32    Kind = "int32"
+	KindInt64    Kind = "int64"
+	KindFloat32  Kind = "float32"
+	KindFloat64  Kind = "float64"
+	KindString   Kind = "string"
+	KindBytes    Kind = "bytes"
+	KindSlice    Kind = "slice"
+	KindArray    Kind = "array"
+	KindMap      Kind = "map"
+	KindOptional Kind = "optional"
+	KindStruct   Kind = "struct"
+	KindCodec    Kind = "codec"
+	KindTime     Kind = "time"
+	KindDuration Kind = "duration"
+)
+
+// Schema describes the wire format of a set of messages and the structs nested
+// within them, independently of Go. It is exported from plans with [NewSchema]
+// or [Registry.Schema], and serialized either as JSON using the struct tags or
+// as IDL text using [Schema.IDL] and [ParseIDL].
+type Schema struct {
+	Messages []MessageSchema `json:"messages"`
+
+	// Structs are the nested struct types referenced by name from the fields.
+	Structs []StructSchema `json:"structs,omitempty"`
+}
+
+// StructSchema describes the fields of a struct, encoded in their order.
+type StructSchema struct {
+	Name string `json:"name"`
+
+	// Size is the minimum encoded size of the fields, and Variable is set if
+	// the size depends on the values.
+	Size     uint `json:"size"`
+	Variable bool `json:"variable,omitempty"`
+
+	Fields []FieldSchema `json:"fields"`
+}
+
+// MessageSchema describes a message type. Its size does not include the type
+// indicator, but includes the checksum trailer.
+type MessageSchema struct {
+	StructSchema
+
+	DebugName     string `json:"debugName,omitempty"`
+	TypeIndicator byte   `json:"typeIndicator"`
+
+	// ByteOrder is the default byte order of the message, "big" or "little".
+	ByteOrder string `json:"byteOrder"`
+
+	// Checksum is the name of the [Checksum] trailer, empty when there is
+	// none.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// FieldSchema describes a single field of a struct or message.
+type FieldSchema struct {
+	Name  string     `json:"name"`
+	Order uint       `json:"order"`
+	Type  TypeSchema `json:"type"`
+}
+
+// TypeSchema describes the encoding of a field, or of the elements, keys, and
+// values within it.
+type TypeSchema struct {
+	Kind Kind `json:"kind"`
+
+	// Size is the minimum encoded size, and Variable is set if the size
+	// depends on the value.
+	Size     uint `json:"size"`
+	Variable bool `json:"variable,omitempty"`
+
+	// ByteOrder is set when the field overrides the byte order it inherits,
+	// "big" or "little".
+	ByteOrder string `json:"byteOrder,omitempty"`
+
+	// Prefix is the bit-size of the length or count prefix, 0 when there is
+	// none or it is a varint.
+	Prefix         byte `json:"prefix,omitempty"`
+	Varint         bool `json:"varint,omitempty"`
+	NullTerminated bool `json:"nullTerminated,omitempty"`
+
+	// Length is the number of elements of arrays.
+	Length int `json:"length,omitempty"`
+
+	// Unit is the precision of times and durations, "s", "ms", "us", or "ns",
+	// and TimeZone is set when times are followed by their zone offset.
+	Unit     string `json:"unit,omitempty"`
+	TimeZone bool   `json:"timeZone,omitempty"`
+
+	// Struct is the name of the nested struct in [Schema.Structs].
+	Struct string `json:"struct,omitempty"`
+
+	Key  *TypeSchema `json:"key,omitempty"`
+	Elem *TypeSchema `json:"elem,omitempty"`
+}
+
+// NewSchema builds the schema of the messages of the plans. Nested structs are
+// named after their Go type, made unique with a numbered suffix if needed.
+func NewSchema(plans ...*TypePlan) *Schema {
+	b := schemaBuilder{
+		schema: &Schema{Messages: make([]MessageSchema, 0, len(plans))},
+		names:  make(map[reflect.Type]string),
+		used:   make(map[string]bool),
+	}
+
+	for _, plan := range plans {
+		msg := MessageSchema{
+			StructSchema:  b.structSchema(plan.typeOf.Name(), plan),
+			DebugName:     plan.debugName,
+			TypeIndicator: plan.typeIndicator,
+			ByteOrder:     byteOrderName(plan.byteOrder),
+		}
+		if plan.checksum != ChecksumNone {
+			msg.Checksum = plan.checksum.String()
+		}
+		b.schema.Messages = append(b.schema.Messages, msg)
+	}
+
+	return b.schema
+}
+
+// Schema returns the schema of the message of this plan, see [NewSchema].
+func (ep TypePlan) Schema() *Schema {
+	return NewSchema(&ep)
+}
+
+// Schema returns the schema of every registered message ordered by their type
+// indicator, including the built-in [ErrorMessage].
+func (reg *Registry) Schema() *Schema {
+	return NewSchema(reg.Plans()...)
+}
+
+// Fingerprint returns the fingerprint of the schema of every registered
+// message, see [Schema.Fingerprint].
+func (reg *Registry) Fingerprint() uint64 {
+	return reg.Schema().Fingerprint()
+}
+
+// Message returns the schema of the message with the type indicator.
+func (s *Schema) Message(typeIndicator byte) (*MessageSchema, bool) {
+	for i := range s.Messages {
+		if s.Messages[i].TypeIndicator == typeIndicator {
+			return &s.Messages[i], true
+		}
+	}
+	return nil, false
+}
+
+// Struct returns the schema of the nested struct with the name.
+func (s *Schema) Struct(name string) (*StructSchema, bool) {
+	for i := range s.Structs {
+		if s.Structs[i].Name == name {
+			return &s.Structs[i], true
+		}
+	}
+	return nil, false
+}
+
+// Fingerprint returns a hash of the wire format described by the schema. It
+// covers the type indicators, byte orders, checksums, and the encoding of every
+// field in order, but not the names of messages, structs, and fields, so that
+// renaming them does not change the fingerprint.
+func (s *Schema) Fingerprint() uint64 {
+	messages := slices.Clone(s.Messages)
+	slices.SortFunc(messages, func(a, b MessageSchema) int {
+		return int(a.TypeIndicator) - int(b.TypeIndicator)
+	})
+
+	hash := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(hash, "message %d %s %s\n", msg.TypeIndicator, msg.ByteOrder, msg.Checksum)
+		s.fingerprintFields(hash, msg.Fields)
+	}
+
+	sum := hash.Sum(nil)
+	return binary.BigEndian.Uint64(sum)
+}
+
+// fingerprintFields writes the encoding of the fields, with nested structs
+// written inline.
+func (s *Schema) fingerprintFields(w io.Writer, fields []FieldSchema) {
+	for _, field := range fields {
+		s.fingerprintType(w, field.Type)
+	}
+	io.WriteString(w, "end\n")
+}
+
+func (s *Schema) fingerprintType(w io.Writer, ts TypeSchema) {
+	fmt.Fprintf(w, "%s %d %t %s %d %t %t %d %s %t\n", ts.Kind, ts.Size, ts.Variable, ts.ByteOrder,
+		ts.Prefix, ts.Varint, ts.NullTerminated, ts.Length, ts.Unit, ts.TimeZone)
+
+	if ts.Key != nil {
+		s.fingerprintType(w, *ts.Key)
+	}
+	if ts.Elem != nil {
+		s.fingerprintType(w, *ts.Elem)
+	}
+	if st, ok := s.Struct(ts.Struct); ok && ts.Kind == KindStruct {
+		s.fingerprintFields(w, st.Fields)
+	}
+}
+
+// schemaBuilder collects the nested structs while building a [Schema], naming
+// each struct type once.
+type schemaBuilder struct {
+	schema *Schema
+	names  map[reflect.Type]string
+	used   map[string]bool
+}
+
+func (b *schemaBuilder) structSchema(name string, plan *TypePlan) StructSchema {
+	s := StructSchema{
+		Name:     name,
+		Size:     plan.size,
+		Variable: plan.varLength,
+		Fields:   make([]FieldSchema, 0, len(plan.entries)),
+	}
+
+	for _, entry := range plan.entries {
+		s.Fields = append(s.Fields, FieldSchema{
+			Name:  entry.Field.Name,
+			Order: entry.Order,
+			Type:  b.typeSchema(entry, plan.byteOrder),
+		})
+	}
+	return s
+}
+
+// structName returns the name of the nested struct, adding it to the schema
+// the first time its type is seen.
+func (b *schemaBuilder) structName(plan *TypePlan) string {
+	if name, ok := b.names[plan.typeOf]; ok {
+		return name
+	}
+
+	base := plan.typeOf.Name()
+	if base == "" {
+		base = "Struct"
+	}
+	name := base
+	for i := 2; b.used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	b.names[plan.typeOf] = name
+	b.used[name] = true
+
+	b.schema.Structs = append(b.schema.Structs, b.structSchema(name, plan))
+	return name
+}
+
+// typeSchema describes the entry, whose byte order is only recorded when it
+// differs from the inherited one.
+func (b *schemaBuilder) typeSchema(pe planEntry, inherited binary.ByteOrder) TypeSchema {
+	ts := TypeSchema{
+		Kind:           entryKind(pe),
+		Size:           pe.Size,
+		Variable:       pe.VarLength,
+		Varint:         pe.Varint,
+		NullTerminated: pe.NullTerminated,
+	}
+	if pe.ByteOrder != inherited {
+		ts.ByteOrder = byteOrderName(pe.ByteOrder)
+	}
+
+	switch ts.Kind {
+	case KindString, KindBytes, KindSlice, KindMap, KindCodec:
+		if pe.VarLength && !pe.Varint && !pe.NullTerminated {
+			ts.Prefix = pe.LengthBits
+		}
+	case KindArray:
+		ts.Length = pe.Type.Len()
+	case KindTime, KindDuration:
+		ts.Unit = unitName(pe.Unit)
+		ts.TimeZone = pe.TimeZone
+	case KindStruct:
+		ts.Struct = b.structName(pe.Nested)
+	}
+
+	if pe.Key != nil {
+		key := b.typeSchema(*pe.Key, pe.ByteOrder)
+		ts.Key = &key
+	}
+	if pe.Elem != nil {
+		elem := b.typeSchema(*pe.Elem, pe.ByteOrder)
+		ts.Elem = &elem
+	}
+	return ts
+}
+
+// entryKind returns the wire encoding of the entry. The int and uint types are
+// encoded as 64-bit.
+func entryKind(pe planEntry) Kind {
+	switch {
+	case pe.Codec != nil:
+		return KindCodec
+	case pe.Type == timeType:
+		return KindTime
+	case pe.Type == durationType:
+		return KindDuration
+	case pe.Optional:
+		return KindOptional
+	case pe.Nested != nil:
+		return KindStruct
+	}
+
+	switch pe.Type.Kind() {
+	case reflect.Bool:
+		return KindBool
+	case reflect.Uint8:
+		return KindUint8
+	case reflect.Uint16:
+		return KindUint16
+	case reflect.Uint32:
+		return KindUint32
+	case reflect.Uint64, reflect.Uint:
+		return KindUint64
+	case reflect.Int8:
+		return KindInt8
+	case reflect.Int16:
+		return KindInt16
+	case reflect.Int32:
+		return KindInt32
+	case reflect.Int64, reflect.Int:
+		return KindInt64
+	case reflect.Float32:
+		return KindFloat32
+	case reflect.Float64:
+		return KindFloat64
+	case reflect.String:
+		return KindString
+	case reflect.Slice:
+		if pe.Elem == nil {
+			return KindBytes
+		}
+		return KindSlice
+	case reflect.Array:
+		return KindArray
+	case reflect.Map:
+		return KindMap
+	}
+	return ""
+}
+
+// byteOrderName returns the schema name of the byte order.
+func byteOrderName(order binary.ByteOrder) string {
+	if order == binary.LittleEndian {
+		return "little"
+	}
+	return "big"
+}
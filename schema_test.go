@@ -0,0 +1,166 @@
+package bytocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSchemaPosition struct {
+	X float32 `bytocol:"0"`
+	Y float32 `bytocol:"1,endian=big"`
+}
+
+type testSchemaMessage struct {
+	ID     uint32               `bytocol:"0,varint"`
+	Name   string               `bytocol:"1,length-prefix=16"`
+	Label  string               `bytocol:"2,null-terminated"`
+	At     testSchemaPosition   `bytocol:"3"`
+	Path   []testSchemaPosition `bytocol:"4,length-prefix=8"`
+	Tags   map[string]int16     `bytocol:"5,varint"`
+	Grid   [2][3]uint8          `bytocol:"6"`
+	Parent *testSchemaPosition  `bytocol:"7"`
+	Color  testColor            `bytocol:"8"`
+	Addr   netip.Addr           `bytocol:"9,length-prefix=8"`
+	Seen   time.Time            `bytocol:"10,unit=ms,tz"`
+	Wait   *time.Duration       `bytocol:"11,unit=s,varint"`
+	Big    uint64               `bytocol:"12,endian=big"`
+	Count  int                  `bytocol:"13"`
+	Extra  struct {
+		A uint8 `bytocol:"0"`
+	} `bytocol:"14"`
+}
+
+func (m testSchemaMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 17, DebugName: "schema", ByteOrder: binary.LittleEndian}
+}
+
+func TestPlanSchema(t *testing.T) {
+	plan, err := PlanType[testSchemaMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := plan.Schema()
+	msg, ok := schema.Message(17)
+	if !ok {
+		t.Fatal("expected message 17 in schema")
+	}
+
+	if msg.Name != "testSchemaMessage" || msg.DebugName != "schema" || msg.ByteOrder != "little" {
+		t.Errorf("unexpected message header %s %q %s", msg.Name, msg.DebugName, msg.ByteOrder)
+	}
+	if msg.Size != plan.Size() || !msg.Variable {
+		t.Errorf("expected variable size %d, got %d", plan.Size(), msg.Size)
+	}
+	if len(msg.Fields) != 15 {
+		t.Fatalf("expected 15 fields, got %d", len(msg.Fields))
+	}
+
+	for _, test := range []struct {
+		field    int
+		expected TypeSchema
+	}{
+		{0, TypeSchema{Kind: KindUint32, Size: 1, Variable: true, Varint: true}},
+		{1, TypeSchema{Kind: KindString, Size: 2, Variable: true, Prefix: 16}},
+		{2, TypeSchema{Kind: KindString, Size: 1, Variable: true, NullTerminated: true}},
+		{3, TypeSchema{Kind: KindStruct, Size: 8, Struct: "testSchemaPosition"}},
+		{5, TypeSchema{
+			Kind: KindMap, Size: 1, Variable: true, Varint: true,
+			Key:  &TypeSchema{Kind: KindString, Size: 8, Variable: true, Prefix: 64},
+			Elem: &TypeSchema{Kind: KindInt16, Size: 2},
+		}},
+		{6, TypeSchema{
+			Kind: KindArray, Size: 6, Length: 2,
+			Elem: &TypeSchema{Kind: KindArray, Size: 3, Length: 3, Elem: &TypeSchema{Kind: KindUint8, Size: 1}},
+		}},
+		{8, TypeSchema{Kind: KindCodec, Size: 3}},
+		{9, TypeSchema{Kind: KindCodec, Size: 1, Variable: true, Prefix: 8}},
+		{10, TypeSchema{Kind: KindTime, Size: 12, Unit: "ms", TimeZone: true}},
+		{11, TypeSchema{
+			Kind: KindOptional, Size: 1, Variable: true,
+			Elem: &TypeSchema{Kind: KindDuration, Size: 1, Variable: true, Varint: true, Unit: "s"},
+		}},
+		{12, TypeSchema{Kind: KindUint64, Size: 8, ByteOrder: "big"}},
+		{13, TypeSchema{Kind: KindInt64, Size: 8}},
+		{14, TypeSchema{Kind: KindStruct, Size: 1, Struct: "Struct"}},
+	} {
+		field := msg.Fields[test.field]
+		if !reflect.DeepEqual(field.Type, test.expected) {
+			t.Errorf("field %s: expected %+v, got %+v", field.Name, test.expected, field.Type)
+		}
+	}
+
+	// Nested structs are listed once, with byte orders relative to the message
+	if len(schema.Structs) != 2 {
+		t.Fatalf("expected 2 nested structs, got %d", len(schema.Structs))
+	}
+	position, ok := schema.Struct("testSchemaPosition")
+	if !ok {
+		t.Fatal("expected nested struct testSchemaPosition")
+	} else if position.Fields[0].Type.ByteOrder != "" || position.Fields[1].Type.ByteOrder != "big" {
+		t.Errorf("expected only Y to override the byte order, got %+v", position.Fields)
+	}
+
+	if path := msg.Fields[4].Type; path.Elem == nil || path.Elem.Struct != "testSchemaPosition" {
+		t.Errorf("expected path of positions, got %+v", path)
+	}
+}
+
+func TestRegistrySchema(t *testing.T) {
+	reg := NewRegistry()
+	if _, err := Register[testMessage](reg); err != nil {
+		t.Fatal(err)
+	}
+
+	schema := reg.Schema()
+	if len(schema.Messages) != 2 || len(schema.Structs) != 0 {
+		t.Fatalf("expected 2 messages and no structs, got %d and %d", len(schema.Messages), len(schema.Structs))
+	}
+
+	errMsg, ok := schema.Message(0)
+	if !ok || errMsg.Name != "ErrorMessage" || errMsg.Fields[0].Type.Prefix != 16 {
+		t.Errorf("expected built-in error message, got %+v", errMsg)
+	}
+
+	msg, ok := schema.Message(1)
+	if !ok {
+		t.Fatal("expected message 1 in schema")
+	}
+	kinds := make([]Kind, 0, len(msg.Fields))
+	for _, field := range msg.Fields {
+		kinds = append(kinds, field.Type.Kind)
+	}
+	expected := []Kind{KindBool, KindUint16, KindInt64, KindFloat32, KindString, KindBytes}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected kinds %v, got %v", expected, kinds)
+	}
+
+	if _, ok = schema.Message(2); ok {
+		t.Error("expected message 2 to not be found")
+	}
+}
+
+func TestSchemaJSON(t *testing.T) {
+	plan, err := PlanType[testSchemaMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := plan.Schema()
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Schema
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(schema, &decoded) {
+		t.Errorf("expected JSON round trip to match, got:\n%s", data)
+	}
+}
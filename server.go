@@ -0,0 +1,496 @@
+package bytocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultMaxCallsPerConn is the limit of concurrent calls per connection when
+// [Server.MaxCallsPerConn] is zero.
+const defaultMaxCallsPerConn = 16
+
+// handlerFunc is a type-erased handler registered with [Handle].
+type handlerFunc func(ctx context.Context, s *Session, msg Message) error
+
+// Server accepts connections from a [net.Listener], wraps each of them in a
+// [Conn], and dispatches the messages received to the handlers registered for
+// their type with [Handle]. Errors returned by handlers are sent back to the
+// peer as an [ErrorMessage].
+//
+// Messages of a single connection are handled one at a time in the order they
+// were received, except requests made with [Client.Call] which are each handled
+// in their own goroutine so that a slow call does not hold up the others.
+// Handlers answer those requests using [Session.Reply]. The exported fields must
+// be set before calling [Server.Serve].
+type Server struct {
+	// MaxConns limits the number of connections served at the same time. Once
+	// reached, new connections wait to be accepted until another one closes.
+	// Zero means no limit.
+	MaxConns int
+
+	// MaxCallsPerConn limits the number of requests made with [Client.Call]
+	// handled at the same time on a single connection. Once reached, the
+	// connection is not read until one of them returns. Zero means 16.
+	MaxCallsPerConn int
+
+	// ConnOptions are applied to the [Conn] of every connection accepted. The
+	// registry of the server is always used.
+	ConnOptions []ConnOption
+
+	// ErrorLog logs errors accepting connections and receiving messages. If nil
+	// the standard logger of the log package is used.
+	ErrorLog *log.Logger
+
+	reg      *Registry
+	handlers map[byte]handlerFunc
+
+	mu         sync.Mutex
+	listeners  map[net.Listener]struct{}
+	sessions   map[*Session]struct{}
+	inShutdown bool
+	slots      chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewServer creates a new [Server] decoding messages with the [Registry]
+// provided. If the registry is nil a new one is created.
+func NewServer(reg *Registry) *Server {
+	if reg == nil {
+		reg = NewRegistry()
+	}
+
+	return &Server{
+		reg:       reg,
+		handlers:  make(map[byte]handlerFunc),
+		listeners: make(map[net.Listener]struct{}),
+		sessions:  make(map[*Session]struct{}),
+	}
+}
+
+// Registry returns the [Registry] used to decode received messages.
+func (srv *Server) Registry() *Registry {
+	return srv.reg
+}
+
+// Handle registers the handler for every message of type T received by the
+// server. The type is registered with the registry of the server if it is not
+// already. It returns an error if the type cannot be registered, or if another
+// handler already exists for its type indicator. Errors returned by the handler
+// are sent back to the peer, as are panics once recovered and logged.
+func Handle[T Message](srv *Server, handler func(ctx context.Context, s *Session, msg T) error) error {
+	plan, err := CachePlan[T]()
+	if err != nil {
+		return err
+	}
+
+	// Messages are decoded as the registered type, which must be T exactly
+	if existing, ok := srv.reg.entry(plan.typeIndicator); !ok {
+		if _, err = Register[T](srv.reg); err != nil {
+			return err
+		}
+	} else if typeOf := reflect.TypeFor[T](); existing.typeOf != typeOf {
+		return fmt.Errorf("bytocol: cannot handle %s, %w %d already used by %s", typeOf, ErrDuplicateTypeIndicator, plan.typeIndicator, existing.typeOf)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if _, ok := srv.handlers[plan.typeIndicator]; ok {
+		return fmt.Errorf("bytocol: handler for %s already registered", plan.debugName)
+	}
+
+	srv.handlers[plan.typeIndicator] = func(ctx context.Context, s *Session, msg Message) error {
+		typed, ok := msg.(T)
+		if !ok {
+			return fmt.Errorf("bytocol: cannot handle %s, received %T", plan.debugName, msg)
+		}
+		return handler(ctx, s, typed)
+	}
+	return nil
+}
+
+// Serve accepts connections from the listener and serves each of them in their
+// own goroutine. It blocks until the listener fails, or until the server is
+// shutdown in which case [ErrServerClosed] is returned. The listener is closed
+// when Serve returns.
+func (srv *Server) Serve(l net.Listener) error {
+	if !srv.trackListener(l) {
+		return ErrServerClosed
+	}
+	defer srv.untrackListener(l)
+
+	var retryDelay time.Duration
+	for {
+		if srv.slots != nil {
+			srv.slots <- struct{}{}
+		}
+
+		netConn, err := l.Accept()
+		if err != nil {
+			if srv.slots != nil {
+				<-srv.slots
+			}
+			if srv.shuttingDown() {
+				return ErrServerClosed
+			}
+
+			// Back-off on temporary failures such as running out of files
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				retryDelay = min(max(2*retryDelay, 5*time.Millisecond), time.Second)
+				srv.logf("bytocol: error accepting connection, retrying in %s: %s", retryDelay, err)
+				time.Sleep(retryDelay)
+				continue
+			}
+			return err
+		}
+		retryDelay = 0
+
+		s := srv.newSession(netConn)
+		if s == nil {
+			netConn.Close()
+			if srv.slots != nil {
+				<-srv.slots
+			}
+			return ErrServerClosed
+		}
+		go srv.serveSession(s)
+	}
+}
+
+// Shutdown gracefully stops the server. Listeners are closed and idle
+// connections are closed right away, while connections currently running
+// handlers are closed once every handler returns. Frames arriving on those
+// connections in the meantime are dropped without being handled. If the
+// context ends before every connection is closed its error is returned, and
+// [Server.Close] can be used to close the remaining connections.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	srv.mu.Lock()
+	srv.inShutdown = true
+	srv.closeListenersLocked()
+	for s := range srv.sessions {
+		s.shutdown()
+	}
+	srv.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		srv.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close immediately stops the server, closing every listener and connection.
+// The contexts given to running handlers are canceled.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	srv.inShutdown = true
+	err := srv.closeListenersLocked()
+	for s := range srv.sessions {
+		s.Close()
+	}
+	return err
+}
+
+func (srv *Server) trackListener(l net.Listener) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.inShutdown {
+		return false
+	}
+	if srv.MaxConns > 0 && srv.slots == nil {
+		srv.slots = make(chan struct{}, srv.MaxConns)
+	}
+	srv.listeners[l] = struct{}{}
+	return true
+}
+
+func (srv *Server) untrackListener(l net.Listener) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if _, ok := srv.listeners[l]; ok {
+		l.Close()
+		delete(srv.listeners, l)
+	}
+}
+
+func (srv *Server) closeListenersLocked() error {
+	var err error
+	for l := range srv.listeners {
+		if closeErr := l.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		delete(srv.listeners, l)
+	}
+	return err
+}
+
+func (srv *Server) shuttingDown() bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.inShutdown
+}
+
+func (srv *Server) logf(format string, args ...any) {
+	if srv.ErrorLog != nil {
+		srv.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// newSession tracks a new session for the connection, or returns nil if the
+// server is shutting down.
+func (srv *Server) newSession(netConn net.Conn) *Session {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.inShutdown {
+		return nil
+	}
+
+	maxCalls := srv.MaxCallsPerConn
+	if maxCalls <= 0 {
+		maxCalls = defaultMaxCallsPerConn
+	}
+
+	opts := append([]ConnOption{WithRegistry(srv.reg)}, srv.ConnOptions...)
+	s := &Session{
+		srv:     srv,
+		conn:    NewConn(netConn, opts...),
+		netConn: netConn,
+		slots:   make(chan struct{}, maxCalls),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	srv.sessions[s] = struct{}{}
+	srv.wg.Add(1)
+	return s
+}
+
+// serveSession receives and handles messages until the connection closes.
+func (srv *Server) serveSession(s *Session) {
+	defer func() {
+		s.Close()
+		s.calls.Wait()
+
+		srv.mu.Lock()
+		delete(srv.sessions, s)
+		srv.mu.Unlock()
+
+		if srv.slots != nil {
+			<-srv.slots
+		}
+		srv.wg.Done()
+	}()
+
+	for {
+		hdr, body, err := s.conn.readFrame()
+		if !s.begin() {
+			// The frame is dropped while shutting down, letting the running
+			// calls reply before closing the connection
+			s.calls.Wait()
+			return
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) && !errors.Is(err, net.ErrClosed) {
+				// The stream can no longer be trusted to be in sync
+				srv.logf("bytocol: closing connection from %s: %s", s.RemoteAddr(), err)
+				s.Send(errorReply(err))
+			}
+			s.end()
+			return
+		}
+
+		if !hdr.correlated() {
+			srv.serveFrame(s.ctx, s, body)
+			if !s.end() {
+				return
+			}
+			continue
+		}
+
+		// Replies to correlated requests carry the same correlation ID, and
+		// they are handled concurrently up to the limit
+		ctx := context.WithValue(s.ctx, correlationKey{}, hdr.id)
+		s.slots <- struct{}{}
+		s.calls.Add(1)
+		go func() {
+			defer s.calls.Done()
+			srv.serveFrame(ctx, s, body)
+			<-s.slots
+			s.end()
+		}()
+	}
+}
+
+// serveFrame decodes the message of the frame and dispatches it to its
+// handler, sending any error back to the peer.
+func (srv *Server) serveFrame(ctx context.Context, s *Session, body []byte) {
+	msg, err := s.conn.decodeFrame(body)
+	if err != nil {
+		var remote ErrorMessage
+		if errors.As(err, &remote) {
+			srv.logf("bytocol: peer %s reported error: %s", s.RemoteAddr(), remote)
+		} else {
+			// The whole frame was read, so only this message is lost
+			srv.replyError(ctx, s, err)
+		}
+	} else if err = srv.handle(ctx, s, msg); err != nil {
+		srv.replyError(ctx, s, err)
+	}
+}
+
+// errorReply wraps the error into an [ErrorMessage] truncated to fit its length
+// prefix.
+func errorReply(err error) ErrorMessage {
+	msg := Error(err)
+	if len(msg.Message) > maxErrorMessageLength {
+		// Cut on a rune boundary so the message stays valid UTF-8
+		cut := maxErrorMessageLength
+		for cut > 0 && !utf8.RuneStart(msg.Message[cut]) {
+			cut--
+		}
+		msg.Message = msg.Message[:cut]
+	}
+	return msg
+}
+
+// replyError sends the error back to the peer as the reply to the request
+// handled with the context. Failures are logged since the peer may otherwise
+// wait for a reply that never comes.
+func (srv *Server) replyError(ctx context.Context, s *Session, err error) {
+	if replyErr := s.Reply(ctx, errorReply(err)); replyErr != nil {
+		srv.logf("bytocol: cannot send error to %s: %s", s.RemoteAddr(), replyErr)
+	}
+}
+
+// handle dispatches the message to the handler registered for its type. Like
+// net/http, a panicking handler is recovered and logged with its stack, and the
+// peer is sent an error rather than crashing the server.
+func (srv *Server) handle(ctx context.Context, s *Session, msg Message) (err error) {
+	info := msg.BytocolMessage()
+	defer func() {
+		if recErr := recover(); recErr != nil {
+			srv.logf("bytocol: panic handling %s from %s: %v\n%s", info.DebugName, s.RemoteAddr(), recErr, debug.Stack())
+			err = fmt.Errorf("bytocol: handler for %s (type %d) panicked", info.DebugName, info.TypeIndicator)
+		}
+	}()
+
+	srv.mu.Lock()
+	handler, ok := srv.handlers[info.TypeIndicator]
+	srv.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("bytocol: no handler for %s (type %d)", info.DebugName, info.TypeIndicator)
+	}
+	return handler(ctx, s, msg)
+}
+
+// Session is a single connection served by a [Server]. It is passed to the
+// handlers so they can reply to the peer, and may be kept to send messages to
+// the peer later on.
+type Session struct {
+	srv     *Server
+	conn    *Conn
+	netConn net.Conn
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// slots bounds the calls handled concurrently, which calls waits for
+	slots chan struct{}
+	calls sync.WaitGroup
+
+	mu      sync.Mutex
+	active  int
+	closing bool
+}
+
+// Send encodes and sends the message to the peer. It is safe to call from
+// multiple goroutines.
+func (s *Session) Send(msg Message) error {
+	return s.conn.Send(msg)
+}
+
+// Reply sends the message to the peer as the response to the request being
+// handled with the context provided. If the request was made with [Client.Call]
+// the reply carries its correlation ID, otherwise this is the same as
+// [Session.Send]. Each request should be replied to at most once.
+func (s *Session) Reply(ctx context.Context, msg Message) error {
+	id, ok := ctx.Value(correlationKey{}).(uint32)
+	if !ok {
+		return s.Send(msg)
+	}
+	return s.conn.sendFrame(frameHeader{flags: frameFlagCorrelated, id: id}, msg)
+}
+
+// RemoteAddr returns the address of the peer.
+func (s *Session) RemoteAddr() net.Addr {
+	return s.netConn.RemoteAddr()
+}
+
+// Close closes the connection and cancels the context of any running handler.
+func (s *Session) Close() error {
+	s.cancel()
+	return s.conn.Close()
+}
+
+// begin marks the session as handling one more message, it returns false if
+// the session is shutting down instead.
+func (s *Session) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return false
+	}
+	s.active++
+	return true
+}
+
+// end marks a message as handled, it returns false if the session should close
+// because the server is shutting down. The last message to end while shutting
+// down closes the connection, which stops the session reading.
+func (s *Session) end() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	if s.closing && s.active == 0 {
+		s.conn.Close()
+	}
+	return !s.closing
+}
+
+// shutdown marks the session as closing and closes it right away if it is not
+// handling any message.
+func (s *Session) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closing = true
+	if s.active == 0 {
+		s.conn.Close()
+	}
+}
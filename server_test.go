@@ -0,0 +1,303 @@
+package bytocol
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startTestServer(t *testing.T, srv *Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		if err := srv.Serve(l); !errors.Is(err, ErrServerClosed) {
+			t.Error(err)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func dialTestServer(t *testing.T, srv *Server, addr string) *Conn {
+	netConn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewConn(netConn, WithRegistry(srv.Registry()))
+}
+
+func TestServerHandle(t *testing.T) {
+	var logs strings.Builder
+	srv := NewServer(nil)
+	srv.ErrorLog = log.New(&logs, "", 0)
+	defer srv.Close()
+
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		if msg.String == "fail" {
+			return errors.New("handler failed")
+		} else if msg.String == "panic" {
+			panic("handler panicked")
+		}
+		msg.Uint++
+		return s.Send(msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Handlers are unique per type
+	err = Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error { return nil })
+	if err == nil {
+		t.Error("expected duplicate handler error")
+	}
+
+	conn := dialTestServer(t, srv, startTestServer(t, srv))
+	defer conn.Close()
+
+	if err = conn.Send(testMessageObj); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.Receive()
+	if err != nil {
+		t.Fatal(err)
+	} else if reply.(testMessage).Uint != testMessageObj.Uint+1 {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	failing := testMessageObj
+	failing.String = "fail"
+	if err = conn.Send(failing); err != nil {
+		t.Fatal(err)
+	}
+
+	var remote ErrorMessage
+	if _, err = conn.Receive(); !errors.As(err, &remote) || remote.Message != "handler failed" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	// Panics are recovered and reported back, and the connection is kept
+	failing.String = "panic"
+	if err = conn.Send(failing); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Receive(); !errors.As(err, &remote) {
+		t.Errorf("expected handler panic error, got %v", err)
+	}
+	if err = conn.Send(testMessageObj); err != nil {
+		t.Fatal(err)
+	} else if _, err = conn.Receive(); err != nil {
+		t.Errorf("expected connection to survive the panic, got %v", err)
+	} else if !strings.Contains(logs.String(), "handler panicked") {
+		t.Errorf("expected panic to be logged, got %q", logs.String())
+	}
+
+	// Messages without handler are reported back
+	if _, err = Register[testBlobMessage](srv.Registry()); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Send(testBlobMessage{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Receive(); !errors.As(err, &remote) {
+		t.Errorf("expected missing handler error, got %v", err)
+	}
+}
+
+func TestHandleRegisteredType(t *testing.T) {
+	noop := func(ctx context.Context, s *Session, msg testValueReceiverMessage) error { return nil }
+	noopPtr := func(ctx context.Context, s *Session, msg *testValueReceiverMessage) error { return nil }
+
+	// The value type is registered, pointers would never be decoded
+	srv := NewServer(nil)
+	if _, err := Register[testValueReceiverMessage](srv.Registry()); err != nil {
+		t.Fatal(err)
+	}
+	if err := Handle(srv, noopPtr); !errors.Is(err, ErrDuplicateTypeIndicator) {
+		t.Errorf("expected pointer handler to be rejected, got %v", err)
+	}
+
+	// Plans built again after clearing the cache still match the type
+	ClearPlanCache()
+	if err := Handle(srv, noop); err != nil {
+		t.Errorf("expected value handler to be accepted, got %v", err)
+	}
+
+	// The other way around
+	srv = NewServer(nil)
+	if _, err := Register[*testValueReceiverMessage](srv.Registry()); err != nil {
+		t.Fatal(err)
+	}
+	if err := Handle(srv, noop); !errors.Is(err, ErrDuplicateTypeIndicator) {
+		t.Errorf("expected value handler to be rejected, got %v", err)
+	}
+	if err := Handle(srv, noopPtr); err != nil {
+		t.Errorf("expected pointer handler to be accepted, got %v", err)
+	}
+}
+
+func TestServerLongError(t *testing.T) {
+	var logs strings.Builder
+	srv := NewServer(nil)
+	srv.ErrorLog = log.New(&logs, "", 0)
+	defer srv.Close()
+
+	// Longer than the 16-bit length prefix of error messages
+	long := strings.Repeat("é", 35000)
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		return errors.New(long)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := Dial("tcp", startTestServer(t, srv), WithRegistry(srv.Registry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var remote ErrorMessage
+	if _, err = client.Call(ctx, testMessageObj); !errors.As(err, &remote) {
+		t.Fatalf("expected truncated handler error, got %v", err)
+	} else if len(remote.Message) != 65534 || !strings.HasPrefix(long, remote.Message) {
+		t.Errorf("unexpected error message length %d", len(remote.Message))
+	} else if logs.Len() != 0 {
+		t.Errorf("unexpected logs %q", logs.String())
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	srv := NewServer(nil)
+	srv.MaxConns = 1
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		close(started)
+		<-release
+		return s.Send(msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialTestServer(t, srv, startTestServer(t, srv))
+	defer conn.Close()
+
+	if err = conn.Send(testMessageObj); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- srv.Shutdown(context.Background())
+	}()
+
+	// Shutdown waits for the running handler
+	select {
+	case err = <-shutdown:
+		t.Fatalf("shutdown returned early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if _, err = conn.Receive(); err != nil {
+		t.Errorf("expected reply before shutdown, got %v", err)
+	}
+	if err = <-shutdown; err != nil {
+		t.Error(err)
+	}
+
+	if err = srv.Serve(nil); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("expected server closed, got %v", err)
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	srv := NewServer(nil)
+
+	started := make(chan struct{})
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialTestServer(t, srv, startTestServer(t, srv))
+	defer conn.Close()
+
+	if err = conn.Send(testMessageObj); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err = srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	// Closing cancels the handler context
+	srv.Close()
+	if err = srv.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServerMaxConns(t *testing.T) {
+	srv := NewServer(nil)
+	srv.MaxConns = 1
+	defer srv.Close()
+
+	err := Handle(srv, func(ctx context.Context, s *Session, msg testMessage) error {
+		return s.Send(msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, srv)
+	first := dialTestServer(t, srv, addr)
+	if err = first.Send(testMessageObj); err != nil {
+		t.Fatal(err)
+	} else if _, err = first.Receive(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second connection is not served until the first closes
+	second := dialTestServer(t, srv, addr)
+	defer second.Close()
+	if err = second.Send(testMessageObj); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan error)
+	go func() {
+		_, err := second.Receive()
+		received <- err
+	}()
+
+	select {
+	case <-received:
+		t.Fatal("second connection served over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	if err = <-received; err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,105 @@
+package bytocol
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Times and durations are encoded as signed integers counting the unit of the
+// field, nanoseconds by default. Times count from the Unix epoch, with the zero
+// time encoded as [zeroTimeUnits], and zoned times are followed by the zone
+// offset in seconds east of UTC.
+
+// zeroTimeUnits is the encoding of the zero time. No other time is encoded as
+// it, so that times around the Unix epoch round-trip.
+const zeroTimeUnits = math.MinInt64
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// Bounds of the times that can be counted in nanoseconds within an int64,
+// excluding the zero time encoding.
+var (
+	minUnixNano = time.Unix(0, math.MinInt64+1)
+	maxUnixNano = time.Unix(0, math.MaxInt64)
+)
+
+// unitName returns the tag value of the unit.
+func unitName(unit time.Duration) string {
+	switch unit {
+	case time.Second:
+		return "s"
+	case time.Millisecond:
+		return "ms"
+	case time.Microsecond:
+		return "us"
+	}
+	return "ns"
+}
+
+// timeToUnix returns the number of units since the Unix epoch, or
+// [zeroTimeUnits] for the zero time. Times outside of the int64 range of
+// nanoseconds, or counting as many units as the zero time, return an
+// [ErrTimeOverflow] error.
+func timeToUnix(t time.Time, unit time.Duration) (int64, error) {
+	if t.IsZero() {
+		return zeroTimeUnits, nil
+	}
+
+	var units int64
+	switch unit {
+	case time.Second:
+		units = t.Unix()
+	case time.Millisecond:
+		units = t.UnixMilli()
+	case time.Microsecond:
+		units = t.UnixMicro()
+	default:
+		if t.Before(minUnixNano) || t.After(maxUnixNano) {
+			return 0, fmt.Errorf("%w: %s in nanoseconds", ErrTimeOverflow, t)
+		}
+		units = t.UnixNano()
+	}
+
+	if units == zeroTimeUnits {
+		return 0, fmt.Errorf("%w: %s in %s", ErrTimeOverflow, t, unitName(unit))
+	}
+	return units, nil
+}
+
+// unixToTime returns the time of the number of units since the Unix epoch in
+// the zone of the offset, or UTC when the offset is 0. [zeroTimeUnits] decode
+// as the zero time.
+func unixToTime(units int64, unit time.Duration, offset int) time.Time {
+	var t time.Time
+	switch {
+	case units == zeroTimeUnits:
+		return t
+	case unit == time.Second:
+		t = time.Unix(units, 0)
+	case unit == time.Millisecond:
+		t = time.UnixMilli(units)
+	case unit == time.Microsecond:
+		t = time.UnixMicro(units)
+	default:
+		t = time.Unix(0, units)
+	}
+
+	if offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", offset))
+}
+
+// unitsToDuration returns the duration of the number of units, or an
+// [ErrTimeOverflow] error if it does not fit in a [time.Duration].
+func unitsToDuration(units int64, unit time.Duration) (time.Duration, error) {
+	if units > math.MaxInt64/int64(unit) || units < math.MinInt64/int64(unit) {
+		return 0, fmt.Errorf("%w: %d units of %s as a duration", ErrTimeOverflow, units, unit)
+	}
+	return time.Duration(units) * unit, nil
+}
@@ -0,0 +1,210 @@
+package bytocol
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testTimeMessage struct {
+	Created  time.Time       `bytocol:"0"`
+	Seconds  time.Time       `bytocol:"1,unit=s"`
+	Zoned    time.Time       `bytocol:"2,unit=ms,tz"`
+	Compact  time.Time       `bytocol:"3,unit=s,varint,tz"`
+	Timeout  time.Duration   `bytocol:"4,unit=ms"`
+	Interval time.Duration   `bytocol:"5,varint"`
+	Expires  *time.Time      `bytocol:"6,unit=s"`
+	History  []time.Time     `bytocol:"7,length-prefix=8"`
+	Spans    []time.Duration `bytocol:"8,length-prefix=8"`
+}
+
+func (m testTimeMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 14, DebugName: "time"}
+}
+
+type testTimeOptionsMessage struct {
+	Count uint32 `bytocol:"0,unit=s"`
+}
+
+func (m testTimeOptionsMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 15, DebugName: "time-options"}
+}
+
+type testDurationZoneMessage struct {
+	Timeout time.Duration `bytocol:"0,tz"`
+}
+
+func (m testDurationZoneMessage) BytocolMessage() MessageInfo {
+	return MessageInfo{TypeIndicator: 16, DebugName: "duration-zone"}
+}
+
+func TestPlanTime(t *testing.T) {
+	plan, err := PlanType[testTimeMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 8 + 8 + 12 + 2 + 8 + 1 + 1 + 1 + 1
+	if size := plan.Size(); size != 42 {
+		t.Errorf("expected minimum size 42, got %d", size)
+	}
+
+	zone := time.FixedZone("", 2*60*60)
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := testTimeMessage{
+		Created:  time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC),
+		Seconds:  time.Date(1969, 12, 31, 23, 59, 0, 0, time.UTC),
+		Zoned:    time.Date(2024, 5, 6, 9, 8, 9, 500000000, zone),
+		Compact:  time.Unix(90, 0).In(zone),
+		Timeout:  1500 * time.Millisecond,
+		Interval: -time.Minute,
+		Expires:  &expires,
+		History:  []time.Time{{}, time.Unix(1, 0).UTC()},
+		Spans:    []time.Duration{time.Nanosecond, time.Hour},
+	}
+
+	data, err := plan.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the encoding of a few fields directly
+	if seconds := data[9:17]; !bytes.Equal(seconds, []byte{255, 255, 255, 255, 255, 255, 255, 196}) {
+		t.Errorf("expected -60 seconds, got %v", seconds)
+	}
+	if compact := data[29:33]; !bytes.Equal(compact, []byte{180, 1, 192, 112}) {
+		t.Errorf("expected varint seconds and offset, got %v", compact)
+	}
+	if timeout := data[33:41]; !bytes.Equal(timeout, []byte{0, 0, 0, 0, 0, 0, 5, 220}) {
+		t.Errorf("expected 1500 milliseconds, got %v", timeout)
+	}
+
+	var decoded testTimeMessage
+	if err = plan.Unmarshal(data[1:], &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !decoded.Created.Equal(msg.Created) || decoded.Created.Location() != time.UTC {
+		t.Errorf("expected created %s in UTC, got %s", msg.Created, decoded.Created)
+	}
+	if !decoded.Seconds.Equal(msg.Seconds) {
+		t.Errorf("expected seconds %s, got %s", msg.Seconds, decoded.Seconds)
+	}
+	if _, offset := decoded.Zoned.Zone(); !decoded.Zoned.Equal(msg.Zoned) || offset != 7200 {
+		t.Errorf("expected zoned %s, got %s", msg.Zoned, decoded.Zoned)
+	}
+	if _, offset := decoded.Compact.Zone(); !decoded.Compact.Equal(msg.Compact) || offset != 7200 {
+		t.Errorf("expected compact %s, got %s", msg.Compact, decoded.Compact)
+	}
+	if decoded.Timeout != msg.Timeout || decoded.Interval != msg.Interval {
+		t.Errorf("expected durations %s and %s, got %s and %s", msg.Timeout, msg.Interval, decoded.Timeout, decoded.Interval)
+	}
+	if decoded.Expires == nil || !decoded.Expires.Equal(expires) {
+		t.Errorf("expected expiry %s, got %v", expires, decoded.Expires)
+	}
+	if len(decoded.History) != 2 || !decoded.History[0].IsZero() || !decoded.History[1].Equal(msg.History[1]) {
+		t.Errorf("expected history %v, got %v", msg.History, decoded.History)
+	}
+	if len(decoded.Spans) != 2 || decoded.Spans[0] != time.Nanosecond || decoded.Spans[1] != time.Hour {
+		t.Errorf("expected spans %v, got %v", msg.Spans, decoded.Spans)
+	}
+
+	explained := plan.Explain(data)
+	for _, expected := range []string{
+		"(2024-05-06T07:08:09.123456789Z)",
+		"(2024-05-06T09:08:09.5+02:00)",
+		"(1.5s)",
+		"(-1m0s)",
+		"All bytes accounted for",
+	} {
+		if !strings.Contains(explained, expected) {
+			t.Errorf("expected explanation to contain %s, got:\n%s", expected, explained)
+		}
+	}
+}
+
+func TestPlanTimeEpoch(t *testing.T) {
+	plan, err := PlanType[testTimeMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The zero time has its own encoding, so the Unix epoch and the times
+	// around it round-trip in every unit and zone
+	epoch := time.Unix(0, 0).In(time.FixedZone("", 60*60))
+	msg := testTimeMessage{
+		Created: time.Unix(0, 1).UTC(),
+		Seconds: epoch.UTC(),
+		Zoned:   epoch,
+		Compact: epoch,
+		History: []time.Time{{}, epoch.UTC()},
+	}
+
+	data, err := plan.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded testTimeMessage
+	if err = plan.Unmarshal(data[1:], &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for i, pair := range [][2]time.Time{
+		{msg.Created, decoded.Created},
+		{msg.Seconds, decoded.Seconds},
+		{msg.Zoned, decoded.Zoned},
+		{msg.Compact, decoded.Compact},
+		{msg.History[1], decoded.History[1]},
+	} {
+		if pair[1].IsZero() || !pair[1].Equal(pair[0]) {
+			t.Errorf("time %d: expected %s, got %s", i, pair[0], pair[1])
+		}
+	}
+	if !decoded.History[0].IsZero() {
+		t.Errorf("expected the zero time, got %s", decoded.History[0])
+	}
+
+	// The encoding of the zero time is not available to other times
+	msg = testTimeMessage{Created: time.Unix(0, math.MinInt64)}
+	if _, err = plan.Marshal(msg); !errors.Is(err, ErrTimeOverflow) {
+		t.Errorf("expected time overflow, got %v", err)
+	}
+}
+
+func TestPlanTimeErrors(t *testing.T) {
+	if _, err := PlanType[testTimeOptionsMessage](); err == nil || !strings.Contains(err.Error(), "time options") {
+		t.Errorf("expected time options error, got %v", err)
+	}
+
+	if _, err := PlanType[testDurationZoneMessage](); err == nil || !strings.Contains(err.Error(), "tz") {
+		t.Errorf("expected tz error, got %v", err)
+	}
+
+	plan, err := PlanType[testTimeMessage]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nanoseconds only cover the years 1678 to 2262
+	msg := testTimeMessage{Created: time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if _, err = plan.Marshal(msg); !errors.Is(err, ErrTimeOverflow) {
+		t.Errorf("expected time overflow, got %v", err)
+	}
+
+	// Milliseconds overflowing a duration, the zero times leave Timeout at 40
+	data, err := plan.Marshal(testTimeMessage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(data[40:48], []byte{127, 255, 255, 255, 255, 255, 255, 255})
+
+	var decodeErr *DecodeError
+	if err = plan.Unmarshal(data[1:], &testTimeMessage{}); !errors.As(err, &decodeErr) || !errors.Is(err, ErrTimeOverflow) {
+		t.Errorf("expected duration overflow, got %v", err)
+	} else if decodeErr.Field != "Timeout" {
+		t.Errorf("expected failing field Timeout, got %s", decodeErr.Field)
+	}
+}
@@ -1 +1,75 @@
 package bytocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// readFull reads exactly the length of the buffer from the reader, looping over
+// short reads as needed. Since it is used within a message, reaching the end of
+// the reader before the buffer is filled is always unexpected. A partially
+// filled buffer is reported as [ErrReadInvariance] wrapping
+// [io.ErrUnexpectedEOF], and an empty one as just [io.ErrUnexpectedEOF].
+func readFull(r io.Reader, buf []byte) error {
+	n, err := io.ReadFull(r, buf)
+	return shortReadError(n, len(buf), err)
+}
+
+// shortReadError returns the error of reading n of the size bytes expected, as
+// reported by [readFull].
+func shortReadError(n int, size int, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if n > 0 {
+			return fmt.Errorf("%w, read %d of %d bytes: %w", ErrReadInvariance, n, size, io.ErrUnexpectedEOF)
+		}
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// countingReader wraps an [io.Reader] and counts the bytes read from it so that
+// decoding errors can report the offset they occurred at. It also carries the
+// [DecodeLimits] of the message being decoded, failing reads past its maximum
+// size, along with the current nesting depth.
+type countingReader struct {
+	r      io.Reader
+	offset int64
+	limits DecodeLimits
+	depth  int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	if maxSize := cr.limits.MaxMessageSize; maxSize != 0 {
+		remaining := cr.remaining()
+		if remaining == 0 && len(p) > 0 {
+			return 0, fmt.Errorf("%w: message exceeds %d bytes", ErrLimitExceeded, maxSize)
+		} else if uint64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := cr.r.Read(p)
+	cr.offset += int64(n)
+	return n, err
+}
+
+// remaining returns the number of bytes that can still be read before reaching
+// the maximum message size, or [math.MaxUint64] when it is not limited.
+func (cr *countingReader) remaining() uint64 {
+	maxSize := cr.limits.MaxMessageSize
+	if maxSize == 0 {
+		return math.MaxUint64
+	}
+	return maxSize - min(uint64(cr.offset), maxSize)
+}
+
+// readOffset returns the number of bytes read so far if the reader is a
+// [countingReader], otherwise -1.
+func readOffset(r io.Reader) int64 {
+	if cr, ok := r.(*countingReader); ok {
+		return cr.offset
+	}
+	return -1
+}
@@ -0,0 +1,57 @@
+package bytocol
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Unsigned variable-length integers are encoded as LEB128, 7 bits per byte
+// with the high bit set on every byte except the last. Signed integers are
+// zigzag encoded first so that small negative values stay small.
+
+func writeUvarint(num uint64, w io.Writer) error {
+	_, err := w.Write(binary.AppendUvarint(nil, num))
+	return err
+}
+
+func writeVarint(num int64, w io.Writer) error {
+	_, err := w.Write(binary.AppendVarint(nil, num))
+	return err
+}
+
+// readUvarint reads an unsigned LEB128 integer byte-by-byte from the reader.
+// If the encoding does not fit in 64 bits an [ErrVarintOverflow] error is
+// returned.
+func readUvarint(r io.Reader) (uint64, error) {
+	var value uint64
+	var shift uint
+	excerpt := make([]byte, 1)
+
+	for i := 0; i < binary.MaxVarintLen64; i++ {
+		if err := readFull(r, excerpt); err != nil {
+			return value, err
+		}
+
+		b := excerpt[0]
+		if b < 0x80 {
+			if i == binary.MaxVarintLen64-1 && b > 1 {
+				return value, ErrVarintOverflow
+			}
+			return value | uint64(b)<<shift, nil
+		}
+		value |= uint64(b&0x7f) << shift
+		shift += 7
+	}
+
+	return value, ErrVarintOverflow
+}
+
+// readVarint reads a zigzag encoded signed LEB128 integer from the reader.
+func readVarint(r io.Reader) (int64, error) {
+	unsigned, err := readUvarint(r)
+	value := int64(unsigned >> 1)
+	if unsigned&1 != 0 {
+		value = ^value
+	}
+	return value, err
+}
@@ -0,0 +1,75 @@
+package bytocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestUvarint(t *testing.T) {
+	tests := map[uint64][]byte{
+		0:              {0},
+		1:              {1},
+		127:            {127},
+		128:            {128, 1},
+		300:            {172, 2},
+		math.MaxUint64: {255, 255, 255, 255, 255, 255, 255, 255, 255, 1},
+	}
+
+	for num, expected := range tests {
+		var buf bytes.Buffer
+		if err := writeUvarint(num, &buf); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("incorrect encoding for %d: %v", num, buf.Bytes())
+		}
+
+		value, err := readUvarint(&buf)
+		if err != nil {
+			t.Error(err)
+		} else if value != num {
+			t.Errorf("incorrect decoding %d for %d", value, num)
+		}
+	}
+
+	// Catch overflowing 64 bits
+	_, err := readUvarint(bytes.NewReader([]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 2}))
+	if !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+
+	// Catch truncated encodings
+	_, err = readUvarint(bytes.NewReader([]byte{128}))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected unexpected EOF, got %v", err)
+	}
+}
+
+func TestVarint(t *testing.T) {
+	tests := map[int64][]byte{
+		0:             {0},
+		-1:            {1},
+		1:             {2},
+		-64:           {127},
+		64:            {128, 1},
+		math.MinInt64: {255, 255, 255, 255, 255, 255, 255, 255, 255, 1},
+	}
+
+	for num, expected := range tests {
+		var buf bytes.Buffer
+		if err := writeVarint(num, &buf); err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("incorrect encoding for %d: %v", num, buf.Bytes())
+		}
+
+		value, err := readVarint(&buf)
+		if err != nil {
+			t.Error(err)
+		} else if value != num {
+			t.Errorf("incorrect decoding %d for %d", value, num)
+		}
+	}
+}